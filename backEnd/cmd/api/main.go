@@ -3,12 +3,19 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
 	"github.com/413ksz/BlueFox/backEnd/pkg/router"
+	"github.com/413ksz/BlueFox/backEnd/pkg/signedurl"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/uploads"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
@@ -66,6 +73,126 @@ func init() {
 		Str("event", "app_db_init_success").
 		Msg("Global database connection successfully initialized.")
 
+	// --- Gateway Configuration ---
+
+	// Create the hub the gateway clients connect to, keeping the voice states in the database
+	gateway.DefaultHub = gateway.NewHub(gateway.DatabaseAccess(database.DB))
+	gateway.DefaultHub.Voice = gateway.NewDatabaseVoice(database.DB)
+
+	// Relay the published events between instances through Postgres when GATEWAY_BUS is "postgres",
+	// a single instance delivers them in-process
+	if os.Getenv("GATEWAY_BUS") == "postgres" {
+		gateway.DefaultBus = gateway.NewPostgresBus(database.DB, os.Getenv("DATABASE_URL"), gateway.POSTGRES_BUS_CHANNEL, gateway.DefaultHub.Publish)
+		// Sessions are recorded in the database, so streams and polls resume them on any instance
+		gateway.DefaultHub.Sessions = gateway.NewDatabaseSessions(database.DB)
+	} else {
+		gateway.DefaultBus = gateway.NewLocalBus(gateway.DefaultHub.Publish)
+	}
+	// Voice events of the hub reach the sessions on every instance
+	gateway.DefaultHub.Bus = gateway.DefaultBus
+
+	log.Info().
+		Str("component", "main_app").
+		Str("event", "gateway_initialized").
+		Str("bus", os.Getenv("GATEWAY_BUS")).
+		Msg("Gateway hub and event bus initialized.")
+
+	// --- Storage Configuration ---
+
+	// Keep the media files in an S3-compatible bucket when STORAGE_BACKEND is "s3", otherwise in the
+	// STORAGE_PATH directory served by the API. The working directory of a serverless function is
+	// read-only, so the directory defaults to the temporary directory. Without a storage the API
+	// still starts and the media routes answer 503
+	var store storage.Storage
+	var err error
+	if os.Getenv("STORAGE_BACKEND") == "s3" {
+		var s3 *storage.S3Storage
+		s3, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") == "true",
+		})
+		if err == nil {
+			store = s3
+		}
+	} else {
+		root := os.Getenv("STORAGE_PATH")
+		if root == "" {
+			root = filepath.Join(os.TempDir(), "bluefox-media")
+		}
+		var local *storage.LocalStorage
+		local, err = storage.NewLocalStorage(root)
+		if err == nil {
+			store = local
+		}
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("component", "main_app").
+			Str("event", "storage_init_failure").
+			Str("backend", os.Getenv("STORAGE_BACKEND")).
+			Msg("Failed to initialize media storage, media routes are unavailable")
+	} else {
+		storage.DefaultStorage = store
+		log.Info().
+			Str("component", "main_app").
+			Str("event", "storage_initialized").
+			Str("backend", os.Getenv("STORAGE_BACKEND")).
+			Msg("Media storage initialized.")
+	}
+
+	// Keep the chunks of resumable uploads in UPLOAD_PATH, which must be shared by all instances.
+	// Without it resumable uploads answer 503
+	uploadPath := os.Getenv("UPLOAD_PATH")
+	if uploadPath == "" {
+		uploadPath = filepath.Join(os.TempDir(), "bluefox-uploads")
+	}
+	chunks, err := uploads.NewChunks(uploadPath)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("component", "main_app").
+			Str("event", "upload_chunks_init_failure").
+			Str("upload_path", uploadPath).
+			Msg("Failed to initialize the upload chunk store, resumable uploads are unavailable")
+	} else {
+		uploads.DefaultChunks = chunks
+	}
+
+	// Sign the URLs of message attachments with MEDIA_URL_SECRET, or a key derived from the JWT
+	// secret when it is not set
+	if urlSecret := os.Getenv("MEDIA_URL_SECRET"); urlSecret != "" {
+		signedurl.Secret = []byte(urlSecret)
+	} else {
+		signedurl.Secret = signedurl.DeriveSecret([]byte(os.Getenv("JWT_SECRET_KEY")))
+	}
+	if signedurl.Secret == nil {
+		log.Warn().
+			Str("component", "main_app").
+			Str("event", "media_url_secret_missing").
+			Msg("Neither MEDIA_URL_SECRET nor JWT_SECRET_KEY is set, attachments cannot be downloaded.")
+	}
+
+	// --- Imaging Configuration ---
+
+	// Process uploaded images in the background. Images left pending by a restart, a failed attempt
+	// or a serverless instance frozen before it finished are picked up again periodically. Without a
+	// storage there is nothing to process
+	if storage.DefaultStorage != nil {
+		imaging.DefaultPipeline = imaging.NewPipeline(database.DB, storage.DefaultStorage)
+		imaging.DefaultPipeline.Start(context.Background(), imaging.WORKERS)
+
+		log.Info().
+			Str("component", "main_app").
+			Str("event", "imaging_initialized").
+			Int("workers", imaging.WORKERS).
+			Msg("Image pipeline initialized.")
+	}
+
 	// --- API Routes ---
 	// Initialize the API router
 	appRouter = mux.NewRouter()
@@ -118,8 +245,9 @@ func main() {
 	// Add CORS middleware to the router to allow cross-origin requests from the front-end app
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Checksum"},
+		ExposedHeaders:   []string{"Location", "Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: true,
 		Debug:            true,
 	})
@@ -31,6 +31,11 @@ const (
 	ERROR_CODE_ENCODE_ERROR                  ErrorCode = "ENCODE_ERROR"
 	ERROR_CODE_UNIQUE_KEY_VIOLATION          ErrorCode = "UNIQUE_KEY_VIOLATION"
 	ERROR_CODE_ENVIREMENT_VARIABLE_NOT_FOUND ErrorCode = "ENVIREMENT_VARIABLE_NOT_FOUND"
+	ERROR_CODE_PAYLOAD_TOO_LARGE             ErrorCode = "PAYLOAD_TOO_LARGE"
+	ERROR_CODE_QUOTA_EXCEEDED                ErrorCode = "QUOTA_EXCEEDED"
+	ERROR_CODE_CONFLICT                      ErrorCode = "CONFLICT"
+	ERROR_CODE_CHECKSUM_MISMATCH             ErrorCode = "CHECKSUM_MISMATCH"
+	ERROR_CODE_UNSUPPORTED_MEDIA_TYPE        ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
 )
 
 var ErrorMessages = map[ErrorCode]struct {
@@ -50,6 +55,11 @@ var ErrorMessages = map[ErrorCode]struct {
 	ERROR_CODE_ENCODE_ERROR:                  {Message: "Error encoding response.", Status: http.StatusInternalServerError},
 	ERROR_CODE_UNIQUE_KEY_VIOLATION:          {Message: "A unique key violation occurred.", Status: http.StatusConflict},
 	ERROR_CODE_ENVIREMENT_VARIABLE_NOT_FOUND: {Message: "Environment variable not found.", Status: http.StatusInternalServerError},
+	ERROR_CODE_PAYLOAD_TOO_LARGE:             {Message: "The request body is too large.", Status: http.StatusRequestEntityTooLarge},
+	ERROR_CODE_QUOTA_EXCEEDED:                {Message: "The storage quota has been exceeded.", Status: http.StatusRequestEntityTooLarge},
+	ERROR_CODE_CONFLICT:                      {Message: "The request conflicts with the current state of the resource.", Status: http.StatusConflict},
+	ERROR_CODE_CHECKSUM_MISMATCH:             {Message: "The checksum of the received content does not match.", Status: http.StatusBadRequest},
+	ERROR_CODE_UNSUPPORTED_MEDIA_TYPE:        {Message: "The media type is not supported.", Status: http.StatusUnsupportedMediaType},
 }
 
 func (code ErrorCode) ApiErrorResponse(details any, err error) *models.CustomError {
@@ -0,0 +1,115 @@
+// Package channeltree contains the rules for nesting server channels under categories
+// and helpers to turn the flat channel list of a server into an ordered tree.
+//
+// A server's channels form a tree with at most one level of nesting:
+// top-level channels and categories, and non-category channels inside a category.
+package channeltree
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+)
+
+var (
+	// ErrCategoryNested is returned when a category would be placed inside another channel.
+	ErrCategoryNested = errors.New("a category cannot have a parent")
+	// ErrParentNotCategory is returned when the parent channel is not a category.
+	ErrParentNotCategory = errors.New("the parent channel must be a category")
+	// ErrParentIsSelf is returned when a channel would become its own parent.
+	ErrParentIsSelf = errors.New("a channel cannot be its own parent")
+	// ErrParentOtherServer is returned when the parent channel belongs to another server.
+	ErrParentOtherServer = errors.New("the parent channel belongs to another server")
+	// ErrNestingTooDeep is returned when a placement would create more than one level of nesting.
+	ErrNestingTooDeep = errors.New("channels can only be nested one level deep")
+	// ErrChildrenRequireCategory is returned when a channel with children would stop being a category.
+	ErrChildrenRequireCategory = errors.New("only categories can contain channels")
+)
+
+// ValidatePlacement checks whether a channel may be placed under the given parent.
+// params:
+// - channel: The channel being created or moved, with its final Type and ServerID.
+// - parent: The new parent channel, or nil to place the channel at the top level.
+// - hasChildren: Whether the channel currently contains other channels.
+// returns:
+// - error: One of the package errors describing the violated rule, or nil if the placement is valid.
+func ValidatePlacement(channel *models.Channel, parent *models.Channel, hasChildren bool) error {
+	if hasChildren && channel.Type != models.ChannelTypeCategory {
+		return ErrChildrenRequireCategory
+	}
+	if parent == nil {
+		return nil
+	}
+	if parent.ID == channel.ID {
+		return ErrParentIsSelf
+	}
+	if channel.Type == models.ChannelTypeCategory {
+		return ErrCategoryNested
+	}
+	if parent.ServerID != channel.ServerID {
+		return ErrParentOtherServer
+	}
+	if parent.Type != models.ChannelTypeCategory {
+		return ErrParentNotCategory
+	}
+	// Categories never have a parent, so this only triggers on inconsistent data.
+	if parent.Parent != nil || hasChildren {
+		return ErrNestingTooDeep
+	}
+	return nil
+}
+
+// Build turns the flat channel list of a server into an ordered tree.
+// Top-level channels are returned with their children attached in ChildChannels.
+// Channels whose parent is missing from the list are treated as top-level channels.
+// Uncategorized channels come before categories, and channels are ordered by position,
+// with name and ID as tie-breakers.
+func Build(channels []models.Channel) []models.Channel {
+	byID := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		byID[channel.ID.String()] = true
+	}
+
+	children := make(map[string][]models.Channel)
+	var roots []models.Channel
+	for _, channel := range channels {
+		channel.ChildChannels = nil
+		if channel.Parent != nil && byID[channel.Parent.String()] {
+			key := channel.Parent.String()
+			children[key] = append(children[key], channel)
+			continue
+		}
+		roots = append(roots, channel)
+	}
+
+	sortChannels(roots)
+	for i := range roots {
+		kids := children[roots[i].ID.String()]
+		sortChannels(kids)
+		roots[i].ChildChannels = kids
+	}
+	if roots == nil {
+		roots = []models.Channel{}
+	}
+	return roots
+}
+
+// sortChannels orders channels in place: uncategorized channels first, then by position, name and ID.
+func sortChannels(channels []models.Channel) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		a, b := channels[i], channels[j]
+		aCategory := a.Type == models.ChannelTypeCategory
+		bCategory := b.Type == models.ChannelTypeCategory
+		if aCategory != bCategory {
+			return !aCategory
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID.String() < b.ID.String()
+	})
+}
@@ -0,0 +1,142 @@
+package channeltree_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/channeltree"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newChannel creates a channel in the given server for the tests.
+func newChannel(serverID uuid.UUID, name string, channelType models.ChannelType, parent *uuid.UUID) models.Channel {
+	return models.Channel{
+		ID:       uuid.New(),
+		ServerID: serverID,
+		Name:     name,
+		Type:     channelType,
+		Parent:   parent,
+	}
+}
+
+// TestValidatePlacement tests the nesting rules enforced by ValidatePlacement.
+func TestValidatePlacement(t *testing.T) {
+	serverID := uuid.New()
+	category := newChannel(serverID, "General", models.ChannelTypeCategory, nil)
+	chat := newChannel(serverID, "chat", models.ChannelTypeChat, nil)
+	nestedChat := newChannel(serverID, "nested", models.ChannelTypeChat, &category.ID)
+	otherServerCategory := newChannel(uuid.New(), "Other", models.ChannelTypeCategory, nil)
+	brokenCategory := newChannel(serverID, "Broken", models.ChannelTypeCategory, &chat.ID)
+
+	tests := []struct {
+		name        string
+		channel     models.Channel
+		parent      *models.Channel
+		hasChildren bool
+		want        error
+	}{
+		{
+			name:    "Valid: Top-level chat channel",
+			channel: chat,
+			parent:  nil,
+			want:    nil,
+		},
+		{
+			name:        "Valid: Top-level category with children",
+			channel:     category,
+			parent:      nil,
+			hasChildren: true,
+			want:        nil,
+		},
+		{
+			name:    "Valid: Chat channel inside a category",
+			channel: chat,
+			parent:  &category,
+			want:    nil,
+		},
+		{
+			name:    "Valid: Voice channel inside a category",
+			channel: newChannel(serverID, "voice", models.ChannelTypeVoice, nil),
+			parent:  &category,
+			want:    nil,
+		},
+		{
+			name:    "Invalid: Category inside a category",
+			channel: newChannel(serverID, "Sub", models.ChannelTypeCategory, nil),
+			parent:  &category,
+			want:    channeltree.ErrCategoryNested,
+		},
+		{
+			name:    "Invalid: Channel inside a chat channel",
+			channel: newChannel(serverID, "child", models.ChannelTypeChat, nil),
+			parent:  &nestedChat,
+			want:    channeltree.ErrParentNotCategory,
+		},
+		{
+			name:    "Invalid: Category as its own parent",
+			channel: category,
+			parent:  &category,
+			want:    channeltree.ErrParentIsSelf,
+		},
+		{
+			name:    "Invalid: Parent in another server",
+			channel: chat,
+			parent:  &otherServerCategory,
+			want:    channeltree.ErrParentOtherServer,
+		},
+		{
+			name:    "Invalid: Parent category with a parent of its own",
+			channel: newChannel(serverID, "deep", models.ChannelTypeChat, nil),
+			parent:  &brokenCategory,
+			want:    channeltree.ErrNestingTooDeep,
+		},
+		{
+			name:        "Invalid: Non-category channel with children",
+			channel:     chat,
+			parent:      nil,
+			hasChildren: true,
+			want:        channeltree.ErrChildrenRequireCategory,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := channeltree.ValidatePlacement(&tt.channel, tt.parent, tt.hasChildren)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestBuild tests that Build nests children under their categories and orders every level.
+func TestBuild(t *testing.T) {
+	serverID := uuid.New()
+	categoryB := newChannel(serverID, "B category", models.ChannelTypeCategory, nil)
+	categoryA := newChannel(serverID, "A category", models.ChannelTypeCategory, nil)
+	general := newChannel(serverID, "general", models.ChannelTypeChat, nil)
+	voice := newChannel(serverID, "voice", models.ChannelTypeVoice, &categoryA.ID)
+	chat := newChannel(serverID, "chat", models.ChannelTypeChat, &categoryA.ID)
+	missingParent := uuid.New()
+	orphan := newChannel(serverID, "orphan", models.ChannelTypeChat, &missingParent)
+
+	tree := channeltree.Build([]models.Channel{categoryB, voice, categoryA, orphan, chat, general})
+
+	if assert.Len(t, tree, 4) {
+		assert.Equal(t, "general", tree[0].Name)
+		assert.Equal(t, "orphan", tree[1].Name)
+		assert.Equal(t, "A category", tree[2].Name)
+		assert.Equal(t, "B category", tree[3].Name)
+	}
+	if assert.Len(t, tree[2].ChildChannels, 2) {
+		assert.Equal(t, "chat", tree[2].ChildChannels[0].Name)
+		assert.Equal(t, "voice", tree[2].ChildChannels[1].Name)
+	}
+	assert.Empty(t, tree[3].ChildChannels)
+}
+
+// TestBuild_Empty tests that Build returns an empty, non-nil slice for a server without channels.
+func TestBuild_Empty(t *testing.T) {
+	tree := channeltree.Build(nil)
+	assert.NotNil(t, tree)
+	assert.Empty(t, tree)
+}
@@ -0,0 +1,184 @@
+package channeltree
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownChannel is returned when a move references a channel that is not part of the server.
+	ErrUnknownChannel = errors.New("the channel does not belong to this server")
+	// ErrDuplicateMove is returned when the same channel is moved more than once in one request.
+	ErrDuplicateMove = errors.New("a channel can only be moved once per request")
+	// ErrInvalidPosition is returned when a move has a negative position.
+	ErrInvalidPosition = errors.New("the position must not be negative")
+)
+
+// Move describes the requested new place of one channel.
+type Move struct {
+	ID uuid.UUID
+	// ParentSet reports whether the parent should change. If false, Parent is ignored
+	// and the channel stays under its current parent.
+	ParentSet bool
+	// Parent is the new parent category, or nil for the top level.
+	Parent *uuid.UUID
+	// Position is the requested index of the channel among its new siblings.
+	Position int
+}
+
+// ApplyMoves applies a full or partial list of moves to the channels of one server.
+// Every resulting placement is validated with ValidatePlacement, then the positions of all
+// sibling groups are normalised to 0..n-1: moved channels are inserted at their requested
+// index, and the channels that were not moved keep their relative order.
+// params:
+// - channels: All channels of the server.
+// - moves: The requested moves.
+// returns:
+// - []models.Channel: The channels whose parent or position changed, ready to be persisted.
+// - error: One of the package errors if a move is invalid. No channel is changed in that case.
+func ApplyMoves(channels []models.Channel, moves []Move) ([]models.Channel, error) {
+	byID := make(map[uuid.UUID]*models.Channel, len(channels))
+	result := make([]models.Channel, len(channels))
+	copy(result, channels)
+	for i := range result {
+		byID[result[i].ID] = &result[i]
+	}
+
+	// Apply the requested parents and remember the requested positions.
+	requested := make(map[uuid.UUID]int, len(moves))
+	order := make(map[uuid.UUID]int, len(moves))
+	for i, move := range moves {
+		channel, ok := byID[move.ID]
+		if !ok {
+			return nil, ErrUnknownChannel
+		}
+		if _, duplicate := requested[move.ID]; duplicate {
+			return nil, ErrDuplicateMove
+		}
+		if move.Position < 0 {
+			return nil, ErrInvalidPosition
+		}
+		if move.ParentSet {
+			channel.Parent = move.Parent
+		}
+		requested[move.ID] = move.Position
+		order[move.ID] = i
+	}
+
+	// Validate the placement of every channel, since moving a channel can affect its parent too.
+	childCount := make(map[uuid.UUID]int)
+	for _, channel := range result {
+		if channel.Parent != nil {
+			childCount[*channel.Parent]++
+		}
+	}
+	for i := range result {
+		channel := &result[i]
+		var parent *models.Channel
+		if channel.Parent != nil {
+			found, ok := byID[*channel.Parent]
+			if !ok {
+				return nil, ErrUnknownChannel
+			}
+			parent = found
+		}
+		if err := ValidatePlacement(channel, parent, childCount[channel.ID] > 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// Group the channels by sibling group and normalise the positions of each group.
+	groups := make(map[string][]*models.Channel)
+	for i := range result {
+		key := siblingGroup(&result[i])
+		groups[key] = append(groups[key], &result[i])
+	}
+	for _, siblings := range groups {
+		var moved, unmoved []*models.Channel
+		for _, channel := range siblings {
+			if _, ok := requested[channel.ID]; ok {
+				moved = append(moved, channel)
+			} else {
+				unmoved = append(unmoved, channel)
+			}
+		}
+		sort.SliceStable(unmoved, func(i, j int) bool {
+			if unmoved[i].Position != unmoved[j].Position {
+				return unmoved[i].Position < unmoved[j].Position
+			}
+			return unmoved[i].ID.String() < unmoved[j].ID.String()
+		})
+		sort.SliceStable(moved, func(i, j int) bool {
+			a, b := moved[i], moved[j]
+			if requested[a.ID] != requested[b.ID] {
+				return requested[a.ID] < requested[b.ID]
+			}
+			return order[a.ID] < order[b.ID]
+		})
+
+		ordered := unmoved
+		for _, channel := range moved {
+			index := requested[channel.ID]
+			if index > len(ordered) {
+				index = len(ordered)
+			}
+			ordered = append(ordered, nil)
+			copy(ordered[index+1:], ordered[index:])
+			ordered[index] = channel
+		}
+		for position, channel := range ordered {
+			channel.Position = position
+		}
+	}
+
+	// Collect the channels whose placement changed.
+	original := make(map[uuid.UUID]models.Channel, len(channels))
+	for _, channel := range channels {
+		original[channel.ID] = channel
+	}
+	var changed []models.Channel
+	for _, channel := range result {
+		before := original[channel.ID]
+		if before.Position != channel.Position || !sameParent(before.Parent, channel.Parent) {
+			changed = append(changed, channel)
+		}
+	}
+	return changed, nil
+}
+
+// NextPosition returns the position after the last sibling a new channel with the given
+// parent and type would have, so new channels are appended to the end of their group.
+func NextPosition(channels []models.Channel, newChannel *models.Channel) int {
+	key := siblingGroup(newChannel)
+	next := 0
+	for i := range channels {
+		if siblingGroup(&channels[i]) == key && channels[i].Position >= next {
+			next = channels[i].Position + 1
+		}
+	}
+	return next
+}
+
+// siblingGroup returns the key of the group a channel is ordered in.
+// Top-level channels and categories are ordered separately, because uncategorized
+// channels are always listed before the categories.
+func siblingGroup(channel *models.Channel) string {
+	if channel.Parent != nil {
+		return channel.Parent.String()
+	}
+	if channel.Type == models.ChannelTypeCategory {
+		return "top:categories"
+	}
+	return "top:channels"
+}
+
+// sameParent reports whether two parent references point to the same channel.
+func sameParent(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
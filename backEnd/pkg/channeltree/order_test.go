@@ -0,0 +1,163 @@
+package channeltree_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/channeltree"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// positionsOf returns the resulting position of every channel, applying the changed channels
+// returned by ApplyMoves on top of the original list.
+func positionsOf(channels []models.Channel, changed []models.Channel) map[string]models.Channel {
+	byName := make(map[string]models.Channel, len(channels))
+	for _, channel := range channels {
+		byName[channel.Name] = channel
+	}
+	for _, channel := range changed {
+		byName[channel.Name] = channel
+	}
+	return byName
+}
+
+// TestApplyMoves_ReorderWithinGroup tests moving a channel to the front of its sibling group.
+func TestApplyMoves_ReorderWithinGroup(t *testing.T) {
+	serverID := uuid.New()
+	a := newChannel(serverID, "a", models.ChannelTypeChat, nil)
+	b := newChannel(serverID, "b", models.ChannelTypeChat, nil)
+	c := newChannel(serverID, "c", models.ChannelTypeChat, nil)
+	a.Position, b.Position, c.Position = 0, 1, 2
+	channels := []models.Channel{a, b, c}
+
+	changed, err := channeltree.ApplyMoves(channels, []channeltree.Move{{ID: c.ID, Position: 0}})
+
+	assert.NoError(t, err)
+	result := positionsOf(channels, changed)
+	assert.Equal(t, 0, result["c"].Position)
+	assert.Equal(t, 1, result["a"].Position)
+	assert.Equal(t, 2, result["b"].Position)
+	assert.Len(t, changed, 3)
+}
+
+// TestApplyMoves_NormalisesGaps tests that sparse or duplicate positions are normalised.
+func TestApplyMoves_NormalisesGaps(t *testing.T) {
+	serverID := uuid.New()
+	a := newChannel(serverID, "a", models.ChannelTypeChat, nil)
+	b := newChannel(serverID, "b", models.ChannelTypeChat, nil)
+	c := newChannel(serverID, "c", models.ChannelTypeChat, nil)
+	a.Position, b.Position, c.Position = 5, 5, 40
+	channels := []models.Channel{a, b, c}
+
+	changed, err := channeltree.ApplyMoves(channels, []channeltree.Move{{ID: c.ID, Position: 99}})
+
+	assert.NoError(t, err)
+	result := positionsOf(channels, changed)
+	assert.ElementsMatch(t, []int{0, 1}, []int{result["a"].Position, result["b"].Position})
+	assert.Equal(t, 2, result["c"].Position)
+}
+
+// TestApplyMoves_ChangeParent tests moving a channel into a category and out of another one.
+func TestApplyMoves_ChangeParent(t *testing.T) {
+	serverID := uuid.New()
+	first := newChannel(serverID, "first", models.ChannelTypeCategory, nil)
+	second := newChannel(serverID, "second", models.ChannelTypeCategory, nil)
+	second.Position = 1
+	x := newChannel(serverID, "x", models.ChannelTypeChat, &first.ID)
+	y := newChannel(serverID, "y", models.ChannelTypeChat, &first.ID)
+	z := newChannel(serverID, "z", models.ChannelTypeChat, &second.ID)
+	y.Position = 1
+	channels := []models.Channel{first, second, x, y, z}
+
+	changed, err := channeltree.ApplyMoves(channels, []channeltree.Move{
+		{ID: x.ID, ParentSet: true, Parent: &second.ID, Position: 0},
+		{ID: z.ID, ParentSet: true, Parent: nil, Position: 0},
+	})
+
+	assert.NoError(t, err)
+	result := positionsOf(channels, changed)
+	assert.Equal(t, second.ID, *result["x"].Parent)
+	assert.Equal(t, 0, result["x"].Position)
+	assert.Nil(t, result["z"].Parent)
+	assert.Equal(t, 0, result["z"].Position)
+	assert.Equal(t, 0, result["y"].Position, "remaining sibling should close the gap")
+}
+
+// TestApplyMoves_Errors tests the moves that must be rejected.
+func TestApplyMoves_Errors(t *testing.T) {
+	serverID := uuid.New()
+	category := newChannel(serverID, "category", models.ChannelTypeCategory, nil)
+	other := newChannel(serverID, "other", models.ChannelTypeCategory, nil)
+	chat := newChannel(serverID, "chat", models.ChannelTypeChat, &category.ID)
+	voice := newChannel(serverID, "voice", models.ChannelTypeVoice, nil)
+	channels := []models.Channel{category, other, chat, voice}
+	unknown := uuid.New()
+
+	tests := []struct {
+		name  string
+		moves []channeltree.Move
+		want  error
+	}{
+		{
+			name:  "Invalid: Category inside a category",
+			moves: []channeltree.Move{{ID: other.ID, ParentSet: true, Parent: &category.ID}},
+			want:  channeltree.ErrCategoryNested,
+		},
+		{
+			name:  "Invalid: Channel inside a voice channel",
+			moves: []channeltree.Move{{ID: chat.ID, ParentSet: true, Parent: &voice.ID}},
+			want:  channeltree.ErrParentNotCategory,
+		},
+		{
+			name:  "Invalid: Unknown channel",
+			moves: []channeltree.Move{{ID: unknown}},
+			want:  channeltree.ErrUnknownChannel,
+		},
+		{
+			name:  "Invalid: Unknown parent",
+			moves: []channeltree.Move{{ID: chat.ID, ParentSet: true, Parent: &unknown}},
+			want:  channeltree.ErrUnknownChannel,
+		},
+		{
+			name:  "Invalid: Duplicate move",
+			moves: []channeltree.Move{{ID: chat.ID}, {ID: chat.ID, Position: 1}},
+			want:  channeltree.ErrDuplicateMove,
+		},
+		{
+			name:  "Invalid: Negative position",
+			moves: []channeltree.Move{{ID: chat.ID, Position: -1}},
+			want:  channeltree.ErrInvalidPosition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := channeltree.ApplyMoves(channels, tt.moves)
+			assert.Equal(t, tt.want, err)
+			assert.Nil(t, changed)
+		})
+	}
+	assert.Equal(t, &category.ID, channels[2].Parent, "input channels must not be modified")
+}
+
+// TestNextPosition tests that new channels are appended to the end of their sibling group.
+func TestNextPosition(t *testing.T) {
+	serverID := uuid.New()
+	category := newChannel(serverID, "category", models.ChannelTypeCategory, nil)
+	category.Position = 7
+	chat := newChannel(serverID, "chat", models.ChannelTypeChat, &category.ID)
+	chat.Position = 3
+	top := newChannel(serverID, "top", models.ChannelTypeChat, nil)
+	channels := []models.Channel{category, chat, top}
+
+	nested := newChannel(serverID, "new", models.ChannelTypeChat, &category.ID)
+	newCategory := newChannel(serverID, "new category", models.ChannelTypeCategory, nil)
+	topLevel := newChannel(serverID, "new top", models.ChannelTypeVoice, nil)
+	empty := newChannel(serverID, "first", models.ChannelTypeChat, &newCategory.ID)
+
+	assert.Equal(t, 4, channeltree.NextPosition(channels, &nested))
+	assert.Equal(t, 8, channeltree.NextPosition(channels, &newCategory))
+	assert.Equal(t, 1, channeltree.NextPosition(channels, &topLevel))
+	assert.Equal(t, 0, channeltree.NextPosition(channels, &empty))
+}
@@ -166,6 +166,7 @@ func ConnectMigrateDB(dbURL string) (*gorm.DB, error) {
 //
 // For such destructive or complex schema changes, consider using a dedicated
 // versioned migration tool (e.g., "golang-migrate/migrate") or manual SQL scripts.
+// Idempotent steps that AutoMigrate cannot express are run afterwards by runPostMigrations.
 //
 // If `isFullMigration` is true, this function will first **DROP ALL TABLES**
 // corresponding to the registered models before re-creating them. This is
@@ -198,6 +199,21 @@ func Migrate(db *gorm.DB, isFullMigration bool) {
 			&models.ServerUserConnect{},
 			&models.MediaAsset{},
 			&models.MessageAttachment{},
+			&models.Conversation{},
+			&models.ConversationParticipant{},
+			&models.Thread{},
+			&models.ThreadFollower{},
+			&models.MessageReaction{},
+			&models.ServerEmoji{},
+			&models.MessageMention{},
+			&models.ReadState{},
+			&models.MessageRevision{},
+			&models.GatewayEvent{},
+			&models.VoiceState{},
+			&models.Upload{},
+			&models.MediaThumbnail{},
+			&models.GatewaySession{},
+			&models.GatewaySessionFrame{},
 			// Add any new top-level models here.
 		)
 		log.Info().
@@ -214,6 +230,21 @@ func Migrate(db *gorm.DB, isFullMigration bool) {
 		&models.ServerUserConnect{},
 		&models.MediaAsset{},
 		&models.MessageAttachment{},
+		&models.Conversation{},
+		&models.ConversationParticipant{},
+		&models.Thread{},
+		&models.ThreadFollower{},
+		&models.MessageReaction{},
+		&models.ServerEmoji{},
+		&models.MessageMention{},
+		&models.ReadState{},
+		&models.MessageRevision{},
+		&models.GatewayEvent{},
+		&models.VoiceState{},
+		&models.Upload{},
+		&models.MediaThumbnail{},
+		&models.GatewaySession{},
+		&models.GatewaySessionFrame{},
 		// Add any new top-level models here.
 	)
 	if err != nil {
@@ -223,6 +254,14 @@ func Migrate(db *gorm.DB, isFullMigration bool) {
 			Str("event", "migration_auto_migrate_failure").
 			Msg("Failed to auto-migrate database")
 	}
+	// Run the schema changes AutoMigrate cannot express, see migrations.go.
+	if err := runPostMigrations(db); err != nil {
+		log.Fatal().
+			Err(err).
+			Str("component", "database").
+			Str("event", "migration_post_migration_failure").
+			Msg("Failed to run post-migration steps")
+	}
 	log.Info().
 		Str("component", "database").
 		Str("event", "migration_complete").
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// messageOwnerConstraint is the name of the check constraint that requires every message
+// to belong to exactly one channel or conversation.
+const messageOwnerConstraint = "chk_messages_owner"
+
+// messageSearchIndex is the name of the GIN index on the content_tsv column of the messages table.
+const messageSearchIndex = "idx_messages_content_search"
+
+// legacyMessageContentIndex is the name of the B-tree index that was used on message content
+// before full-text search. It cannot serve text search, so it is dropped.
+const legacyMessageContentIndex = "idx_content_type_search"
+
+// runPostMigrations runs the idempotent schema changes that GORM's AutoMigrate cannot express.
+// Every step checks the current schema first, so running it repeatedly is safe.
+//
+// Parameters:
+//
+//	db: The GORM database instance on which to run the steps.
+//
+// Returns:
+//
+//	error: An error if any step fails.
+func runPostMigrations(db *gorm.DB) error {
+	if err := ensureMessageOwnerConstraint(db); err != nil {
+		return err
+	}
+	if err := ensureMessageSearch(db); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ensureMessageOwnerConstraint adds the chk_messages_owner check constraint to the messages table.
+//
+// Messages created before the channel_id and conversation_id columns existed have no owner.
+// If such rows exist, the constraint is added as NOT VALID: PostgreSQL then enforces it for
+// every new or updated row, but skips the existing ones. These legacy rows are not reachable
+// through any channel or conversation. Once they are reassigned or deleted, the constraint can
+// be validated with:
+//
+//	ALTER TABLE messages VALIDATE CONSTRAINT chk_messages_owner;
+func ensureMessageOwnerConstraint(db *gorm.DB) error {
+	if db.Migrator().HasConstraint(&models.Message{}, messageOwnerConstraint) {
+		return nil
+	}
+
+	var orphanCount int64
+	if err := db.Model(&models.Message{}).
+		Where("channel_id IS NULL AND conversation_id IS NULL").
+		Count(&orphanCount).Error; err != nil {
+		return fmt.Errorf("failed to count messages without owner: %w", err)
+	}
+
+	statement := fmt.Sprintf(
+		"ALTER TABLE messages ADD CONSTRAINT %s CHECK (num_nonnulls(channel_id, conversation_id) = 1)",
+		messageOwnerConstraint,
+	)
+	if orphanCount > 0 {
+		statement += " NOT VALID"
+		log.Warn().
+			Str("component", "database").
+			Str("event", "migration_legacy_messages_without_owner").
+			Int64("message_count", orphanCount).
+			Str("constraint_name", messageOwnerConstraint).
+			Msg("Existing messages have no channel or conversation. The owner constraint is added as NOT VALID and only enforced for new rows.")
+	}
+
+	if err := db.Exec(statement).Error; err != nil {
+		return fmt.Errorf("failed to add %s constraint: %w", messageOwnerConstraint, err)
+	}
+
+	log.Info().
+		Str("component", "database").
+		Str("event", "migration_constraint_added").
+		Str("constraint_name", messageOwnerConstraint).
+		Bool("validated", orphanCount == 0).
+		Msg("Message owner constraint added.")
+	return nil
+}
+
+// ensureMessageSearch adds the full-text search column of message content and its GIN index.
+//
+// content_tsv is a stored generated column, so PostgreSQL keeps it in sync with the content and
+// it is not part of the GORM model. The simple configuration is used because messages are
+// written in many languages: words are lower-cased but not stemmed.
+func ensureMessageSearch(db *gorm.DB) error {
+	if err := db.Exec("DROP INDEX IF EXISTS " + legacyMessageContentIndex).Error; err != nil {
+		return fmt.Errorf("failed to drop %s index: %w", legacyMessageContentIndex, err)
+	}
+
+	if !db.Migrator().HasColumn(&models.Message{}, "content_tsv") {
+		statement := "ALTER TABLE messages ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED"
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to add content_tsv column: %w", err)
+		}
+		log.Info().
+			Str("component", "database").
+			Str("event", "migration_column_added").
+			Str("column_name", "content_tsv").
+			Msg("Message search column added.")
+	}
+
+	statement := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON messages USING GIN (content_tsv)", messageSearchIndex)
+	if err := db.Exec(statement).Error; err != nil {
+		return fmt.Errorf("failed to create %s index: %w", messageSearchIndex, err)
+	}
+	return nil
+}
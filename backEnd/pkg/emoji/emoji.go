@@ -0,0 +1,155 @@
+// Package emoji parses the emoji used in reactions and message content.
+// An emoji is either a Unicode emoji sequence or a custom server emoji, which is written as
+// name:id and identified by its ID alone. In message content custom emoji are written as
+// <:name:id>, or <a:name:id> when animated, and typed by users as :name: shortcodes.
+package emoji
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// MAX_UNICODE_EMOJI_BYTES is the longest Unicode emoji sequence accepted, long enough for
+// ZWJ sequences such as family and flag emoji.
+const MAX_UNICODE_EMOJI_BYTES = 64
+
+// NAME_PATTERN defines the regex for valid custom emoji names: 2-32 letters, digits or underscores.
+const NAME_PATTERN = `^[A-Za-z0-9_]{2,32}$`
+
+// contentTokenRegex matches custom emoji already written in the content format, which are
+// kept as they are, and :name: shortcodes, capturing the name.
+var contentTokenRegex = regexp.MustCompile(`<a?:[A-Za-z0-9_]{2,32}:[0-9a-fA-F-]{36}>|:([A-Za-z0-9_]{2,32}):`)
+
+// ErrInvalidEmoji is returned when a string is neither a Unicode emoji nor a custom emoji reference.
+var ErrInvalidEmoji = errors.New("the emoji must be a Unicode emoji or a custom emoji in the name:id format")
+
+// Emoji is a parsed emoji.
+type Emoji struct {
+	// Unicode is the emoji sequence, empty for custom emoji.
+	Unicode string
+	// CustomID is the ID of a custom server emoji, nil for Unicode emoji.
+	CustomID *uuid.UUID
+}
+
+// Key returns the value an emoji is stored and compared by: the sequence of a Unicode emoji,
+// or the ID of a custom emoji, so renaming a custom emoji keeps its reactions together.
+func (e Emoji) Key() string {
+	if e.CustomID != nil {
+		return e.CustomID.String()
+	}
+	return e.Unicode
+}
+
+// IsCustom reports whether the emoji is a custom server emoji.
+func (e Emoji) IsCustom() bool {
+	return e.CustomID != nil
+}
+
+// Parse parses a Unicode emoji, a custom emoji reference in the name:id format or the bare
+// ID of a custom emoji, which is the format returned by Key.
+// params:
+// - value: The emoji to parse.
+// returns:
+// - Emoji: The parsed emoji.
+// - error: ErrInvalidEmoji if the value is not an emoji.
+func Parse(value string) (Emoji, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		return Emoji{CustomID: &id}, nil
+	}
+	if name, rawID, found := strings.Cut(value, ":"); found {
+		id, err := uuid.Parse(rawID)
+		if err != nil || name == "" {
+			return Emoji{}, ErrInvalidEmoji
+		}
+		return Emoji{CustomID: &id}, nil
+	}
+	if !IsUnicode(value) {
+		return Emoji{}, ErrInvalidEmoji
+	}
+	return Emoji{Unicode: value}, nil
+}
+
+// IsUnicode reports whether the value is a single Unicode emoji sequence. It accepts pictographic
+// symbols combined with joiners, variation selectors, skin tone modifiers, keycaps and tags,
+// without validating that the combination is a recommended emoji sequence.
+func IsUnicode(value string) bool {
+	if value == "" || len(value) > MAX_UNICODE_EMOJI_BYTES || !utf8.ValidString(value) {
+		return false
+	}
+	hasSymbol := false
+	hasKeycap := strings.ContainsRune(value, '\u20e3')
+	for _, r := range value {
+		switch {
+		case unicode.Is(unicode.So, r):
+			hasSymbol = true
+		case r == '\u200d', r == '\ufe0e', r == '\ufe0f', r == '\u20e3':
+			// Zero width joiner, variation selectors and the combining keycap.
+		case r >= 0x1F3FB && r <= 0x1F3FF:
+			// Skin tone modifiers.
+		case r >= 0xE0020 && r <= 0xE007F:
+			// Tag characters of subdivision flags.
+		case hasKeycap && (r == '#' || r == '*' || (r >= '0' && r <= '9')):
+			hasSymbol = true
+		default:
+			return false
+		}
+	}
+	return hasSymbol
+}
+
+// Custom is a custom server emoji as written in message content.
+type Custom struct {
+	Name     string
+	ID       uuid.UUID
+	Animated bool
+}
+
+// String returns the content format of the emoji, <:name:id> or <a:name:id> when animated.
+func (c Custom) String() string {
+	prefix := "<:"
+	if c.Animated {
+		prefix = "<a:"
+	}
+	return prefix + c.Name + ":" + c.ID.String() + ">"
+}
+
+// Shortcodes returns the distinct names of the :name: shortcodes in the content, in order of
+// their first appearance. Custom emoji already in the content format are not included.
+func Shortcodes(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range contentTokenRegex.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// ReplaceShortcodes replaces the :name: shortcodes of known custom emoji in the content with
+// their content format. Unknown shortcodes are left as typed.
+// params:
+// - content: The message content.
+// - known: The custom emoji available to the author, by name.
+// returns:
+// - string: The content with the known shortcodes resolved.
+func ReplaceShortcodes(content string, known map[string]Custom) string {
+	return contentTokenRegex.ReplaceAllStringFunc(content, func(token string) string {
+		if !strings.HasPrefix(token, ":") {
+			return token
+		}
+		custom, ok := known[strings.Trim(token, ":")]
+		if !ok {
+			return token
+		}
+		return custom.String()
+	})
+}
@@ -0,0 +1,124 @@
+package emoji_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/emoji"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsUnicode tests the IsUnicode function.
+func TestIsUnicode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "Valid: Simple emoji", input: "😀", want: true},
+		{name: "Valid: Emoji with variation selector", input: "❤️", want: true},
+		{name: "Valid: Skin tone modifier", input: "👍🏽", want: true},
+		{name: "Valid: ZWJ sequence", input: "👩‍💻", want: true},
+		{name: "Valid: Flag", input: "🇭🇺", want: true},
+		{name: "Valid: Keycap", input: "1️⃣", want: true},
+		{name: "Invalid: Empty", input: "", want: false},
+		{name: "Invalid: Text", input: "smile", want: false},
+		{name: "Invalid: Emoji followed by text", input: "😀a", want: false},
+		{name: "Invalid: Digit without keycap", input: "1", want: false},
+		{name: "Invalid: Lone joiner", input: "\u200d", want: false},
+		{name: "Invalid: Too long", input: "😀😀😀😀😀😀😀😀😀😀😀😀😀😀😀😀😀", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, emoji.IsUnicode(tt.input))
+		})
+	}
+}
+
+// TestParse tests parsing Unicode emoji and custom emoji references.
+func TestParse(t *testing.T) {
+	const id = "6f9619ff-8b86-d011-b42d-00cf4fc964ff"
+
+	tests := []struct {
+		name    string
+		input   string
+		wantKey string
+		custom  bool
+		wantErr bool
+	}{
+		{name: "Valid: Unicode emoji", input: "🎉", wantKey: "🎉"},
+		{name: "Valid: Custom emoji reference", input: "party:" + id, wantKey: id, custom: true},
+		{name: "Valid: Custom emoji ID", input: id, wantKey: id, custom: true},
+		{name: "Invalid: Custom emoji without name", input: ":" + id, wantErr: true},
+		{name: "Invalid: Custom emoji with invalid ID", input: "party:123", wantErr: true},
+		{name: "Invalid: Shortcode", input: ":party:", wantErr: true},
+		{name: "Invalid: Text", input: "party", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := emoji.Parse(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, emoji.ErrInvalidEmoji)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKey, got.Key())
+			assert.Equal(t, tt.custom, got.IsCustom())
+		})
+	}
+}
+
+// TestShortcodes tests collecting the shortcodes of message content.
+func TestShortcodes(t *testing.T) {
+	const id = "6f9619ff-8b86-d011-b42d-00cf4fc964ff"
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "No shortcodes", content: "hello there", want: nil},
+		{name: "Single shortcode", content: "gg :party:", want: []string{"party"}},
+		{name: "Adjacent shortcodes", content: ":party::wave:", want: []string{"party", "wave"}},
+		{name: "Duplicates are collected once", content: ":wave: hi :wave:", want: []string{"wave"}},
+		{name: "Resolved emoji are skipped", content: "<:party:" + id + "> :wave:", want: []string{"wave"}},
+		{name: "Too short names are ignored", content: ":a:", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, emoji.Shortcodes(tt.content))
+		})
+	}
+}
+
+// TestReplaceShortcodes tests resolving shortcodes to the content format of custom emoji.
+func TestReplaceShortcodes(t *testing.T) {
+	partyID := uuid.MustParse("6f9619ff-8b86-d011-b42d-00cf4fc964ff")
+	waveID := uuid.MustParse("0e3b1e3a-5d43-4bb4-9df5-2f0b5c6b8a11")
+	known := map[string]emoji.Custom{
+		"party": {Name: "party", ID: partyID},
+		"wave":  {Name: "wave", ID: waveID, Animated: true},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "Known shortcode", content: "gg :party:", want: "gg <:party:" + partyID.String() + ">"},
+		{name: "Animated emoji", content: ":wave:", want: "<a:wave:" + waveID.String() + ">"},
+		{name: "Unknown shortcode is kept", content: ":unknown:", want: ":unknown:"},
+		{name: "Resolved emoji are kept", content: "<:party:" + partyID.String() + ">", want: "<:party:" + partyID.String() + ">"},
+		{name: "Times are not shortcodes", content: "at 10:30:45", want: "at 10:30:45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, emoji.ReplaceShortcodes(tt.content, known))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"slices"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Access lists the servers and conversations a user can subscribe to.
+type Access struct {
+	ServerIDs       []uuid.UUID
+	ConversationIDs []uuid.UUID
+}
+
+// ids returns the IDs of the servers and conversations.
+func (a Access) ids() []uuid.UUID {
+	return append(slices.Clone(a.ServerIDs), a.ConversationIDs...)
+}
+
+// AccessFunc lists the servers and conversations of a user.
+type AccessFunc func(userID uuid.UUID) (Access, error)
+
+// DatabaseAccess lists the servers a user owns or is a member of and the conversations they take
+// part in from the database.
+// params:
+// - db: The GORM database instance.
+// returns:
+// - AccessFunc: The function querying the access of a user.
+func DatabaseAccess(db *gorm.DB) AccessFunc {
+	return func(userID uuid.UUID) (Access, error) {
+		var access Access
+		if err := db.Model(&models.Server{}).Where("id IN (?)", permissions.MemberServers(db, userID)).Pluck("id", &access.ServerIDs).Error; err != nil {
+			return Access{}, err
+		}
+		if err := db.Model(&models.Conversation{}).Where("id IN (?)", permissions.ReadableConversations(db, userID)).Pluck("id", &access.ConversationIDs).Error; err != nil {
+			return Access{}, err
+		}
+		return access, nil
+	}
+}
@@ -0,0 +1,40 @@
+package gateway
+
+// Bus carries the events published on an API instance to the hubs of every instance.
+type Bus interface {
+	// Publish delivers an event to the hub of this instance and to the other instances.
+	Publish(event Event) error
+	// Close stops receiving events from the other instances.
+	Close() error
+}
+
+// LocalBus delivers events to the hub of this instance only, for deployments running one instance.
+type LocalBus struct {
+	deliver func(Event)
+}
+
+// NewLocalBus creates a bus delivering events in-process.
+// params:
+// - deliver: The function delivering an event to the hub, usually Hub.Publish.
+// returns:
+// - *LocalBus: The bus.
+func NewLocalBus(deliver func(Event)) *LocalBus {
+	return &LocalBus{deliver: deliver}
+}
+
+// Publish delivers an event to the hub.
+// params:
+// - event: The event to publish.
+// returns:
+// - error: Always nil.
+func (b *LocalBus) Publish(event Event) error {
+	b.deliver(event)
+	return nil
+}
+
+// Close does nothing, the local bus holds no resources.
+// returns:
+// - error: Always nil.
+func (b *LocalBus) Close() error {
+	return nil
+}
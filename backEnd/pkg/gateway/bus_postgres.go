@@ -0,0 +1,229 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// POSTGRES_BUS_CHANNEL is the notification channel the instances share by default.
+	POSTGRES_BUS_CHANNEL = "gateway_events"
+	// MAX_NOTIFY_PAYLOAD is the largest notification payload sent in bytes, below the 8000 bytes
+	// Postgres accepts. Larger events are stored in the gateway_events table.
+	MAX_NOTIFY_PAYLOAD = 7900
+	// EVENT_RETENTION is how long stored events are kept for the instances to load them.
+	EVENT_RETENTION = 5 * time.Minute
+	// RECONNECT_MIN_DELAY is the delay before the listener first reconnects after losing its connection.
+	RECONNECT_MIN_DELAY = 250 * time.Millisecond
+	// RECONNECT_MAX_DELAY is the longest delay between two reconnection attempts.
+	RECONNECT_MAX_DELAY = 30 * time.Second
+)
+
+// busMessage is the notification payload of an event, or the body of a stored one.
+type busMessage struct {
+	// Origin is the bus that published the event, which delivered it to its hub already.
+	Origin uuid.UUID `json:"origin"`
+	// Ref is the ID of the stored event when the event was too large for the notification.
+	Ref     *uuid.UUID      `json:"ref,omitempty"`
+	Type    EventType       `json:"type,omitempty"`
+	Topics  []uuid.UUID     `json:"topics,omitempty"`
+	Refresh []uuid.UUID     `json:"refresh,omitempty"`
+	Exclude []uuid.UUID     `json:"exclude,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// PostgresBus carries events between the instances sharing a Postgres database with LISTEN and
+// NOTIFY. Events are delivered to the hub of the publishing instance directly and sent to the
+// others as notifications. A dedicated connection listens for them and reconnects with backoff
+// when it is lost, events published while it is disconnected do not reach this instance.
+type PostgresBus struct {
+	db      *gorm.DB
+	dsn     string
+	channel string
+	origin  uuid.UUID
+	deliver func(Event)
+
+	listening atomic.Bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewPostgresBus creates a bus and starts listening for the events of the other instances.
+// params:
+// - db: The GORM database instance used to send notifications and store large events.
+// - dsn: The connection string of the database, for the listening connection.
+// - channel: The notification channel, POSTGRES_BUS_CHANNEL unless instances are separated.
+// - deliver: The function delivering an event to the hub, usually Hub.Publish.
+// returns:
+// - *PostgresBus: The bus, listening in the background.
+func NewPostgresBus(db *gorm.DB, dsn string, channel string, deliver func(Event)) *PostgresBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &PostgresBus{
+		db:      db,
+		dsn:     dsn,
+		channel: channel,
+		origin:  uuid.New(),
+		deliver: deliver,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go b.listen()
+	return b
+}
+
+// Publish delivers an event to the hub and notifies the other instances.
+// params:
+// - event: The event to publish.
+// returns:
+// - error: The error encoding, storing or sending the event.
+func (b *PostgresBus) Publish(event Event) error {
+	b.deliver(event)
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	message := busMessage{Origin: b.origin, Type: event.Type, Topics: event.Topics, Refresh: event.Refresh, Exclude: event.Exclude, Data: data}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if len(payload) <= MAX_NOTIFY_PAYLOAD {
+		return b.db.Exec("SELECT pg_notify(?, ?)", b.channel, string(payload)).Error
+	}
+
+	// Too large for a notification, the other instances load the event by its ID.
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		stored := models.GatewayEvent{Payload: string(payload)}
+		if err := tx.Create(&stored).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("created_at < ?", time.Now().Add(-EVENT_RETENTION)).Delete(&models.GatewayEvent{}).Error; err != nil {
+			return err
+		}
+		reference, err := json.Marshal(busMessage{Origin: b.origin, Ref: &stored.ID})
+		if err != nil {
+			return err
+		}
+		// Notifications sent in a transaction are delivered once it commits.
+		return tx.Exec("SELECT pg_notify(?, ?)", b.channel, string(reference)).Error
+	})
+}
+
+// Listening reports whether the bus is currently listening for the events of the other instances.
+// returns:
+// - bool: True while the listening connection is up.
+func (b *PostgresBus) Listening() bool {
+	return b.listening.Load()
+}
+
+// Close stops listening and waits for the listening connection to close.
+// returns:
+// - error: Always nil.
+func (b *PostgresBus) Close() error {
+	b.cancel()
+	<-b.done
+	return nil
+}
+
+// listen keeps a listening connection open until the bus is closed, reconnecting with backoff.
+func (b *PostgresBus) listen() {
+	defer close(b.done)
+	delay := RECONNECT_MIN_DELAY
+	for {
+		err := b.listenOnce(func() { delay = RECONNECT_MIN_DELAY })
+		b.listening.Store(false)
+		if b.ctx.Err() != nil {
+			return
+		}
+		log.Warn().
+			Str("component", "gateway").
+			Str("method_name", "listen").
+			Str("event", "bus_listener_disconnected").
+			Str("channel", b.channel).
+			Dur("retry_in", delay).
+			Err(err).
+			Msg("Lost the event bus connection, reconnecting.")
+
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, RECONNECT_MAX_DELAY)
+	}
+}
+
+// listenOnce opens a listening connection and receives notifications until it fails.
+func (b *PostgresBus) listenOnce(connected func()) error {
+	conn, err := pgx.Connect(b.ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(b.ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+	b.listening.Store(true)
+	connected()
+	log.Info().
+		Str("component", "gateway").
+		Str("method_name", "listen").
+		Str("event", "bus_listening").
+		Str("channel", b.channel).
+		Msg("Listening for the events of the other instances.")
+
+	for {
+		notification, err := conn.WaitForNotification(b.ctx)
+		if err != nil {
+			return err
+		}
+		b.receive(notification.Payload)
+	}
+}
+
+// receive delivers an event notified by another instance, loading it first if it was stored.
+func (b *PostgresBus) receive(payload string) {
+	var message busMessage
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "receive").
+			Str("event", "bus_payload_invalid").
+			Err(err).
+			Msg("Could not decode an event bus notification.")
+		return
+	}
+	if message.Origin == b.origin {
+		return
+	}
+	if message.Ref != nil {
+		var stored models.GatewayEvent
+		err := b.db.WithContext(b.ctx).First(&stored, "id = ?", *message.Ref).Error
+		if err == nil {
+			err = json.Unmarshal([]byte(stored.Payload), &message)
+		}
+		if err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "receive").
+				Str("event", "bus_event_load_failed").
+				Str("event_id", message.Ref.String()).
+				Err(err).
+				Msg("Could not load a stored event bus event.")
+			return
+		}
+	}
+	b.deliver(Event{Type: message.Type, Topics: message.Topics, Data: message.Data, Refresh: message.Refresh, Exclude: message.Exclude})
+}
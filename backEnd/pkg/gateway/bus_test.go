@@ -0,0 +1,136 @@
+package gateway_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recorder records the events delivered by a bus.
+type recorder struct {
+	mu     sync.Mutex
+	events []gateway.Event
+}
+
+func (r *recorder) deliver(event gateway.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// wait waits until count events were delivered and returns them.
+func (r *recorder) wait(t *testing.T, count int) []gateway.Event {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.events) >= count
+	}, 5*time.Second, 5*time.Millisecond)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]gateway.Event(nil), r.events...)
+}
+
+// TestLocalBus tests delivering events in-process.
+func TestLocalBus(t *testing.T) {
+	var received recorder
+	bus := gateway.NewLocalBus(received.deliver)
+	event := gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{sharedServer}, Data: "hello"}
+	require.NoError(t, bus.Publish(event))
+	assert.Equal(t, []gateway.Event{event}, received.wait(t, 1))
+	assert.NoError(t, bus.Close())
+}
+
+// newPostgresBuses opens two buses on a channel of their own in the database of DATABASE_URL.
+func newPostgresBuses(t *testing.T) (*gorm.DB, string, [2]*gateway.PostgresBus, [2]*recorder) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.GatewayEvent{}))
+
+	channel := "gateway_events_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	var buses [2]*gateway.PostgresBus
+	var recorders [2]*recorder
+	for i := range buses {
+		recorders[i] = &recorder{}
+		buses[i] = gateway.NewPostgresBus(db, dsn, channel, recorders[i].deliver)
+		bus := buses[i]
+		t.Cleanup(func() { bus.Close() })
+	}
+	for _, bus := range buses {
+		require.Eventually(t, bus.Listening, 5*time.Second, 5*time.Millisecond)
+	}
+	return db, channel, buses, recorders
+}
+
+// TestPostgresBus tests relaying events between two instances.
+func TestPostgresBus(t *testing.T) {
+	_, _, buses, recorders := newPostgresBuses(t)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"notification payload", "hello"},
+		// Too large for a notification, the event is stored and loaded by its ID.
+		{"stored event", strings.Repeat("x", 3*gateway.MAX_NOTIFY_PAYLOAD)},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := gateway.Event{
+				Type:    gateway.EventMessageCreate,
+				Topics:  []uuid.UUID{sharedServer, conversation},
+				Data:    tt.data,
+				Refresh: []uuid.UUID{bob},
+				Exclude: []uuid.UUID{alice},
+			}
+			require.NoError(t, buses[0].Publish(event))
+
+			received := recorders[1].wait(t, i+1)[i]
+			assert.Equal(t, event.Type, received.Type)
+			assert.Equal(t, event.Topics, received.Topics)
+			assert.Equal(t, event.Refresh, received.Refresh)
+			assert.Equal(t, event.Exclude, received.Exclude)
+			expected, err := json.Marshal(tt.data)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(received.Data.(json.RawMessage)))
+
+			// The publishing instance delivers the event once, without its own notification.
+			assert.Equal(t, event, recorders[0].wait(t, i+1)[i])
+		})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, recorders[0].wait(t, len(tests)), len(tests))
+}
+
+// TestPostgresBusReconnect tests that the bus listens again after losing its connection.
+func TestPostgresBusReconnect(t *testing.T) {
+	db, channel, buses, recorders := newPostgresBuses(t)
+
+	require.NoError(t, db.Exec(
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query = ?",
+		`LISTEN "`+channel+`"`,
+	).Error)
+	require.Eventually(t, func() bool { return !buses[0].Listening() && !buses[1].Listening() }, 5*time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return buses[0].Listening() && buses[1].Listening() }, 5*time.Second, 5*time.Millisecond)
+
+	require.NoError(t, buses[1].Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{sharedServer}, Data: "after reconnecting"}))
+	received := recorders[0].wait(t, 1)
+	assert.JSONEq(t, `"after reconnecting"`, string(received[0].Data.(json.RawMessage)))
+}
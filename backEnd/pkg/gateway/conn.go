@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// Conn is a gateway connection of a user.
+type Conn struct {
+	hub    *Hub
+	ws     *websocket.Conn
+	userID uuid.UUID
+
+	// outbound queues the encoded frames to write.
+	outbound chan []byte
+	// done is closed when the connection closes.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// session is the session of the connection, nil until the client identifies or resumes.
+	// It is only accessed by the read loop.
+	session *session
+}
+
+// newConn creates a connection without session. Its queue also holds a full replay, so resuming
+// never counts as a slow consumer.
+func newConn(hub *Hub, ws *websocket.Conn, userID uuid.UUID) *Conn {
+	return &Conn{
+		hub:      hub,
+		ws:       ws,
+		userID:   userID,
+		outbound: make(chan []byte, hub.SendBuffer+hub.ReplayBuffer),
+		done:     make(chan struct{}),
+	}
+}
+
+// send queues an encoded frame. A connection whose queue is full is closed as a slow consumer,
+// so one client that stopped reading never holds back the events of the others.
+func (c *Conn) send(_ uint64, frame []byte) {
+	select {
+	case <-c.done:
+	case c.outbound <- frame:
+	default:
+		log.Warn().
+			Str("component", "gateway").
+			Str("method_name", "send").
+			Str("event", "slow_consumer_disconnected").
+			Str("user_id", c.userID.String()).
+			Msg("Closing a gateway connection that does not read its events.")
+		c.close(CloseSlowConsumer, "slow consumer")
+	}
+}
+
+// queue encodes and queues an unsequenced frame.
+func (c *Conn) queue(op Opcode, data any) {
+	frame, err := encodeFrame(op, data)
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "queue").
+			Str("event", "frame_encode_failed").
+			Str("op", string(op)).
+			Err(err).
+			Msg("Could not encode gateway frame.")
+		return
+	}
+	c.send(0, frame)
+}
+
+// close sends a close frame and closes the connection, only the first call has an effect.
+// The close frame is written in the background, as it waits for a pending write of the write
+// loop and a slow consumer would otherwise hold back the publisher.
+func (c *Conn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		go func() {
+			message := websocket.FormatCloseMessage(code, reason)
+			_ = c.ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(WRITE_TIMEOUT))
+			_ = c.ws.Close()
+		}()
+	})
+}
+
+// writeLoop writes the queued frames and pings the client until the connection closes.
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(PING_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case frame := <-c.outbound:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+			if err := c.ws.WriteMessage(websocket.TextMessage, frame); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(WRITE_TIMEOUT)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+// readLoop reads the frames of the client until the connection closes or stays silent for too long.
+func (c *Conn) readLoop() {
+	extend := func() {
+		_ = c.ws.SetReadDeadline(time.Now().Add(HEARTBEAT_TIMEOUT))
+	}
+	c.ws.SetReadLimit(MAX_FRAME_SIZE)
+	c.ws.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+	extend()
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		extend()
+
+		var frame Frame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			c.close(CloseInvalidFrame, "frames must be JSON envelopes")
+			return
+		}
+		c.handle(frame)
+	}
+}
+
+// handle answers a frame of the client.
+func (c *Conn) handle(frame Frame) {
+	switch frame.Op {
+	case OpHeartbeat:
+		c.queue(OpHeartbeatAck, nil)
+	case OpIdentify:
+		if c.session != nil {
+			c.queue(OpError, ErrorData{Message: "the connection already has a session"})
+			return
+		}
+		c.session = c.hub.identify(c, c.userID)
+	case OpResume:
+		if c.session != nil {
+			c.queue(OpError, ErrorData{Message: "the connection already has a session"})
+			return
+		}
+		var request ResumeData
+		if err := json.Unmarshal(frame.Data, &request); err != nil || request.SessionID == uuid.Nil {
+			c.queue(OpError, ErrorData{Message: "resume needs a session_id and seq"})
+			return
+		}
+		c.session = c.hub.resume(c, c.userID, request, true)
+		if c.session == nil {
+			c.queue(OpInvalidSession, nil)
+		}
+	case OpSubscribe, OpUnsubscribe:
+		if c.session == nil {
+			c.queue(OpError, ErrorData{Message: "identify or resume first"})
+			return
+		}
+		var request SubscribeData
+		if err := json.Unmarshal(frame.Data, &request); err != nil || len(request.IDs) == 0 {
+			c.queue(OpError, ErrorData{Message: "subscriptions need a non-empty ids list"})
+			return
+		}
+		if len(request.IDs) > MAX_SUBSCRIBE_IDS {
+			c.queue(OpError, ErrorData{Message: "too many ids in one frame"})
+			return
+		}
+		c.session.subscribe(request.IDs, frame.Op == OpSubscribe)
+		c.session.refresh()
+	case OpVoiceStateUpdate:
+		if c.session == nil {
+			c.queue(OpError, ErrorData{Message: "identify or resume first"})
+			return
+		}
+		var request VoiceStateUpdateData
+		if err := json.Unmarshal(frame.Data, &request); err != nil {
+			c.queue(OpError, ErrorData{Message: "voice state updates need a channel_id or null"})
+			return
+		}
+		if err := c.hub.updateVoice(c.session, request); err != nil {
+			c.queue(OpError, ErrorData{Message: voiceError(c.userID, err)})
+		}
+	case OpVoiceSignal:
+		if c.session == nil {
+			c.queue(OpError, ErrorData{Message: "identify or resume first"})
+			return
+		}
+		var request VoiceSignalRequest
+		err := json.Unmarshal(frame.Data, &request)
+		if err != nil || request.SessionID == uuid.Nil || len(request.Data) == 0 ||
+			(request.Type != VoiceSignalOffer && request.Type != VoiceSignalAnswer && request.Type != VoiceSignalCandidate) {
+			c.queue(OpError, ErrorData{Message: "voice signals need a session_id, an offer, answer or candidate type and data"})
+			return
+		}
+		if err := c.hub.relaySignal(c.session, request); err != nil {
+			c.queue(OpError, ErrorData{Message: voiceError(c.userID, err)})
+		}
+	default:
+		c.queue(OpError, ErrorData{Message: "unknown op " + string(frame.Op)})
+	}
+}
@@ -0,0 +1,315 @@
+// Package gateway pushes chat events to clients connected over WebSocket.
+//
+// Clients connect to GET /api/gateway with a JWT, in the Authorization header or, as browsers
+// cannot set headers on WebSocket requests, in the token query parameter. Every frame in both
+// directions is a JSON envelope:
+//
+//	{"op": "dispatch", "type": "MESSAGE_CREATE", "seq": 42, "data": {...}}
+//
+// op is one of the Opcode values, type and seq are only set on dispatch frames and data depends on
+// both. After connecting the server sends a hello frame holding the heartbeat interval in
+// milliseconds, {"heartbeat_interval": 30000}. The client then starts a session with an identify
+// frame, answered with a READY dispatch holding the session ID and the subscriptions. The client
+// sends a heartbeat frame every interval and the server answers with heartbeat_ack. The server
+// also pings the connection, connections silent for longer than HEARTBEAT_TIMEOUT are closed.
+//
+// Every dispatch of a session carries the next number of its sequence. When the connection drops
+// the session is kept for SESSION_TIMEOUT and keeps buffering its last REPLAY_BUFFER dispatches. A
+// client reconnecting in time sends a resume frame instead of identify, {"session_id": "...",
+// "seq": 41}, and receives the dispatches it missed followed by a RESUMED dispatch. If the session
+// expired, belongs to another user or missed more dispatches than the buffer holds, the server
+// answers with invalid_session and the client identifies again, fetching what it missed over HTTP.
+//
+// A session is subscribed to every server and conversation of its user by default and always
+// receives the events of the user itself. The client narrows or widens the subscriptions with
+// subscribe and unsubscribe frames holding {"ids": [...]}, answered with a SUBSCRIPTIONS_UPDATE
+// dispatch. Clients that cannot keep up with their events are disconnected with the
+// CloseSlowConsumer close code and are expected to resume.
+//
+// Voice channels use WebRTC between the connected clients, the gateway only relays the signaling.
+// A client joins a voice channel with a voice_state_update frame, {"channel_id": "...", "muted":
+// false, "deafened": false, "streaming": false, "speaking": false}, sends it again when its flags
+// change and leaves with a null channel_id. A user is in one voice channel at a time, and leaves
+// it when the session expires. Every change is dispatched to the server of the channel as a
+// VOICE_STATE_UPDATE, GET /api/servers/{id}/channels/{channelId}/voice lists the participants.
+// The peers form a mesh: the joining client sends an offer to the session of every participant
+// with voice_signal frames, {"session_id": "...", "type": "offer", "data": {...}}, and receives
+// their answers and ICE candidates as VOICE_SIGNAL dispatches.
+//
+// Clients that cannot open WebSocket connections receive the same frames over Server-Sent Events
+// from GET /api/events/stream, or by long polling GET /api/events/poll. SSE events carry the
+// session ID and sequence number as their ID, so EventSource resumes the session through the
+// Last-Event-ID header when it reconnects. Streams and polls end after STREAM_TIMEOUT and
+// POLL_TIMEOUT to fit the execution limits of serverless functions.
+//
+// Events reach the hubs of all instances through a Bus: LocalBus for a single instance, or
+// PostgresBus relaying them with LISTEN and NOTIFY when instances share the database. Sessions are
+// held by the hub of one instance, which buffers their dispatches. Without a SessionStore they can
+// only be resumed on that instance. With DatabaseSessions the dispatches are also written to the
+// database in the background, and an instance resuming a session takes it over with its stored
+// dispatches, the previous instance dropping its copy. A dispatch made moments before the session
+// is taken over can still be unwritten and is then not replayed. Events published while the
+// instance holding a session does not run, such as a suspended serverless function, are not
+// replayed. Presence is published through the bus: a user comes online with their first session
+// and goes offline with their last, counted in the SessionStore across instances when there is one.
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// HEARTBEAT_INTERVAL is how often clients send a heartbeat.
+	HEARTBEAT_INTERVAL = 30 * time.Second
+	// HEARTBEAT_TIMEOUT is how long a connection can stay silent before it is closed.
+	HEARTBEAT_TIMEOUT = HEARTBEAT_INTERVAL + HEARTBEAT_INTERVAL/2
+	// PING_INTERVAL is how often the server pings a connection.
+	PING_INTERVAL = 20 * time.Second
+	// WRITE_TIMEOUT is the longest a single frame may take to be written.
+	WRITE_TIMEOUT = 10 * time.Second
+	// SEND_BUFFER is the number of frames queued for a connection before it counts as a slow consumer.
+	SEND_BUFFER = 256
+	// REPLAY_BUFFER is the number of dispatches a session keeps for resuming.
+	REPLAY_BUFFER = 512
+	// SESSION_TIMEOUT is how long a session without connection can be resumed.
+	SESSION_TIMEOUT = 2 * time.Minute
+	// STREAM_TIMEOUT is how long a Server-Sent Events response lasts before the client reconnects.
+	STREAM_TIMEOUT = 50 * time.Second
+	// STREAM_RETRY is how long EventSource clients wait before reconnecting.
+	STREAM_RETRY = time.Second
+	// POLL_TIMEOUT is how long a long polling request waits for a frame.
+	POLL_TIMEOUT = 25 * time.Second
+	// MAX_FRAME_SIZE is the maximum size of a frame sent by a client in bytes, large enough for
+	// the session descriptions of voice signals.
+	MAX_FRAME_SIZE = 16384
+	// MAX_SUBSCRIBE_IDS is the maximum number of IDs in a subscribe or unsubscribe frame.
+	MAX_SUBSCRIBE_IDS = 100
+)
+
+// Close codes sent by the gateway in addition to the standard WebSocket ones.
+const (
+	CloseInvalidFrame   = 4002 // The client sent a frame that is not a valid envelope
+	CloseSlowConsumer   = 4008 // The client did not read its events fast enough
+	CloseSessionResumed = 4009 // The session was resumed on another connection
+)
+
+// Opcode is the operation of a frame.
+type Opcode string
+
+const (
+	OpHello            Opcode = "hello"              // Server: first frame of a connection
+	OpHeartbeat        Opcode = "heartbeat"          // Client: keeps the connection alive
+	OpHeartbeatAck     Opcode = "heartbeat_ack"      // Server: answers a heartbeat
+	OpIdentify         Opcode = "identify"           // Client: starts a new session
+	OpResume           Opcode = "resume"             // Client: resumes a session, replaying the missed dispatches
+	OpInvalidSession   Opcode = "invalid_session"    // Server: the session cannot be resumed, the client identifies again
+	OpDispatch         Opcode = "dispatch"           // Server: an event
+	OpSubscribe        Opcode = "subscribe"          // Client: adds servers and conversations to the subscriptions
+	OpUnsubscribe      Opcode = "unsubscribe"        // Client: removes servers and conversations from the subscriptions
+	OpVoiceStateUpdate Opcode = "voice_state_update" // Client: joins, updates or leaves a voice channel
+	OpVoiceSignal      Opcode = "voice_signal"       // Client: relays a WebRTC signal to a peer in the voice channel
+	OpError            Opcode = "error"              // Server: a client frame was rejected, the connection stays open
+)
+
+// EventType is the type of a dispatched event.
+type EventType string
+
+const (
+	EventReady               EventType = "READY"                // ReadyData, sent once after identify
+	EventResumed             EventType = "RESUMED"              // ResumedData, sent after the replayed dispatches
+	EventSubscriptionsUpdate EventType = "SUBSCRIPTIONS_UPDATE" // SubscriptionsData
+	EventMessageCreate       EventType = "MESSAGE_CREATE"       // models.MessagePayload
+	EventMessageUpdate       EventType = "MESSAGE_UPDATE"       // models.MessagePayload
+	EventMessageDelete       EventType = "MESSAGE_DELETE"       // MessageDeleteData
+	EventReactionAdd         EventType = "REACTION_ADD"         // ReactionData
+	EventReactionRemove      EventType = "REACTION_REMOVE"      // ReactionData
+	EventChannelCreate       EventType = "CHANNEL_CREATE"       // models.Channel
+	EventChannelUpdate       EventType = "CHANNEL_UPDATE"       // models.Channel
+	EventChannelDelete       EventType = "CHANNEL_DELETE"       // ChannelDeleteData
+	EventConversationCreate  EventType = "CONVERSATION_CREATE"  // models.ConversationPayload
+	EventConversationUpdate  EventType = "CONVERSATION_UPDATE"  // models.ConversationPayload
+	EventParticipantAdd      EventType = "PARTICIPANT_ADD"      // ParticipantData
+	EventParticipantRemove   EventType = "PARTICIPANT_REMOVE"   // ParticipantData
+	EventPresenceUpdate      EventType = "PRESENCE_UPDATE"      // PresenceData
+	EventTypingStart         EventType = "TYPING_START"         // TypingData
+	EventVoiceStateUpdate    EventType = "VOICE_STATE_UPDATE"   // models.VoiceState
+	EventVoiceSignal         EventType = "VOICE_SIGNAL"         // VoiceSignalData
+)
+
+// Frame is the JSON envelope of every frame.
+type Frame struct {
+	Op   Opcode    `json:"op"`
+	Type EventType `json:"type,omitempty"`
+	// Seq is the sequence number of a dispatch within its session.
+	Seq  uint64          `json:"seq,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Event is an event published to the connections subscribed to any of its topics.
+type Event struct {
+	Type EventType
+	// Topics are the IDs of the servers, conversations and users whose subscribers receive the event.
+	Topics []uuid.UUID
+	Data   any
+	// Refresh are the IDs of the users whose servers or conversations change with the event.
+	// Their connections reload their subscriptions after receiving it.
+	Refresh []uuid.UUID
+	// Exclude are the IDs of the users who do not receive the event although subscribed.
+	Exclude []uuid.UUID
+}
+
+// HelloData is the data of a hello frame.
+type HelloData struct {
+	// HeartbeatInterval is the heartbeat interval in milliseconds.
+	HeartbeatInterval int64 `json:"heartbeat_interval"`
+}
+
+// SubscribeData is the data of subscribe and unsubscribe frames.
+type SubscribeData struct {
+	// IDs are the IDs of servers and conversations.
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// ResumeData is the data of a resume frame.
+type ResumeData struct {
+	SessionID uuid.UUID `json:"session_id"`
+	// Seq is the sequence number of the last dispatch the client received.
+	Seq uint64 `json:"seq"`
+}
+
+// ErrorData is the data of an error frame.
+type ErrorData struct {
+	Message string `json:"message"`
+}
+
+// SubscriptionsData lists the servers and conversations a connection is subscribed to.
+type SubscriptionsData struct {
+	ServerIDs       []uuid.UUID `json:"server_ids"`
+	ConversationIDs []uuid.UUID `json:"conversation_ids"`
+}
+
+// ReadyData is the data of the READY event.
+type ReadyData struct {
+	SessionID uuid.UUID `json:"session_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	SubscriptionsData
+}
+
+// ResumedData is the data of the RESUMED event.
+type ResumedData struct {
+	SessionID uuid.UUID `json:"session_id"`
+	// Replayed is the number of dispatches sent again.
+	Replayed int `json:"replayed"`
+}
+
+// MessageDeleteData is the data of the MESSAGE_DELETE event.
+type MessageDeleteData struct {
+	ID             uuid.UUID  `json:"id"`
+	ChannelID      *uuid.UUID `json:"channel_id,omitempty"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
+	ThreadID       *uuid.UUID `json:"thread_id,omitempty"`
+}
+
+// ReactionData is the data of the REACTION_ADD and REACTION_REMOVE events.
+type ReactionData struct {
+	MessageID      uuid.UUID  `json:"message_id"`
+	ChannelID      *uuid.UUID `json:"channel_id,omitempty"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
+	ThreadID       *uuid.UUID `json:"thread_id,omitempty"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Emoji          string     `json:"emoji"`
+}
+
+// ChannelDeleteData is the data of the CHANNEL_DELETE event.
+type ChannelDeleteData struct {
+	ID       uuid.UUID `json:"id"`
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+// ParticipantData is the data of the PARTICIPANT_ADD and PARTICIPANT_REMOVE events.
+type ParticipantData struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	// User is the added user, nil for removals.
+	User *models.PublicUser `json:"user,omitempty"`
+}
+
+// Presence is the online status of a user.
+type Presence string
+
+const (
+	PresenceOnline  Presence = "online"  // The user has at least one open connection
+	PresenceOffline Presence = "offline" // The last connection of the user closed
+)
+
+// PresenceData is the data of the PRESENCE_UPDATE event.
+type PresenceData struct {
+	UserID uuid.UUID `json:"user_id"`
+	Status Presence  `json:"status"`
+}
+
+// TypingData is the data of the TYPING_START event.
+type TypingData struct {
+	ChannelID      *uuid.UUID `json:"channel_id,omitempty"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
+	ThreadID       *uuid.UUID `json:"thread_id,omitempty"`
+	UserID         uuid.UUID  `json:"user_id"`
+	// ExpiresAt is when clients stop showing the user as typing, no event is sent then.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// encodeFrame encodes an unsequenced frame with the given data.
+func encodeFrame(op Opcode, data any) ([]byte, error) {
+	frame := Frame{Op: op}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		frame.Data = raw
+	}
+	return json.Marshal(frame)
+}
+
+// ErrInvalidEventID is returned when an event ID is not a session ID and a sequence number.
+var ErrInvalidEventID = errors.New("event IDs are a session ID and a sequence number separated by a colon")
+
+// FormatEventID returns the Server-Sent Events ID of a dispatch.
+// params:
+// - sessionID: The ID of the session.
+// - seq: The sequence number of the dispatch.
+// returns:
+// - string: The event ID, "<session_id>:<seq>".
+func FormatEventID(sessionID uuid.UUID, seq uint64) string {
+	return sessionID.String() + ":" + strconv.FormatUint(seq, 10)
+}
+
+// ParseEventID parses a Server-Sent Events ID created by FormatEventID.
+// params:
+// - id: The event ID.
+// returns:
+// - uuid.UUID: The ID of the session.
+// - uint64: The sequence number of the dispatch.
+// - error: ErrInvalidEventID if the ID is malformed.
+func ParseEventID(id string) (uuid.UUID, uint64, error) {
+	session, seq, found := strings.Cut(id, ":")
+	if !found {
+		return uuid.Nil, 0, ErrInvalidEventID
+	}
+	sessionID, err := uuid.Parse(session)
+	if err != nil {
+		return uuid.Nil, 0, ErrInvalidEventID
+	}
+	number, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, ErrInvalidEventID
+	}
+	return sessionID, number, nil
+}
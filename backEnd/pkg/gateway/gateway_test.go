@@ -0,0 +1,418 @@
+package gateway_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice        = uuid.MustParse("0b7f6c2e-3f0a-4a53-9d2b-4c0f4f3f9a10")
+	bob          = uuid.MustParse("5d1e0c8a-7b2f-4e6d-8a9c-1f2e3d4c5b6a")
+	sharedServer = uuid.MustParse("1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+	aliceServer  = uuid.MustParse("9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d")
+	conversation = uuid.MustParse("3e4f5a6b-7c8d-4e9f-8a0b-1c2d3e4f5a6b")
+)
+
+// accessStub is an AccessFunc backed by a map that tests can change.
+type accessStub struct {
+	mu     sync.Mutex
+	access map[uuid.UUID]gateway.Access
+}
+
+func (s *accessStub) get(userID uuid.UUID) (gateway.Access, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.access[userID], nil
+}
+
+func (s *accessStub) set(userID uuid.UUID, access gateway.Access) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.access[userID] = access
+}
+
+// newTestHub starts a gateway server whose connections are authenticated by the user query parameter.
+func newTestHub(t *testing.T) (*gateway.Hub, *accessStub, string) {
+	stub := &accessStub{access: map[uuid.UUID]gateway.Access{
+		alice: {ServerIDs: []uuid.UUID{sharedServer, aliceServer}},
+		bob:   {ServerIDs: []uuid.UUID{sharedServer}},
+	}}
+	hub := gateway.NewHub(stub.get)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := gateway.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		hub.Serve(ws, uuid.MustParse(r.URL.Query().Get("user")))
+	}))
+	t.Cleanup(server.Close)
+	return hub, stub, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// dial opens a connection and reads its hello frame.
+func dial(t *testing.T, url string, userID uuid.UUID) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial(url+"?user="+userID.String(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+
+	hello := readFrame(t, ws)
+	require.Equal(t, gateway.OpHello, hello.Op)
+	var helloData gateway.HelloData
+	require.NoError(t, json.Unmarshal(hello.Data, &helloData))
+	assert.Equal(t, gateway.HEARTBEAT_INTERVAL.Milliseconds(), helloData.HeartbeatInterval)
+	return ws
+}
+
+// connect identifies on a new connection of a user without other sessions and reads its READY
+// and own presence frames.
+func connect(t *testing.T, url string, userID uuid.UUID) (*websocket.Conn, gateway.ReadyData) {
+	ws := dial(t, url, userID)
+	require.NoError(t, ws.WriteJSON(gateway.Frame{Op: gateway.OpIdentify}))
+
+	ready := readFrame(t, ws)
+	require.Equal(t, gateway.EventReady, ready.Type)
+	var readyData gateway.ReadyData
+	require.NoError(t, json.Unmarshal(ready.Data, &readyData))
+
+	// The connection is registered once the user is announced online.
+	presence := readFrame(t, ws)
+	require.Equal(t, gateway.EventPresenceUpdate, presence.Type)
+	assert.JSONEq(t, `{"user_id":"`+userID.String()+`","status":"online"}`, string(presence.Data))
+	return ws, readyData
+}
+
+// readFrame reads the next frame of a connection.
+func readFrame(t *testing.T, ws *websocket.Conn) gateway.Frame {
+	t.Helper()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var frame gateway.Frame
+	require.NoError(t, ws.ReadJSON(&frame))
+	return frame
+}
+
+// writeFrame sends a frame with the given data.
+func writeFrame(t *testing.T, ws *websocket.Conn, op gateway.Opcode, data any) {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.NoError(t, ws.WriteJSON(gateway.Frame{Op: op, Data: raw}))
+}
+
+// TestReady tests the subscriptions announced after connecting.
+func TestReady(t *testing.T) {
+	_, _, url := newTestHub(t)
+	_, ready := connect(t, url, alice)
+	assert.NotEqual(t, uuid.Nil, ready.SessionID)
+	assert.Equal(t, alice, ready.UserID)
+	assert.Equal(t, []uuid.UUID{sharedServer, aliceServer}, ready.ServerIDs)
+	assert.Empty(t, ready.ConversationIDs)
+}
+
+// TestPublish tests that events only reach the connections subscribed to their topics.
+func TestPublish(t *testing.T) {
+	hub, _, url := newTestHub(t)
+	hub.SessionTimeout = 10 * time.Millisecond
+	aliceWS, _ := connect(t, url, alice)
+	bobWS, _ := connect(t, url, bob)
+	// Alice learns that Bob came online in their shared server.
+	presence := readFrame(t, aliceWS)
+	require.Equal(t, gateway.EventPresenceUpdate, presence.Type)
+	assert.JSONEq(t, `{"user_id":"`+bob.String()+`","status":"online"}`, string(presence.Data))
+
+	hub.Publish(gateway.Event{Type: gateway.EventChannelCreate, Topics: []uuid.UUID{aliceServer}, Data: map[string]string{"name": "private"}})
+	hub.Publish(gateway.Event{Type: gateway.EventChannelCreate, Topics: []uuid.UUID{sharedServer, alice}, Data: map[string]string{"name": "shared"}})
+
+	// Alice gets both events, the second one once although the connection follows both of its topics.
+	first := readFrame(t, aliceWS)
+	assert.Equal(t, gateway.OpDispatch, first.Op)
+	assert.JSONEq(t, `{"name":"private"}`, string(first.Data))
+	assert.JSONEq(t, `{"name":"shared"}`, string(readFrame(t, aliceWS).Data))
+
+	// Bob only gets the event of the shared server.
+	assert.JSONEq(t, `{"name":"shared"}`, string(readFrame(t, bobWS).Data))
+
+	// Excluded users do not get the event although subscribed.
+	hub.Publish(gateway.Event{Type: gateway.EventTypingStart, Topics: []uuid.UUID{sharedServer}, Data: "typing", Exclude: []uuid.UUID{alice}})
+	assert.JSONEq(t, `"typing"`, string(readFrame(t, bobWS).Data))
+
+	// Alice learns that Bob went offline once the session of Bob expired.
+	require.NoError(t, bobWS.Close())
+	presence = readFrame(t, aliceWS)
+	assert.JSONEq(t, `{"user_id":"`+bob.String()+`","status":"offline"}`, string(presence.Data))
+}
+
+// TestHeartbeat tests that heartbeats are acknowledged and invalid frames are rejected.
+func TestHeartbeat(t *testing.T) {
+	_, _, url := newTestHub(t)
+	ws := dial(t, url, alice)
+
+	require.NoError(t, ws.WriteJSON(gateway.Frame{Op: gateway.OpHeartbeat}))
+	assert.Equal(t, gateway.OpHeartbeatAck, readFrame(t, ws).Op)
+
+	// Subscriptions need a session.
+	writeFrame(t, ws, gateway.OpSubscribe, gateway.SubscribeData{IDs: []uuid.UUID{sharedServer}})
+	assert.Equal(t, gateway.OpError, readFrame(t, ws).Op)
+
+	require.NoError(t, ws.WriteJSON(gateway.Frame{Op: "bogus"}))
+	assert.Equal(t, gateway.OpError, readFrame(t, ws).Op)
+
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("not json")))
+	_, _, err := ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, gateway.CloseInvalidFrame))
+}
+
+// TestSubscriptions tests narrowing and widening the subscriptions of a connection.
+func TestSubscriptions(t *testing.T) {
+	hub, _, url := newTestHub(t)
+	ws, _ := connect(t, url, alice)
+
+	writeFrame(t, ws, gateway.OpUnsubscribe, gateway.SubscribeData{IDs: []uuid.UUID{sharedServer}})
+	update := readFrame(t, ws)
+	require.Equal(t, gateway.EventSubscriptionsUpdate, update.Type)
+	var subscriptions gateway.SubscriptionsData
+	require.NoError(t, json.Unmarshal(update.Data, &subscriptions))
+	assert.Equal(t, []uuid.UUID{aliceServer}, subscriptions.ServerIDs)
+
+	// Events of the unsubscribed server are no longer delivered, events of the user still are.
+	hub.Publish(gateway.Event{Type: gateway.EventChannelCreate, Topics: []uuid.UUID{sharedServer}, Data: "shared"})
+	hub.Publish(gateway.Event{Type: gateway.EventChannelCreate, Topics: []uuid.UUID{alice}, Data: "personal"})
+	assert.JSONEq(t, `"personal"`, string(readFrame(t, ws).Data))
+
+	// Servers the user is not a member of cannot be subscribed to.
+	writeFrame(t, ws, gateway.OpSubscribe, gateway.SubscribeData{IDs: []uuid.UUID{sharedServer, conversation}})
+	require.NoError(t, json.Unmarshal(readFrame(t, ws).Data, &subscriptions))
+	assert.Equal(t, []uuid.UUID{sharedServer, aliceServer}, subscriptions.ServerIDs)
+	assert.Empty(t, subscriptions.ConversationIDs)
+}
+
+// TestRefresh tests that connections follow a conversation their user joined.
+func TestRefresh(t *testing.T) {
+	hub, stub, url := newTestHub(t)
+	ws, _ := connect(t, url, alice)
+
+	stub.set(alice, gateway.Access{ServerIDs: []uuid.UUID{sharedServer, aliceServer}, ConversationIDs: []uuid.UUID{conversation}})
+	hub.Publish(gateway.Event{Type: gateway.EventConversationCreate, Topics: []uuid.UUID{alice}, Data: "created", Refresh: []uuid.UUID{alice}})
+	assert.Equal(t, gateway.EventConversationCreate, readFrame(t, ws).Type)
+
+	update := readFrame(t, ws)
+	require.Equal(t, gateway.EventSubscriptionsUpdate, update.Type)
+	var subscriptions gateway.SubscriptionsData
+	require.NoError(t, json.Unmarshal(update.Data, &subscriptions))
+	assert.Equal(t, []uuid.UUID{conversation}, subscriptions.ConversationIDs)
+
+	hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{conversation}, Data: "hello"})
+	assert.JSONEq(t, `"hello"`, string(readFrame(t, ws).Data))
+}
+
+// TestResume tests that a resumed session replays the dispatches missed while disconnected.
+func TestResume(t *testing.T) {
+	hub, _, url := newTestHub(t)
+	ws, ready := connect(t, url, alice)
+	require.NoError(t, ws.Close())
+
+	// READY and the presence of the user were dispatches 1 and 2.
+	hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{aliceServer}, Data: "first"})
+	hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{alice}, Data: "second"})
+	assert.True(t, hub.Online(alice))
+
+	ws = dial(t, url, alice)
+	writeFrame(t, ws, gateway.OpResume, gateway.ResumeData{SessionID: ready.SessionID, Seq: 2})
+	first := readFrame(t, ws)
+	assert.Equal(t, uint64(3), first.Seq)
+	assert.JSONEq(t, `"first"`, string(first.Data))
+	second := readFrame(t, ws)
+	assert.Equal(t, uint64(4), second.Seq)
+	assert.JSONEq(t, `"second"`, string(second.Data))
+
+	resumed := readFrame(t, ws)
+	require.Equal(t, gateway.EventResumed, resumed.Type)
+	assert.Equal(t, uint64(5), resumed.Seq)
+	var resumedData gateway.ResumedData
+	require.NoError(t, json.Unmarshal(resumed.Data, &resumedData))
+	assert.Equal(t, gateway.ResumedData{SessionID: ready.SessionID, Replayed: 2}, resumedData)
+
+	// The session keeps its sequence and subscriptions on the new connection.
+	hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{sharedServer}, Data: "third"})
+	third := readFrame(t, ws)
+	assert.Equal(t, uint64(6), third.Seq)
+	assert.JSONEq(t, `"third"`, string(third.Data))
+
+	// Resuming again takes the session over from the current connection.
+	other := dial(t, url, alice)
+	writeFrame(t, other, gateway.OpResume, gateway.ResumeData{SessionID: ready.SessionID, Seq: 6})
+	assert.Equal(t, gateway.EventResumed, readFrame(t, other).Type)
+	_, _, err := ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, gateway.CloseSessionResumed))
+}
+
+// TestInvalidSession tests the sessions that cannot be resumed.
+func TestInvalidSession(t *testing.T) {
+	hub, _, url := newTestHub(t)
+	hub.ReplayBuffer = 2
+	ws, ready := connect(t, url, alice)
+	require.NoError(t, ws.Close())
+	for range 3 {
+		hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{aliceServer}, Data: "missed"})
+	}
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		request gateway.ResumeData
+	}{
+		{"unknown session", alice, gateway.ResumeData{SessionID: uuid.New(), Seq: 2}},
+		{"session of another user", bob, gateway.ResumeData{SessionID: ready.SessionID, Seq: 2}},
+		{"dispatches no longer buffered", alice, gateway.ResumeData{SessionID: ready.SessionID, Seq: 2}},
+		{"sequence from the future", alice, gateway.ResumeData{SessionID: ready.SessionID, Seq: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dial(t, url, tt.userID)
+			writeFrame(t, ws, gateway.OpResume, tt.request)
+			assert.Equal(t, gateway.OpInvalidSession, readFrame(t, ws).Op)
+
+			// The client identifies again on the same connection.
+			require.NoError(t, ws.WriteJSON(gateway.Frame{Op: gateway.OpIdentify}))
+			ready := readFrame(t, ws)
+			assert.Equal(t, gateway.EventReady, ready.Type)
+			assert.Equal(t, uint64(1), ready.Seq)
+		})
+	}
+}
+
+// TestSessionExpiry tests that sessions that are not resumed in time expire.
+func TestSessionExpiry(t *testing.T) {
+	hub, _, url := newTestHub(t)
+	hub.SessionTimeout = 10 * time.Millisecond
+	ws, ready := connect(t, url, alice)
+	require.NoError(t, ws.Close())
+	require.Eventually(t, func() bool { return !hub.Online(alice) }, 2*time.Second, 5*time.Millisecond)
+
+	ws = dial(t, url, alice)
+	writeFrame(t, ws, gateway.OpResume, gateway.ResumeData{SessionID: ready.SessionID, Seq: 2})
+	assert.Equal(t, gateway.OpInvalidSession, readFrame(t, ws).Op)
+}
+
+// receive reads the next frame of a stream.
+func receive(t *testing.T, stream *gateway.Stream) (uint64, gateway.Frame) {
+	t.Helper()
+	select {
+	case received := <-stream.Frames():
+		var frame gateway.Frame
+		require.NoError(t, json.Unmarshal(received.Frame, &frame))
+		assert.Equal(t, frame.Seq, received.Seq)
+		return received.Seq, frame
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "no frame received")
+		return 0, gateway.Frame{}
+	}
+}
+
+// TestStream tests resuming sessions over streams.
+func TestStream(t *testing.T) {
+	hub, _, _ := newTestHub(t)
+	stream, err := hub.OpenStream(alice, uuid.Nil, 0, true)
+	require.NoError(t, err)
+	seq, ready := receive(t, stream)
+	assert.Equal(t, uint64(1), seq)
+	assert.Equal(t, gateway.EventReady, ready.Type)
+	_, presence := receive(t, stream)
+	assert.Equal(t, gateway.EventPresenceUpdate, presence.Type)
+	sessionID := stream.SessionID()
+	stream.Close()
+
+	// Dispatches published between two polls are buffered by the session.
+	hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{aliceServer}, Data: "missed"})
+	stream, err = hub.OpenStream(alice, sessionID, 2, false)
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, stream.SessionID())
+	seq, missed := receive(t, stream)
+	assert.Equal(t, uint64(3), seq)
+	assert.JSONEq(t, `"missed"`, string(missed.Data))
+
+	// Opening another stream of the session closes this one.
+	other, err := hub.OpenStream(alice, sessionID, 3, true)
+	require.NoError(t, err)
+	<-stream.Done()
+	_, resumed := receive(t, other)
+	assert.Equal(t, gateway.EventResumed, resumed.Type)
+	other.Close()
+
+	// An unknown session starts a new one.
+	stream, err = hub.OpenStream(alice, uuid.New(), 3, true)
+	require.NoError(t, err)
+	assert.NotEqual(t, sessionID, stream.SessionID())
+	seq, invalid := receive(t, stream)
+	assert.Zero(t, seq)
+	assert.Equal(t, gateway.OpInvalidSession, invalid.Op)
+	_, ready = receive(t, stream)
+	assert.Equal(t, gateway.EventReady, ready.Type)
+	stream.Close()
+}
+
+// TestEventID tests formatting and parsing Server-Sent Events IDs.
+func TestEventID(t *testing.T) {
+	sessionID := uuid.MustParse("7f3e2d1c-0b9a-4887-a6b5-c4d3e2f1a0b9")
+	assert.Equal(t, "7f3e2d1c-0b9a-4887-a6b5-c4d3e2f1a0b9:42", gateway.FormatEventID(sessionID, 42))
+
+	tests := []struct {
+		name      string
+		id        string
+		sessionID uuid.UUID
+		seq       uint64
+		err       error
+	}{
+		{"valid", "7f3e2d1c-0b9a-4887-a6b5-c4d3e2f1a0b9:42", sessionID, 42, nil},
+		{"zero sequence", "7f3e2d1c-0b9a-4887-a6b5-c4d3e2f1a0b9:0", sessionID, 0, nil},
+		{"missing sequence", "7f3e2d1c-0b9a-4887-a6b5-c4d3e2f1a0b9", uuid.Nil, 0, gateway.ErrInvalidEventID},
+		{"negative sequence", "7f3e2d1c-0b9a-4887-a6b5-c4d3e2f1a0b9:-1", uuid.Nil, 0, gateway.ErrInvalidEventID},
+		{"invalid session", "session:42", uuid.Nil, 0, gateway.ErrInvalidEventID},
+		{"empty", "", uuid.Nil, 0, gateway.ErrInvalidEventID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionID, seq, err := gateway.ParseEventID(tt.id)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.sessionID, sessionID)
+			assert.Equal(t, tt.seq, seq)
+		})
+	}
+}
+
+// TestSlowConsumer tests that a connection that does not keep up with its events is closed.
+func TestSlowConsumer(t *testing.T) {
+	hub, _, url := newTestHub(t)
+	hub.SendBuffer = 4
+	hub.ReplayBuffer = 4
+	ws, _ := connect(t, url, alice)
+
+	assert.True(t, hub.Online(alice))
+
+	// Large events fill the socket buffers and then the queue while the client does not read.
+	payload := strings.Repeat("x", 256*1024)
+	for range 64 {
+		hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{aliceServer}, Data: payload})
+	}
+
+	// The delivered events are followed by the close frame.
+	for {
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+		if _, _, err := ws.ReadMessage(); err != nil {
+			assert.True(t, websocket.IsCloseError(err, gateway.CloseSlowConsumer), err.Error())
+			break
+		}
+	}
+	// The session stays for resuming.
+	assert.True(t, hub.Online(alice))
+}
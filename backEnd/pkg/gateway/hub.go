@@ -0,0 +1,473 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultHub is the hub the gateway handlers connect clients to, nil until the application sets it.
+var DefaultHub *Hub
+
+// DefaultBus is the bus events are published to by the API handlers.
+// It is nil until the application sets it, publishing is then a no-op.
+var DefaultBus Bus
+
+// AllowedOrigins are the origins of other hosts allowed to connect, besides the host of the API itself.
+var AllowedOrigins = []string{"http://localhost:3000"}
+
+// upgrader upgrades gateway requests to WebSocket connections.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// Hub keeps the sessions indexed by their topics and delivers published events to them.
+type Hub struct {
+	// SendBuffer is the number of frames queued for a connection before it counts as a slow consumer.
+	SendBuffer int
+	// ReplayBuffer is the number of dispatches a session keeps for resuming.
+	ReplayBuffer int
+	// SessionTimeout is how long a session without connection can be resumed.
+	SessionTimeout time.Duration
+	// Voice keeps the voice states of the sessions, voice channels are unavailable while it is nil.
+	Voice VoiceStore
+	// Bus carries the voice events created by the hub to every instance, nil delivers them to this hub only.
+	Bus Bus
+	// Sessions records the sessions so any instance can resume them, nil keeps them in this hub only.
+	Sessions SessionStore
+
+	access AccessFunc
+	// instance identifies the hub in the session store.
+	instance uuid.UUID
+
+	mu sync.RWMutex
+	// sessions holds the sessions by their ID.
+	sessions map[uuid.UUID]*session
+	// topics holds the sessions subscribed to each server, conversation and user.
+	topics map[uuid.UUID]map[*session]struct{}
+	// users holds the sessions of each user.
+	users map[uuid.UUID]map[*session]struct{}
+}
+
+// NewHub creates a hub resolving the servers and conversations of users with access.
+// params:
+// - access: The function listing the servers and conversations of a user.
+// returns:
+// - *Hub: The hub, without any session.
+func NewHub(access AccessFunc) *Hub {
+	return &Hub{
+		SendBuffer:     SEND_BUFFER,
+		ReplayBuffer:   REPLAY_BUFFER,
+		SessionTimeout: SESSION_TIMEOUT,
+		access:         access,
+		instance:       uuid.New(),
+		sessions:       make(map[uuid.UUID]*session),
+		topics:         make(map[uuid.UUID]map[*session]struct{}),
+		users:          make(map[uuid.UUID]map[*session]struct{}),
+	}
+}
+
+// Publish publishes an event to DefaultBus.
+// params:
+// - event: The event to publish.
+func Publish(event Event) {
+	if DefaultBus == nil {
+		return
+	}
+	if err := DefaultBus.Publish(event); err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "Publish").
+			Str("event", "bus_publish_failed").
+			Str("event_type", string(event.Type)).
+			Err(err).
+			Msg("Could not publish an event to the other instances.")
+	}
+}
+
+// Upgrade upgrades a gateway request to a WebSocket connection. Requests from origins other than
+// the API host and AllowedOrigins are rejected. On failure an HTTP error response has been sent.
+// params:
+// - w: The response writer of the request.
+// - r: The gateway request.
+// returns:
+// - *websocket.Conn: The WebSocket connection.
+// - error: The upgrade error.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	return upgrader.Upgrade(w, r, nil)
+}
+
+// Publish dispatches an event to every session subscribed to one of its topics, once even when
+// it is subscribed to several of them, except the sessions of excluded users.
+// params:
+// - event: The event to publish.
+func (h *Hub) Publish(event Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "Publish").
+			Str("event", "event_encode_failed").
+			Str("event_type", string(event.Type)).
+			Err(err).
+			Msg("Could not encode gateway event.")
+		return
+	}
+
+	h.mu.RLock()
+	receivers := make(map[*session]struct{})
+	for _, topic := range event.Topics {
+		for s := range h.topics[topic] {
+			if !slices.Contains(event.Exclude, s.userID) {
+				receivers[s] = struct{}{}
+			}
+		}
+	}
+	var refreshed []*session
+	for _, userID := range event.Refresh {
+		for s := range h.users[userID] {
+			refreshed = append(refreshed, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for s := range receivers {
+		s.dispatch(event.Type, data)
+	}
+	for _, s := range refreshed {
+		go s.refresh()
+	}
+}
+
+// publish delivers an event created by the hub through its bus.
+func (h *Hub) publish(event Event) {
+	if h.Bus == nil {
+		h.Publish(event)
+		return
+	}
+	if err := h.Bus.Publish(event); err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "publish").
+			Str("event", "bus_publish_failed").
+			Str("event_type", string(event.Type)).
+			Err(err).
+			Msg("Could not publish an event to the other instances.")
+	}
+}
+
+// Serve runs a connection of an authenticated user until it closes. Its session is kept for
+// resuming afterwards.
+// params:
+// - ws: The upgraded WebSocket connection.
+// - userID: The ID of the user.
+func (h *Hub) Serve(ws *websocket.Conn, userID uuid.UUID) {
+	conn := newConn(h, ws, userID)
+	conn.queue(OpHello, HelloData{HeartbeatInterval: HEARTBEAT_INTERVAL.Milliseconds()})
+
+	go conn.writeLoop()
+	conn.readLoop()
+
+	if conn.session != nil {
+		conn.session.detach(conn)
+	}
+	conn.close(websocket.CloseNormalClosure, "")
+}
+
+// Online reports whether a user has a session, sessions waiting to be resumed included.
+// params:
+// - userID: The ID of the user.
+// returns:
+// - bool: True if the user is online.
+func (h *Hub) Online(userID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
+// identify starts a new session of a user on a transport and dispatches READY. It returns nil and
+// closes the transport if the subscriptions cannot be loaded.
+func (h *Hub) identify(conn transport, userID uuid.UUID) *session {
+	s := newSession(h, conn, userID)
+	topics, subscriptions, err := s.load()
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "identify").
+			Str("event", "subscriptions_load_failed").
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not load the subscriptions of a gateway session.")
+		conn.close(websocket.CloseInternalServerErr, "could not load subscriptions")
+		return nil
+	}
+	// Sessions started at the same time on several instances all find none recorded before them,
+	// each of them announces the user rather than none.
+	first := true
+	if h.Sessions != nil {
+		first = h.alone(userID, "identify")
+		err := h.Sessions.Create(SessionRecord{ID: s.id, UserID: userID, InstanceID: h.instance, All: true})
+		if err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "identify").
+				Str("event", "session_store_failed").
+				Str("user_id", userID.String()).
+				Err(err).
+				Msg("Could not record a gateway session.")
+			conn.close(websocket.CloseInternalServerErr, "could not record the session")
+			return nil
+		}
+	}
+
+	// READY is dispatched before the session is registered, so it comes before any event.
+	s.dispatchData(EventReady, ReadyData{SessionID: s.id, UserID: userID, SubscriptionsData: subscriptions})
+	if local := h.register(s, topics); h.Sessions == nil {
+		first = local
+	}
+	if first {
+		h.publishPresence(s, PresenceOnline)
+	}
+	return s
+}
+
+// resume attaches a transport to a session of a user. It returns nil if the session cannot be resumed.
+func (h *Hub) resume(conn transport, userID uuid.UUID, request ResumeData, announce bool) *session {
+	h.mu.RLock()
+	s := h.sessions[request.SessionID]
+	h.mu.RUnlock()
+	if h.Sessions != nil {
+		return h.resumeStored(conn, userID, request, announce, s)
+	}
+	if s == nil || s.userID != userID || !s.attach(conn, request.Seq, announce) {
+		return nil
+	}
+	return s
+}
+
+// resumeStored resumes a session recorded in the session store, taking it over from the instance
+// holding it. local is the session with the same ID on this hub, nil if there is none.
+func (h *Hub) resumeStored(conn transport, userID uuid.UUID, request ResumeData, announce bool, local *session) *session {
+	record, err := h.Sessions.Claim(request.SessionID, userID, h.instance)
+	if err != nil {
+		if !errors.Is(err, ErrSessionNotFound) {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "resume").
+				Str("event", "session_store_failed").
+				Str("session_id", request.SessionID.String()).
+				Err(err).
+				Msg("Could not claim a gateway session.")
+		}
+		return nil
+	}
+	if local != nil {
+		if record.InstanceID == h.instance {
+			if !local.attach(conn, request.Seq, announce) {
+				return nil
+			}
+			return local
+		}
+		// The session was resumed elsewhere since, the dispatches of this copy are outdated.
+		local.end()
+	}
+
+	s, err := h.restore(record, request.Seq)
+	if err != nil || s == nil {
+		if err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "resume").
+				Str("event", "session_restore_failed").
+				Str("session_id", request.SessionID.String()).
+				Err(err).
+				Msg("Could not restore a gateway session from another instance.")
+		}
+		// Nobody holds the session anymore, it cannot be resumed again.
+		if err := h.Sessions.Release(record.ID, h.instance); err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "resume").
+				Str("event", "session_store_failed").
+				Str("session_id", request.SessionID.String()).
+				Err(err).
+				Msg("Could not remove a gateway session.")
+		}
+		return nil
+	}
+	if !s.attach(conn, request.Seq, announce) {
+		return nil
+	}
+	return s
+}
+
+// restore recreates and registers a session claimed from another instance with the dispatches
+// after lastSeq. It returns nil if the dispatches after lastSeq are not stored.
+func (h *Hub) restore(record *SessionRecord, lastSeq uint64) (*session, error) {
+	if lastSeq > record.Seq {
+		return nil, nil
+	}
+	frames, err := h.Sessions.Frames(record.ID, lastSeq)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(frames)) != record.Seq-lastSeq {
+		return nil, nil
+	}
+	s := restoreSession(h, record, frames)
+	topics, _, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	// The user was online with the session already.
+	h.register(s, topics)
+	return s, nil
+}
+
+// register adds a session with its topics. It reports whether it is the first session of the user
+// on this hub.
+func (h *Hub) register(s *session, topics []uuid.UUID) bool {
+	h.mu.Lock()
+	s.registered = true
+	h.sessions[s.id] = s
+	h.setTopicsLocked(s, topics)
+	if h.users[s.userID] == nil {
+		h.users[s.userID] = make(map[*session]struct{})
+	}
+	h.users[s.userID][s] = struct{}{}
+	first := len(h.users[s.userID]) == 1
+	h.mu.Unlock()
+	return first
+}
+
+// unregister removes an expired session, disconnecting it from its voice channel. The last session
+// of a user announces them offline, counted in the session store when there is one, after the
+// session was released from it.
+func (h *Hub) unregister(s *session) {
+	h.mu.Lock()
+	s.registered = false
+	delete(h.sessions, s.id)
+	h.setTopicsLocked(s, nil)
+	delete(h.users[s.userID], s)
+	last := len(h.users[s.userID]) == 0
+	if last {
+		delete(h.users, s.userID)
+	}
+	h.mu.Unlock()
+
+	if h.Sessions != nil {
+		last = h.alone(s.userID, "unregister")
+	}
+	if last {
+		h.publishPresence(s, PresenceOffline)
+	}
+	if h.Voice != nil {
+		if err := h.leaveVoice(s.id); err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "unregister").
+				Str("event", "voice_leave_failed").
+				Str("user_id", s.userID.String()).
+				Err(err).
+				Msg("Could not disconnect an expired session from its voice channel.")
+		}
+	}
+}
+
+// drop removes a session another instance took over. The user is not announced offline and stays
+// in their voice channel, they moved with the session.
+func (h *Hub) drop(s *session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sessions[s.id] != s {
+		return
+	}
+	s.registered = false
+	delete(h.sessions, s.id)
+	h.setTopicsLocked(s, nil)
+	delete(h.users[s.userID], s)
+	if len(h.users[s.userID]) == 0 {
+		delete(h.users, s.userID)
+	}
+}
+
+// setTopics replaces the topics of a registered session.
+func (h *Hub) setTopics(s *session, topics []uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.registered {
+		h.setTopicsLocked(s, topics)
+	}
+}
+
+// setTopicsLocked replaces the topics of a session, the caller holds the write lock.
+func (h *Hub) setTopicsLocked(s *session, topics []uuid.UUID) {
+	for topic := range s.topics {
+		if slices.Contains(topics, topic) {
+			continue
+		}
+		delete(h.topics[topic], s)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+		delete(s.topics, topic)
+	}
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = make(map[*session]struct{})
+		}
+		h.topics[topic][s] = struct{}{}
+		s.topics[topic] = struct{}{}
+	}
+}
+
+// publishPresence publishes the presence of the user of a session to every server and
+// conversation they are part of, whether or not the session is subscribed to them.
+func (h *Hub) publishPresence(s *session, status Presence) {
+	h.publish(Event{
+		Type:   EventPresenceUpdate,
+		Topics: s.currentAccess().ids(),
+		Data:   PresenceData{UserID: s.userID, Status: status},
+	})
+}
+
+// alone reports whether a user has no session recorded in the session store on any instance. It
+// reports true if the store fails, so presence is announced twice rather than not at all.
+func (h *Hub) alone(userID uuid.UUID, method string) bool {
+	count, err := h.Sessions.Count(userID)
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", method).
+			Str("event", "session_store_failed").
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not count the gateway sessions of a user.")
+		return true
+	}
+	return count == 0
+}
+
+// checkOrigin accepts requests without an origin, such as those of native clients, requests from
+// the API host and requests from AllowedOrigins.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return parsed.Host == r.Host || slices.Contains(AllowedOrigins, origin)
+}
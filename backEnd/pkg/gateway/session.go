@@ -0,0 +1,429 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// transport delivers the frames of a session to a client, a WebSocket connection or a stream.
+type transport interface {
+	// send queues an encoded frame without blocking, seq is 0 for frames other than dispatches.
+	send(seq uint64, frame []byte)
+	// close ends the transport, only the first call has an effect.
+	close(code int, reason string)
+}
+
+// session is the subscription of a client to the events of its user. It outlives its connection
+// for SESSION_TIMEOUT, so the client can resume it after reconnecting.
+type session struct {
+	id     uuid.UUID
+	hub    *Hub
+	userID uuid.UUID
+
+	// registered and topics are guarded by the hub lock.
+	registered bool
+	topics     map[uuid.UUID]struct{}
+
+	// subscriptionsMu guards the fields below and serializes subscription reloads.
+	subscriptionsMu sync.Mutex
+	// all is set while the session follows every server and conversation of its user.
+	all bool
+	// interests are the servers and conversations the client subscribed to when all is not set.
+	interests map[uuid.UUID]struct{}
+	// access is the last loaded access of the user.
+	access Access
+
+	// mu guards the fields below, dispatches are sequenced and sent while holding it.
+	mu sync.Mutex
+	// seq is the sequence number of the last dispatch.
+	seq uint64
+	// replay holds the last dispatches, the last one has sequence number seq.
+	replay [][]byte
+	// conn is the transport of the session, nil while the client is away.
+	conn transport
+	// expiry ends the session once it was without connection for too long.
+	expiry *time.Timer
+	// ended is set once the session expired and can no longer be resumed.
+	ended bool
+
+	// storeMu guards the fields below, the dispatches waiting to be written to the session store.
+	// They are written in the background, so publishing never waits for the database.
+	storeMu sync.Mutex
+	// unstored are the dispatches not written yet, at most the last ReplayBuffer ones.
+	unstored []StreamFrame
+	// storing is set while a writer goroutine writes the unstored dispatches.
+	storing bool
+	// stored is signaled when the writer stops.
+	stored *sync.Cond
+}
+
+// newSession creates a session on a transport following every server and conversation of the user.
+func newSession(hub *Hub, conn transport, userID uuid.UUID) *session {
+	s := &session{
+		id:        uuid.New(),
+		hub:       hub,
+		userID:    userID,
+		topics:    make(map[uuid.UUID]struct{}),
+		all:       true,
+		interests: make(map[uuid.UUID]struct{}),
+		conn:      conn,
+	}
+	s.stored = sync.NewCond(&s.storeMu)
+	return s
+}
+
+// restoreSession recreates a session recorded by another instance, replay holding its last dispatches.
+func restoreSession(hub *Hub, record *SessionRecord, replay [][]byte) *session {
+	s := newSession(hub, nil, record.UserID)
+	s.id = record.ID
+	s.all = record.All
+	for _, id := range record.Interests {
+		s.interests[id] = struct{}{}
+	}
+	s.seq = record.Seq
+	s.replay = replay
+	return s
+}
+
+// dispatch sequences an event, keeps it for resuming and sends it to the connection if there is one.
+func (s *session) dispatch(eventType EventType, data json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatchLocked(eventType, data)
+}
+
+// dispatchLocked dispatches an event, the caller holds the session lock.
+func (s *session) dispatchLocked(eventType EventType, data json.RawMessage) {
+	if s.ended {
+		return
+	}
+	frame, err := json.Marshal(Frame{Op: OpDispatch, Type: eventType, Seq: s.seq + 1, Data: data})
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "dispatch").
+			Str("event", "frame_encode_failed").
+			Str("event_type", string(eventType)).
+			Err(err).
+			Msg("Could not encode gateway frame.")
+		return
+	}
+	s.seq++
+	s.replay = append(s.replay, frame)
+	if len(s.replay) > s.hub.ReplayBuffer {
+		s.replay = s.replay[1:]
+	}
+	if s.hub.Sessions != nil {
+		s.store(s.seq, frame)
+	}
+	if s.conn != nil {
+		s.conn.send(s.seq, frame)
+	}
+}
+
+// dispatchData encodes the data of an event and dispatches it.
+func (s *session) dispatchData(eventType EventType, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "dispatchData").
+			Str("event", "event_encode_failed").
+			Str("event_type", string(eventType)).
+			Err(err).
+			Msg("Could not encode gateway event.")
+		return
+	}
+	s.dispatch(eventType, raw)
+}
+
+// attach resumes the session on a transport, sending the dispatches after lastSeq followed by
+// RESUMED if announce is set. A transport the session is still attached to is closed.
+// It returns false if the session ended or the dispatches after lastSeq are no longer buffered.
+func (s *session) attach(conn transport, lastSeq uint64, announce bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || lastSeq > s.seq || s.seq-lastSeq > uint64(len(s.replay)) {
+		return false
+	}
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+	if s.conn != nil {
+		s.conn.close(CloseSessionResumed, "session resumed on another connection")
+	}
+	s.conn = conn
+
+	missed := s.replay[len(s.replay)-int(s.seq-lastSeq):]
+	for i, frame := range missed {
+		conn.send(lastSeq+uint64(i)+1, frame)
+	}
+	if announce {
+		data, _ := json.Marshal(ResumedData{SessionID: s.id, Replayed: len(missed)})
+		s.dispatchLocked(EventResumed, data)
+	}
+	return true
+}
+
+// detach removes a closed transport from the session, which expires unless it is resumed in time.
+// The expiry is recorded once the dispatches are stored, so any instance can resume the session
+// with the dispatches the client has not received.
+func (s *session) detach(conn transport) {
+	s.mu.Lock()
+	if s.conn != conn {
+		s.mu.Unlock()
+		return
+	}
+	s.conn = nil
+	s.expiry = time.AfterFunc(s.hub.SessionTimeout, s.expire)
+	s.mu.Unlock()
+	if s.hub.Sessions == nil {
+		return
+	}
+
+	s.flush()
+	err := s.hub.Sessions.Detach(s.id, s.hub.instance, time.Now().Add(s.hub.SessionTimeout))
+	if errors.Is(err, ErrSessionMoved) {
+		s.end()
+	} else if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "detach").
+			Str("event", "session_store_failed").
+			Str("session_id", s.id.String()).
+			Err(err).
+			Msg("Could not record the expiry of a gateway session.")
+	}
+}
+
+// store queues a dispatch for the session store and starts a writer if none is running.
+// Dispatches beyond the replay buffer are dropped, the instance resuming the session could not
+// replay them anyway.
+func (s *session) store(seq uint64, frame []byte) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	s.unstored = append(s.unstored, StreamFrame{Seq: seq, Frame: frame})
+	if len(s.unstored) > s.hub.ReplayBuffer {
+		s.unstored = s.unstored[1:]
+	}
+	if !s.storing {
+		s.storing = true
+		go s.write()
+	}
+}
+
+// write writes the queued dispatches to the session store in batches until none is left. A session
+// held by another instance ends.
+func (s *session) write() {
+	for {
+		s.storeMu.Lock()
+		frames := s.unstored
+		s.unstored = nil
+		if len(frames) == 0 {
+			s.storing = false
+			s.stored.Broadcast()
+			s.storeMu.Unlock()
+			return
+		}
+		s.storeMu.Unlock()
+
+		err := s.hub.Sessions.Append(s.id, s.hub.instance, frames, s.hub.ReplayBuffer)
+		if errors.Is(err, ErrSessionMoved) {
+			s.storeMu.Lock()
+			s.unstored = nil
+			s.storing = false
+			s.stored.Broadcast()
+			s.storeMu.Unlock()
+			s.end()
+			return
+		}
+		if err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "write").
+				Str("event", "session_store_failed").
+				Str("session_id", s.id.String()).
+				Int("dispatches", len(frames)).
+				Err(err).
+				Msg("Could not store gateway dispatches, the session cannot be resumed on other instances.")
+		}
+	}
+}
+
+// flush waits until the queued dispatches are written to the session store.
+func (s *session) flush() {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	for s.storing {
+		s.stored.Wait()
+	}
+}
+
+// expire ends a session that was not resumed.
+func (s *session) expire() {
+	s.mu.Lock()
+	if s.conn != nil || s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.replay = nil
+	s.mu.Unlock()
+	if s.hub.Sessions != nil {
+		err := s.hub.Sessions.Release(s.id, s.hub.instance)
+		if errors.Is(err, ErrSessionMoved) {
+			// The user and their voice state moved with the session.
+			s.hub.drop(s)
+			return
+		}
+		if err != nil {
+			log.Error().
+				Str("component", "gateway").
+				Str("method_name", "expire").
+				Str("event", "session_store_failed").
+				Str("session_id", s.id.String()).
+				Err(err).
+				Msg("Could not remove an expired gateway session.")
+		}
+	}
+	s.hub.unregister(s)
+}
+
+// end ends a session another instance took over, closing its transport so the client resumes it
+// there, and removes it from the hub.
+func (s *session) end() {
+	s.mu.Lock()
+	s.ended = true
+	s.replay = nil
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+	if s.conn != nil {
+		s.conn.close(CloseSessionResumed, "session resumed on another instance")
+		s.conn = nil
+	}
+	s.mu.Unlock()
+	s.hub.drop(s)
+}
+
+// notify queues an unsequenced frame on the transport of the session if there is one.
+func (s *session) notify(op Opcode, data any) {
+	frame, err := encodeFrame(op, data)
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "notify").
+			Str("event", "frame_encode_failed").
+			Str("op", string(op)).
+			Err(err).
+			Msg("Could not encode gateway frame.")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.send(0, frame)
+	}
+}
+
+// subscribe adds servers and conversations to the interests of the client, or removes them.
+// Only IDs the user has access to can be added.
+func (s *session) subscribe(ids []uuid.UUID, add bool) {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+	if s.all {
+		if add {
+			return
+		}
+		s.all = false
+		for _, id := range s.access.ids() {
+			s.interests[id] = struct{}{}
+		}
+	}
+	for _, id := range ids {
+		if !add {
+			delete(s.interests, id)
+		} else if slices.Contains(s.access.ids(), id) {
+			s.interests[id] = struct{}{}
+		}
+	}
+	if s.hub.Sessions == nil {
+		return
+	}
+	interests := make([]uuid.UUID, 0, len(s.interests))
+	for id := range s.interests {
+		interests = append(interests, id)
+	}
+	// A session held by another instance ends with its next dispatch.
+	err := s.hub.Sessions.Subscribe(s.id, s.hub.instance, s.all, interests)
+	if err != nil && !errors.Is(err, ErrSessionMoved) {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "subscribe").
+			Str("event", "session_store_failed").
+			Str("session_id", s.id.String()).
+			Err(err).
+			Msg("Could not record the subscriptions of a gateway session.")
+	}
+}
+
+// load reloads the access of the user and returns the resulting topics and subscriptions.
+func (s *session) load() ([]uuid.UUID, SubscriptionsData, error) {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+	access, err := s.hub.access(s.userID)
+	if err != nil {
+		return nil, SubscriptionsData{}, err
+	}
+	s.access = access
+
+	subscriptions := SubscriptionsData{ServerIDs: []uuid.UUID{}, ConversationIDs: []uuid.UUID{}}
+	// The session follows its own ID for the events addressed to it, such as voice signals.
+	topics := []uuid.UUID{s.userID, s.id}
+	for _, id := range access.ServerIDs {
+		if _, ok := s.interests[id]; s.all || ok {
+			subscriptions.ServerIDs = append(subscriptions.ServerIDs, id)
+			topics = append(topics, id)
+		}
+	}
+	for _, id := range access.ConversationIDs {
+		if _, ok := s.interests[id]; s.all || ok {
+			subscriptions.ConversationIDs = append(subscriptions.ConversationIDs, id)
+			topics = append(topics, id)
+		}
+	}
+	return topics, subscriptions, nil
+}
+
+// refresh reloads the subscriptions of a registered session and dispatches them.
+func (s *session) refresh() {
+	topics, subscriptions, err := s.load()
+	if err != nil {
+		log.Error().
+			Str("component", "gateway").
+			Str("method_name", "refresh").
+			Str("event", "subscriptions_load_failed").
+			Str("user_id", s.userID.String()).
+			Err(err).
+			Msg("Could not reload the subscriptions of a gateway session.")
+		s.notify(OpError, ErrorData{Message: "could not reload subscriptions"})
+		return
+	}
+	s.hub.setTopics(s, topics)
+	s.dispatchData(EventSubscriptionsUpdate, subscriptions)
+}
+
+// currentAccess returns the last loaded access of the user.
+func (s *session) currentAccess() Access {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+	return s.access
+}
@@ -0,0 +1,234 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SESSION_RETENTION is how long a connected session without dispatches is kept, so the sessions of
+// instances that stopped without detaching them are eventually removed.
+const SESSION_RETENTION = 24 * time.Hour
+
+// DatabaseSessions keeps the sessions in the gateway_sessions table and their last dispatches in
+// the gateway_session_frames table, shared by every instance.
+type DatabaseSessions struct {
+	db *gorm.DB
+}
+
+// NewDatabaseSessions creates a session store on the database.
+// params:
+// - db: The GORM database instance.
+// returns:
+// - *DatabaseSessions: The session store.
+func NewDatabaseSessions(db *gorm.DB) *DatabaseSessions {
+	return &DatabaseSessions{db: db}
+}
+
+// Create records a new session, removing the expired ones.
+// params:
+// - record: The session with its ID, user, instance and subscriptions.
+// returns:
+// - error: A database error.
+func (d *DatabaseSessions) Create(record SessionRecord) error {
+	interests, err := encodeInterests(record.Interests)
+	if err != nil {
+		return err
+	}
+	session := models.GatewaySession{
+		ID:         record.ID,
+		UserID:     record.UserID,
+		InstanceID: record.InstanceID,
+		Seq:        int64(record.Seq),
+		All:        record.All,
+		Interests:  interests,
+	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Where("expires_at < ? OR (expires_at IS NULL AND updated_at < ?)", now, now.Add(-SESSION_RETENTION)).
+			Delete(&models.GatewaySession{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&session).Error
+	})
+}
+
+// Append stores dispatches of a session and removes the dispatches before the last limit ones.
+// params:
+// - sessionID: The ID of the session.
+// - instanceID: The ID of the hub holding the session.
+// - frames: The encoded dispatch frames with their sequence numbers, in sequence order.
+// - limit: The number of dispatches kept.
+// returns:
+// - error: ErrSessionMoved if another instance holds the session, or a database error.
+func (d *DatabaseSessions) Append(sessionID, instanceID uuid.UUID, frames []StreamFrame, limit int) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	last := int64(frames[len(frames)-1].Seq)
+	stored := make([]models.GatewaySessionFrame, 0, len(frames))
+	for _, frame := range frames {
+		stored = append(stored, models.GatewaySessionFrame{SessionID: sessionID, Seq: int64(frame.Seq), Frame: string(frame.Frame)})
+	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		// Updating the session locks it, so a claim waits until the dispatches are stored.
+		if err := d.update(tx, sessionID, instanceID, map[string]any{"seq": last}); err != nil {
+			return err
+		}
+		if err := tx.Create(&stored).Error; err != nil {
+			return err
+		}
+		return tx.Where("session_id = ? AND seq <= ?", sessionID, last-int64(limit)).
+			Delete(&models.GatewaySessionFrame{}).Error
+	})
+}
+
+// Subscribe records the subscriptions of a session.
+// params:
+// - sessionID: The ID of the session.
+// - instanceID: The ID of the hub holding the session.
+// - all: Whether the session follows every server and conversation of its user.
+// - interests: The subscribed servers and conversations when all is not set.
+// returns:
+// - error: ErrSessionMoved if another instance holds the session, or a database error.
+func (d *DatabaseSessions) Subscribe(sessionID, instanceID uuid.UUID, all bool, interests []uuid.UUID) error {
+	encoded, err := encodeInterests(interests)
+	if err != nil {
+		return err
+	}
+	return d.update(d.db, sessionID, instanceID, map[string]any{"all": all, "interests": encoded})
+}
+
+// Detach records that a session lost its connection.
+// params:
+// - sessionID: The ID of the session.
+// - instanceID: The ID of the hub holding the session.
+// - expiresAt: When the session can no longer be resumed.
+// returns:
+// - error: ErrSessionMoved if another instance holds the session, or a database error.
+func (d *DatabaseSessions) Detach(sessionID, instanceID uuid.UUID, expiresAt time.Time) error {
+	return d.update(d.db, sessionID, instanceID, map[string]any{"expires_at": expiresAt})
+}
+
+// Claim moves a session of a user to an instance and clears its expiry.
+// params:
+// - sessionID: The ID of the session.
+// - userID: The ID of the user resuming the session.
+// - instanceID: The ID of the hub taking the session over.
+// returns:
+// - *SessionRecord: The session as recorded before the claim.
+// - error: ErrSessionNotFound if the session does not exist, expired or belongs to another user.
+func (d *DatabaseSessions) Claim(sessionID, userID, instanceID uuid.UUID) (*SessionRecord, error) {
+	var session models.GatewaySession
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ? AND (expires_at IS NULL OR expires_at > ?)", sessionID, userID, time.Now()).
+			First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.GatewaySession{}).Where("id = ?", sessionID).
+			Updates(map[string]any{"instance_id": instanceID, "expires_at": nil}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record := &SessionRecord{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		InstanceID: session.InstanceID,
+		Seq:        uint64(session.Seq),
+		All:        session.All,
+	}
+	if err := json.Unmarshal([]byte(session.Interests), &record.Interests); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Frames returns the stored dispatches of a session after a sequence number.
+// params:
+// - sessionID: The ID of the session.
+// - after: The sequence number of the last dispatch the client received.
+// returns:
+// - [][]byte: The encoded dispatch frames in sequence order.
+// - error: A database error.
+func (d *DatabaseSessions) Frames(sessionID uuid.UUID, after uint64) ([][]byte, error) {
+	var stored []models.GatewaySessionFrame
+	if err := d.db.Where("session_id = ? AND seq > ?", sessionID, int64(after)).Order("seq ASC").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	frames := make([][]byte, 0, len(stored))
+	for _, frame := range stored {
+		frames = append(frames, []byte(frame.Frame))
+	}
+	return frames, nil
+}
+
+// Release removes an expired session with its dispatches.
+// params:
+// - sessionID: The ID of the session.
+// - instanceID: The ID of the hub holding the session.
+// returns:
+// - error: ErrSessionMoved if another instance holds the session, or a database error.
+func (d *DatabaseSessions) Release(sessionID, instanceID uuid.UUID) error {
+	result := d.db.Where("id = ? AND instance_id = ?", sessionID, instanceID).Delete(&models.GatewaySession{})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	// A session removed after expiring is released already.
+	var count int64
+	if err := d.db.Model(&models.GatewaySession{}).Where("id = ?", sessionID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSessionMoved
+	}
+	return nil
+}
+
+// Count returns the number of connected or unexpired sessions of a user on every instance.
+// params:
+// - userID: The ID of the user.
+// returns:
+// - int: The number of sessions.
+// - error: A database error.
+func (d *DatabaseSessions) Count(userID uuid.UUID) (int, error) {
+	now := time.Now()
+	var count int64
+	err := d.db.Model(&models.GatewaySession{}).
+		Where("user_id = ? AND (expires_at > ? OR (expires_at IS NULL AND updated_at >= ?))", userID, now, now.Add(-SESSION_RETENTION)).
+		Count(&count).Error
+	return int(count), err
+}
+
+// update updates a session held by an instance. A session that is no longer recorded expired,
+// it counts as moved as this instance cannot hold it anymore either.
+func (d *DatabaseSessions) update(tx *gorm.DB, sessionID, instanceID uuid.UUID, values map[string]any) error {
+	result := tx.Model(&models.GatewaySession{}).Where("id = ? AND instance_id = ?", sessionID, instanceID).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionMoved
+	}
+	return nil
+}
+
+// encodeInterests encodes the interests of a session as a JSON array.
+func encodeInterests(interests []uuid.UUID) (string, error) {
+	if interests == nil {
+		interests = []uuid.UUID{}
+	}
+	encoded, err := json.Marshal(interests)
+	return string(encoded), err
+}
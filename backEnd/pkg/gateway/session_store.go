@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSessionNotFound is returned when a session does not exist, expired or belongs to another user.
+	ErrSessionNotFound = errors.New("gateway session not found")
+	// ErrSessionMoved is returned when another instance took over a session.
+	ErrSessionMoved = errors.New("the gateway session is held by another instance")
+)
+
+// SessionRecord is a session as recorded in a SessionStore.
+type SessionRecord struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// InstanceID is the hub holding the session.
+	InstanceID uuid.UUID
+	// Seq is the sequence number of the last dispatch.
+	Seq uint64
+	// All is set while the session follows every server and conversation of its user.
+	All bool
+	// Interests are the servers and conversations the client subscribed to when All is not set.
+	Interests []uuid.UUID
+}
+
+// SessionStore records the sessions and their last dispatches, so a session can be resumed on
+// any instance. The instance holding a session buffers its dispatches and writes them to the store
+// in the background, the one resuming it takes it over and the previous one drops its copy once it
+// notices.
+type SessionStore interface {
+	// Create records a new session held by the instance of the record.
+	Create(record SessionRecord) error
+	// Append stores dispatches of a session held by an instance, in sequence order, and keeps its
+	// last limit dispatches. It returns ErrSessionMoved if another instance holds the session.
+	Append(sessionID, instanceID uuid.UUID, frames []StreamFrame, limit int) error
+	// Subscribe records the subscriptions of a session held by an instance.
+	// It returns ErrSessionMoved if another instance holds the session.
+	Subscribe(sessionID, instanceID uuid.UUID, all bool, interests []uuid.UUID) error
+	// Detach records that a session held by an instance lost its connection and expires at expiresAt.
+	// It returns ErrSessionMoved if another instance holds the session.
+	Detach(sessionID, instanceID uuid.UUID, expiresAt time.Time) error
+	// Claim moves a connected or unexpired session of a user to an instance and clears its expiry.
+	// It returns the session as it was recorded before, or ErrSessionNotFound.
+	Claim(sessionID, userID, instanceID uuid.UUID) (*SessionRecord, error)
+	// Frames returns the stored dispatches of a session after the given sequence number, in order.
+	Frames(sessionID uuid.UUID, after uint64) ([][]byte, error)
+	// Release removes an expired session held by an instance.
+	// It returns ErrSessionMoved if another instance holds the session.
+	Release(sessionID, instanceID uuid.UUID) error
+	// Count returns the number of connected or unexpired sessions of a user on every instance.
+	Count(userID uuid.UUID) (int, error)
+}
@@ -0,0 +1,354 @@
+package gateway_test
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// storedSession is a session kept by sessionStub.
+type storedSession struct {
+	record    gateway.SessionRecord
+	expiresAt *time.Time
+	frames    map[uint64][]byte
+}
+
+// sessionStub is a SessionStore in memory shared by the hubs of a test.
+type sessionStub struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*storedSession
+	// gate holds back Append until it is closed, when set.
+	gate chan struct{}
+}
+
+// seq returns the sequence number of the last stored dispatch of a session.
+func (s *sessionStub) seq(sessionID uuid.UUID) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[sessionID].record.Seq
+}
+
+func (s *sessionStub) Create(record gateway.SessionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[record.ID] = &storedSession{record: record, frames: make(map[uint64][]byte)}
+	return nil
+}
+
+// held returns a session held by an instance, the caller holds the lock.
+func (s *sessionStub) held(sessionID, instanceID uuid.UUID) (*storedSession, error) {
+	stored := s.sessions[sessionID]
+	if stored == nil || stored.record.InstanceID != instanceID {
+		return nil, gateway.ErrSessionMoved
+	}
+	return stored, nil
+}
+
+func (s *sessionStub) Append(sessionID, instanceID uuid.UUID, frames []gateway.StreamFrame, limit int) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.held(sessionID, instanceID)
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		stored.record.Seq = frame.Seq
+		stored.frames[frame.Seq] = frame.Frame
+		delete(stored.frames, frame.Seq-uint64(limit))
+	}
+	return nil
+}
+
+func (s *sessionStub) Subscribe(sessionID, instanceID uuid.UUID, all bool, interests []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.held(sessionID, instanceID)
+	if err != nil {
+		return err
+	}
+	stored.record.All = all
+	stored.record.Interests = interests
+	return nil
+}
+
+func (s *sessionStub) Detach(sessionID, instanceID uuid.UUID, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.held(sessionID, instanceID)
+	if err != nil {
+		return err
+	}
+	stored.expiresAt = &expiresAt
+	return nil
+}
+
+func (s *sessionStub) Claim(sessionID, userID, instanceID uuid.UUID) (*gateway.SessionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.sessions[sessionID]
+	if stored == nil || stored.record.UserID != userID || (stored.expiresAt != nil && stored.expiresAt.Before(time.Now())) {
+		return nil, gateway.ErrSessionNotFound
+	}
+	record := stored.record
+	stored.record.InstanceID = instanceID
+	stored.expiresAt = nil
+	return &record, nil
+}
+
+func (s *sessionStub) Frames(sessionID uuid.UUID, after uint64) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var frames [][]byte
+	for seq := after + 1; ; seq++ {
+		frame, ok := s.sessions[sessionID].frames[seq]
+		if !ok {
+			return frames, nil
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func (s *sessionStub) Release(sessionID, instanceID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.held(sessionID, instanceID); err != nil {
+		return err
+	}
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *sessionStub) Count(userID uuid.UUID) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, stored := range s.sessions {
+		if stored.record.UserID == userID && (stored.expiresAt == nil || stored.expiresAt.After(time.Now())) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// TestResumeOnOtherInstance tests resuming a stream session on another instance sharing the session store.
+func TestResumeOnOtherInstance(t *testing.T) {
+	store := &sessionStub{sessions: make(map[uuid.UUID]*storedSession)}
+	var hubs [2]*gateway.Hub
+	for i := range hubs {
+		hubs[i], _, _ = newTestHub(t)
+		hubs[i].Sessions = store
+		hubs[i].ReplayBuffer = 3
+	}
+	// The bus delivers every event to both instances.
+	publish := func(event gateway.Event) {
+		for _, hub := range hubs {
+			hub.Publish(event)
+		}
+	}
+
+	stream, err := hubs[0].OpenStream(alice, uuid.Nil, 0, true)
+	require.NoError(t, err)
+	_, ready := receive(t, stream)
+	require.Equal(t, gateway.EventReady, ready.Type)
+	receive(t, stream)
+	sessionID := stream.SessionID()
+	stream.Close()
+
+	// READY and the presence of the user were dispatches 1 and 2.
+	publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{aliceServer}, Data: "first"})
+	// Dispatches are stored in the background.
+	require.Eventually(t, func() bool { return store.seq(sessionID) == 3 }, 2*time.Second, 5*time.Millisecond)
+
+	stream, err = hubs[1].OpenStream(alice, sessionID, 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, stream.SessionID())
+	seq, first := receive(t, stream)
+	assert.Equal(t, uint64(3), seq)
+	assert.JSONEq(t, `"first"`, string(first.Data))
+	// The user was online already, restoring the session does not announce them.
+	seq, resumed := receive(t, stream)
+	assert.Equal(t, uint64(4), seq)
+	assert.Equal(t, gateway.EventResumed, resumed.Type)
+
+	// The first instance drops its copy with the next event, the session keeps its sequence.
+	publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{sharedServer}, Data: "second"})
+	seq, second := receive(t, stream)
+	assert.Equal(t, uint64(5), seq)
+	assert.JSONEq(t, `"second"`, string(second.Data))
+	require.Eventually(t, func() bool { return !hubs[0].Online(alice) }, 2*time.Second, 5*time.Millisecond)
+	assert.True(t, hubs[1].Online(alice))
+	stream.Close()
+
+	// The session moves back with the dispatches the first instance did not see.
+	stream, err = hubs[0].OpenStream(alice, sessionID, 4, false)
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, stream.SessionID())
+	seq, second = receive(t, stream)
+	assert.Equal(t, uint64(5), seq)
+	assert.JSONEq(t, `"second"`, string(second.Data))
+	stream.Close()
+
+	// Sessions of other users and sequences that are not stored cannot be resumed. A session that
+	// failed to be restored is removed, it can no longer be resumed at all.
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		lastSeq uint64
+	}{
+		{"session of another user", bob, 5},
+		{"dispatches no longer stored", alice, 1},
+		{"removed session", alice, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := hubs[1].OpenStream(tt.userID, sessionID, tt.lastSeq, true)
+			require.NoError(t, err)
+			defer stream.Close()
+			_, invalid := receive(t, stream)
+			assert.Equal(t, gateway.OpInvalidSession, invalid.Op)
+			_, ready := receive(t, stream)
+			assert.Equal(t, gateway.EventReady, ready.Type)
+			assert.NotEqual(t, sessionID, stream.SessionID())
+		})
+	}
+}
+
+// TestPresenceOnSeveralInstances tests that presence is announced through the bus by the first and
+// last session of a user across the instances sharing the session store.
+func TestPresenceOnSeveralInstances(t *testing.T) {
+	store := &sessionStub{sessions: make(map[uuid.UUID]*storedSession)}
+	var hubs [2]*gateway.Hub
+	for i := range hubs {
+		hubs[i], _, _ = newTestHub(t)
+		hubs[i].Sessions = store
+		hubs[i].SessionTimeout = 10 * time.Millisecond
+	}
+	bus := gateway.NewLocalBus(func(event gateway.Event) {
+		for _, hub := range hubs {
+			hub.Publish(event)
+		}
+	})
+	for _, hub := range hubs {
+		hub.Bus = bus
+	}
+
+	// Bob sees alice come online on the other instance.
+	bobStream, err := hubs[1].OpenStream(bob, uuid.Nil, 0, true)
+	require.NoError(t, err)
+	defer bobStream.Close()
+	receive(t, bobStream)
+	receive(t, bobStream)
+	aliceStream, err := hubs[0].OpenStream(alice, uuid.Nil, 0, true)
+	require.NoError(t, err)
+	receive(t, aliceStream)
+	receive(t, aliceStream)
+	_, online := receive(t, bobStream)
+	require.Equal(t, gateway.EventPresenceUpdate, online.Type)
+	assert.JSONEq(t, `{"user_id":"`+alice.String()+`","status":"online"}`, string(online.Data))
+
+	// A second session on the other instance and the expiry of the first do not change presence.
+	secondStream, err := hubs[1].OpenStream(alice, uuid.Nil, 0, true)
+	require.NoError(t, err)
+	receive(t, secondStream)
+	aliceStream.Close()
+	require.Eventually(t, func() bool { return !hubs[0].Online(alice) }, 2*time.Second, 5*time.Millisecond)
+	bus.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{sharedServer}, Data: "hello"})
+	_, message := receive(t, bobStream)
+	assert.Equal(t, gateway.EventMessageCreate, message.Type)
+
+	// The last session announces alice offline.
+	secondStream.Close()
+	_, offline := receive(t, bobStream)
+	require.Equal(t, gateway.EventPresenceUpdate, offline.Type)
+	assert.JSONEq(t, `{"user_id":"`+alice.String()+`","status":"offline"}`, string(offline.Data))
+}
+
+// TestPublishWithSlowStore tests that publishing does not wait for the session store, the
+// dispatches are stored in batches once it is available again.
+func TestPublishWithSlowStore(t *testing.T) {
+	store := &sessionStub{sessions: make(map[uuid.UUID]*storedSession), gate: make(chan struct{})}
+	hub, _, _ := newTestHub(t)
+	hub.Sessions = store
+	stream, err := hub.OpenStream(alice, uuid.Nil, 0, true)
+	require.NoError(t, err)
+	receive(t, stream)
+	receive(t, stream)
+
+	published := make(chan struct{})
+	go func() {
+		for range 10 {
+			hub.Publish(gateway.Event{Type: gateway.EventMessageCreate, Topics: []uuid.UUID{aliceServer}, Data: "hello"})
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "publishing waited for the session store")
+	}
+	for seq := uint64(3); seq <= 12; seq++ {
+		received, _ := receive(t, stream)
+		assert.Equal(t, seq, received)
+	}
+
+	close(store.gate)
+	require.Eventually(t, func() bool { return store.seq(stream.SessionID()) == 12 }, 2*time.Second, 5*time.Millisecond)
+	stream.Close()
+}
+
+// TestDatabaseSessions tests taking a session over in the database of DATABASE_URL.
+func TestDatabaseSessions(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.GatewaySession{}, &models.GatewaySessionFrame{}))
+
+	store := gateway.NewDatabaseSessions(db)
+	first, second := uuid.New(), uuid.New()
+	record := gateway.SessionRecord{ID: uuid.New(), UserID: alice, InstanceID: first, All: true}
+	require.NoError(t, store.Create(record))
+	t.Cleanup(func() { db.Delete(&models.GatewaySession{}, "id = ?", record.ID) })
+	require.NoError(t, store.Append(record.ID, first, []gateway.StreamFrame{{Seq: 1, Frame: []byte("1")}, {Seq: 2, Frame: []byte("2")}}, 2))
+	require.NoError(t, store.Append(record.ID, first, []gateway.StreamFrame{{Seq: 3, Frame: []byte("3")}}, 2))
+	require.NoError(t, store.Subscribe(record.ID, first, false, []uuid.UUID{sharedServer}))
+	require.NoError(t, store.Detach(record.ID, first, time.Now().Add(time.Minute)))
+
+	_, err = store.Claim(record.ID, bob, second)
+	assert.ErrorIs(t, err, gateway.ErrSessionNotFound)
+
+	claimed, err := store.Claim(record.ID, alice, second)
+	require.NoError(t, err)
+	assert.Equal(t, first, claimed.InstanceID)
+	assert.Equal(t, uint64(3), claimed.Seq)
+	assert.False(t, claimed.All)
+	assert.Equal(t, []uuid.UUID{sharedServer}, claimed.Interests)
+
+	// Only the last two dispatches are kept.
+	frames, err := store.Frames(record.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("2"), []byte("3")}, frames)
+
+	// The previous instance no longer holds the session.
+	assert.ErrorIs(t, store.Append(record.ID, first, []gateway.StreamFrame{{Seq: 4, Frame: []byte("4")}}, 2), gateway.ErrSessionMoved)
+	assert.ErrorIs(t, store.Release(record.ID, first), gateway.ErrSessionMoved)
+	require.NoError(t, store.Append(record.ID, second, []gateway.StreamFrame{{Seq: 4, Frame: []byte("4")}}, 2))
+	require.NoError(t, store.Release(record.ID, second))
+	_, err = store.Claim(record.ID, alice, first)
+	assert.ErrorIs(t, err, gateway.ErrSessionNotFound)
+}
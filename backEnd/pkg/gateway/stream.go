@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrStreamUnavailable is returned when a stream cannot load the subscriptions of its user.
+var ErrStreamUnavailable = errors.New("could not load the subscriptions of the stream")
+
+// StreamFrame is a frame received by a stream.
+type StreamFrame struct {
+	// Seq is the sequence number of a dispatch, 0 for other frames.
+	Seq   uint64
+	Frame []byte
+}
+
+// Stream is a receive-only transport of a session, used by clients that cannot open a WebSocket
+// connection such as Server-Sent Events and long polling clients. It receives the same frames as
+// a WebSocket connection after identifying or resuming.
+type Stream struct {
+	hub    *Hub
+	userID uuid.UUID
+
+	frames    chan StreamFrame
+	done      chan struct{}
+	closeOnce sync.Once
+
+	session *session
+}
+
+// PollData is the data of a long polling response.
+type PollData struct {
+	SessionID uuid.UUID `json:"session_id"`
+	// Seq is the sequence number of the last dispatch received, to pass with the next poll.
+	Seq uint64 `json:"seq"`
+	// Frames are the received frames in the gateway envelope.
+	Frames []json.RawMessage `json:"frames"`
+}
+
+// OpenStream opens a stream of a user. It resumes the given session after lastSeq, sending
+// RESUMED afterwards if announce is set. Without a session, or if the session cannot be resumed,
+// it starts a new one, sending invalid_session before READY in the latter case.
+// params:
+// - userID: The ID of the user.
+// - sessionID: The ID of the session to resume, uuid.Nil to start a new one.
+// - lastSeq: The sequence number of the last dispatch the client received.
+// - announce: Whether to send RESUMED after the missed dispatches.
+// returns:
+// - *Stream: The open stream.
+// - error: ErrStreamUnavailable if the subscriptions of the user cannot be loaded.
+func (h *Hub) OpenStream(userID, sessionID uuid.UUID, lastSeq uint64, announce bool) (*Stream, error) {
+	stream := &Stream{
+		hub:    h,
+		userID: userID,
+		frames: make(chan StreamFrame, h.SendBuffer+h.ReplayBuffer),
+		done:   make(chan struct{}),
+	}
+	if sessionID != uuid.Nil {
+		stream.session = h.resume(stream, userID, ResumeData{SessionID: sessionID, Seq: lastSeq}, announce)
+		if stream.session != nil {
+			return stream, nil
+		}
+		frame, _ := encodeFrame(OpInvalidSession, nil)
+		stream.send(0, frame)
+	}
+	stream.session = h.identify(stream, userID)
+	if stream.session == nil {
+		return nil, ErrStreamUnavailable
+	}
+	return stream, nil
+}
+
+// SessionID returns the ID of the session of the stream.
+// returns:
+// - uuid.UUID: The session ID.
+func (s *Stream) SessionID() uuid.UUID {
+	return s.session.id
+}
+
+// Frames returns the channel receiving the frames of the stream.
+// returns:
+// - <-chan StreamFrame: The frames, in the order they were sent.
+func (s *Stream) Frames() <-chan StreamFrame {
+	return s.frames
+}
+
+// Done returns a channel closed when the stream was closed, by Close, because the client did not
+// receive its frames fast enough or because the session was resumed elsewhere.
+// returns:
+// - <-chan struct{}: The closed channel.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close closes the stream, keeping its session for resuming.
+func (s *Stream) Close() {
+	s.session.detach(s)
+	s.close(0, "")
+}
+
+// send queues a frame. A stream whose queue is full is closed as a slow consumer.
+func (s *Stream) send(seq uint64, frame []byte) {
+	select {
+	case <-s.done:
+	case s.frames <- StreamFrame{Seq: seq, Frame: frame}:
+	default:
+		log.Warn().
+			Str("component", "gateway").
+			Str("method_name", "send").
+			Str("event", "slow_consumer_disconnected").
+			Str("user_id", s.userID.String()).
+			Msg("Closing a gateway stream that does not read its events.")
+		s.close(CloseSlowConsumer, "slow consumer")
+	}
+}
+
+// close marks the stream as closed, its reader ends the response.
+func (s *Stream) close(int, string) {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
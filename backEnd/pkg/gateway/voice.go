@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrVoiceUnavailable is returned when the hub has no voice store.
+	ErrVoiceUnavailable = errors.New("voice channels are not available")
+	// ErrVoiceChannelNotFound is returned when the voice channel does not exist or the user is not a member of its server.
+	ErrVoiceChannelNotFound = errors.New("voice channel not found")
+	// ErrNotVoiceChannel is returned when a user tries to join a channel that is not a voice channel.
+	ErrNotVoiceChannel = errors.New("only voice channels can be joined")
+	// ErrVoiceForbidden is returned when the user lacks the connect permission in the channel.
+	ErrVoiceForbidden = errors.New("missing connect permission")
+	// ErrVoiceChannelFull is returned when the channel reached its user limit.
+	ErrVoiceChannelFull = errors.New("the voice channel is full")
+	// ErrVoiceNotConnected is returned when a session that is not in a voice channel sends a signal.
+	ErrVoiceNotConnected = errors.New("the session is not connected to a voice channel")
+	// ErrVoicePeerNotFound is returned when the receiver of a signal is not in the channel of the sender.
+	ErrVoicePeerNotFound = errors.New("the receiving session is not connected to the same voice channel")
+)
+
+// voiceErrors are the errors whose message is sent to the client.
+var voiceErrors = []error{
+	ErrVoiceUnavailable,
+	ErrVoiceChannelNotFound,
+	ErrNotVoiceChannel,
+	ErrVoiceForbidden,
+	ErrVoiceChannelFull,
+	ErrVoiceNotConnected,
+	ErrVoicePeerNotFound,
+}
+
+// VoiceStore keeps the voice states of the sessions connected to voice channels.
+type VoiceStore interface {
+	// Join connects the session of a state to its voice channel or updates its state there. It
+	// checks the channel, the connect permission and the user limit, sets Suppressed and JoinedAt,
+	// and replaces the state of the user in another channel or from another session.
+	// It returns the stored state and the replaced one, nil if the user was not connected.
+	Join(state models.VoiceState) (*models.VoiceState, *models.VoiceState, error)
+	// Leave disconnects a session and returns its state, nil if it was not connected.
+	Leave(sessionID uuid.UUID) (*models.VoiceState, error)
+	// Get returns the state of a session, nil if it is not connected.
+	Get(sessionID uuid.UUID) (*models.VoiceState, error)
+}
+
+// VoiceStateUpdateData is the data of a voice_state_update frame.
+type VoiceStateUpdateData struct {
+	// ChannelID is the voice channel to join or stay in, nil to leave.
+	ChannelID *uuid.UUID `json:"channel_id"`
+	Muted     bool       `json:"muted"`
+	Deafened  bool       `json:"deafened"`
+	Streaming bool       `json:"streaming"`
+	Speaking  bool       `json:"speaking"`
+}
+
+// VoiceSignalType is the kind of a WebRTC signaling message.
+type VoiceSignalType string
+
+const (
+	VoiceSignalOffer     VoiceSignalType = "offer"     // An SDP offer
+	VoiceSignalAnswer    VoiceSignalType = "answer"    // An SDP answer
+	VoiceSignalCandidate VoiceSignalType = "candidate" // An ICE candidate
+)
+
+// VoiceSignalRequest is the data of a voice_signal frame.
+type VoiceSignalRequest struct {
+	// SessionID is the session of the receiving peer.
+	SessionID uuid.UUID       `json:"session_id"`
+	Type      VoiceSignalType `json:"type"`
+	// Data is the session description or candidate, relayed as sent.
+	Data json.RawMessage `json:"data"`
+}
+
+// VoiceSignalData is the data of the VOICE_SIGNAL event.
+type VoiceSignalData struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	// UserID and SessionID are the user and the session of the sending peer.
+	UserID    uuid.UUID       `json:"user_id"`
+	SessionID uuid.UUID       `json:"session_id"`
+	Type      VoiceSignalType `json:"type"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// updateVoice joins, updates or leaves the voice channel of a session and publishes the resulting
+// states to the servers of the channels.
+func (h *Hub) updateVoice(s *session, request VoiceStateUpdateData) error {
+	if h.Voice == nil {
+		return ErrVoiceUnavailable
+	}
+	if request.ChannelID == nil {
+		return h.leaveVoice(s.id)
+	}
+
+	state, previous, err := h.Voice.Join(models.VoiceState{
+		UserID:    s.userID,
+		SessionID: s.id,
+		ChannelID: request.ChannelID,
+		Muted:     request.Muted,
+		Deafened:  request.Deafened,
+		Streaming: request.Streaming,
+		Speaking:  request.Speaking,
+	})
+	if err != nil {
+		return err
+	}
+	// The server of the new channel learns about the move with the new state.
+	if previous != nil && previous.ServerID != state.ServerID {
+		previous.ChannelID = nil
+		h.publishVoice(previous)
+	}
+	h.publishVoice(state)
+	return nil
+}
+
+// leaveVoice disconnects a session from its voice channel and announces it.
+func (h *Hub) leaveVoice(sessionID uuid.UUID) error {
+	state, err := h.Voice.Leave(sessionID)
+	if err != nil || state == nil {
+		return err
+	}
+	state.ChannelID = nil
+	h.publishVoice(state)
+	return nil
+}
+
+// publishVoice publishes a voice state to its server.
+func (h *Hub) publishVoice(state *models.VoiceState) {
+	h.publish(Event{Type: EventVoiceStateUpdate, Topics: []uuid.UUID{state.ServerID}, Data: state})
+}
+
+// relaySignal relays a signaling message of a session to a peer in its voice channel.
+func (h *Hub) relaySignal(s *session, request VoiceSignalRequest) error {
+	if h.Voice == nil {
+		return ErrVoiceUnavailable
+	}
+	sender, err := h.Voice.Get(s.id)
+	if err != nil {
+		return err
+	}
+	if sender == nil {
+		return ErrVoiceNotConnected
+	}
+	receiver, err := h.Voice.Get(request.SessionID)
+	if err != nil {
+		return err
+	}
+	if receiver == nil || *receiver.ChannelID != *sender.ChannelID || receiver.SessionID == s.id {
+		return ErrVoicePeerNotFound
+	}
+
+	// Sessions follow their own ID, so the signal reaches the receiver on any instance.
+	h.publish(Event{
+		Type:   EventVoiceSignal,
+		Topics: []uuid.UUID{receiver.SessionID},
+		Data: VoiceSignalData{
+			ChannelID: *sender.ChannelID,
+			UserID:    s.userID,
+			SessionID: s.id,
+			Type:      request.Type,
+			Data:      request.Data,
+		},
+	})
+	return nil
+}
+
+// voiceError returns the message of a voice error sent to the client. Unexpected errors are logged.
+func voiceError(userID uuid.UUID, err error) string {
+	for _, known := range voiceErrors {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	log.Error().
+		Str("component", "gateway").
+		Str("method_name", "voiceError").
+		Str("event", "voice_update_failed").
+		Str("user_id", userID.String()).
+		Err(err).
+		Msg("Could not update a voice state.")
+	return "could not update the voice state"
+}
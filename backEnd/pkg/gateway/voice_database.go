@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"errors"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DatabaseVoice keeps the voice states in the voice_states table, shared by every instance.
+// States of an instance that stops without its sessions expiring stay until their users join again.
+type DatabaseVoice struct {
+	db *gorm.DB
+}
+
+// NewDatabaseVoice creates a voice store on the database.
+// params:
+// - db: The GORM database instance.
+// returns:
+// - *DatabaseVoice: The voice store.
+func NewDatabaseVoice(db *gorm.DB) *DatabaseVoice {
+	return &DatabaseVoice{db: db}
+}
+
+// Join connects the session of a state to its voice channel or updates its state there.
+// params:
+// - state: The state with UserID, SessionID, ChannelID and the flags set by the client.
+// returns:
+// - *models.VoiceState: The stored state.
+// - *models.VoiceState: The replaced state of the user, nil if they were not connected.
+// - error: ErrVoiceChannelNotFound, ErrNotVoiceChannel, ErrVoiceForbidden, ErrVoiceChannelFull or a database error.
+func (v *DatabaseVoice) Join(state models.VoiceState) (*models.VoiceState, *models.VoiceState, error) {
+	var previous *models.VoiceState
+	err := v.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the channel serializes the joins, so the user limit holds.
+		var channel models.Channel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&channel, "id = ?", *state.ChannelID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVoiceChannelNotFound
+			}
+			return err
+		}
+		if channel.Type != models.ChannelTypeVoice {
+			return ErrNotVoiceChannel
+		}
+
+		perms, err := permissions.ForServer(tx, channel.ServerID, state.UserID)
+		if err != nil {
+			if errors.Is(err, permissions.ErrServerNotFound) || errors.Is(err, permissions.ErrNotMember) {
+				return ErrVoiceChannelNotFound
+			}
+			return err
+		}
+		perms = perms.Without(channel.DeniedPermissions)
+		if !perms.Has(models.PermissionViewChannels) {
+			return ErrVoiceChannelNotFound
+		}
+		if !perms.Has(models.PermissionConnect) {
+			return ErrVoiceForbidden
+		}
+
+		var existing models.VoiceState
+		err = tx.First(&existing, "user_id = ?", state.UserID).Error
+		if err == nil {
+			previous = &existing
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		state.ServerID = channel.ServerID
+		state.JoinedAt = time.Now()
+		if previous != nil && *previous.ChannelID == channel.ID {
+			state.JoinedAt = previous.JoinedAt
+		} else if channel.UserLimit > 0 {
+			var count int64
+			if err := tx.Model(&models.VoiceState{}).Where("channel_id = ?", channel.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(channel.UserLimit) {
+				return ErrVoiceChannelFull
+			}
+		}
+		state.Suppressed = !perms.Has(models.PermissionSpeak)
+		if state.Suppressed {
+			state.Speaking = false
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"session_id", "channel_id", "server_id", "muted", "deafened", "streaming", "speaking", "suppressed", "joined_at", "updated_at"}),
+		}).Create(&state).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &state, previous, nil
+}
+
+// Leave disconnects a session from its voice channel.
+// params:
+// - sessionID: The ID of the gateway session.
+// returns:
+// - *models.VoiceState: The removed state, nil if the session was not connected.
+// - error: A database error.
+func (v *DatabaseVoice) Leave(sessionID uuid.UUID) (*models.VoiceState, error) {
+	var states []models.VoiceState
+	err := v.db.Clauses(clause.Returning{}).Where("session_id = ?", sessionID).Delete(&states).Error
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+	return &states[0], nil
+}
+
+// Get returns the voice state of a session.
+// params:
+// - sessionID: The ID of the gateway session.
+// returns:
+// - *models.VoiceState: The state, nil if the session is not connected.
+// - error: A database error.
+func (v *DatabaseVoice) Get(sessionID uuid.UUID) (*models.VoiceState, error) {
+	var state models.VoiceState
+	err := v.db.First(&state, "session_id = ?", sessionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
@@ -0,0 +1,168 @@
+package gateway_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	carol        = uuid.MustParse("7f3a2b1c-0d9e-4f8a-b7c6-d5e4f3a2b1c0")
+	voiceChannel = uuid.MustParse("2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e")
+)
+
+// voiceStub is a VoiceStore in memory with a single voice channel in the shared server.
+type voiceStub struct {
+	mu     sync.Mutex
+	limit  int
+	states map[uuid.UUID]models.VoiceState
+}
+
+func (v *voiceStub) Join(state models.VoiceState) (*models.VoiceState, *models.VoiceState, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if *state.ChannelID != voiceChannel {
+		return nil, nil, gateway.ErrVoiceChannelNotFound
+	}
+	var previous *models.VoiceState
+	if existing, ok := v.states[state.UserID]; ok {
+		previous = &existing
+	} else if len(v.states) >= v.limit {
+		return nil, nil, gateway.ErrVoiceChannelFull
+	}
+	state.ServerID = sharedServer
+	v.states[state.UserID] = state
+	return &state, previous, nil
+}
+
+func (v *voiceStub) Leave(sessionID uuid.UUID) (*models.VoiceState, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for userID, state := range v.states {
+		if state.SessionID == sessionID {
+			delete(v.states, userID)
+			return &state, nil
+		}
+	}
+	return nil, nil
+}
+
+func (v *voiceStub) Get(sessionID uuid.UUID) (*models.VoiceState, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, state := range v.states {
+		if state.SessionID == sessionID {
+			return &state, nil
+		}
+	}
+	return nil, nil
+}
+
+// readVoiceState reads the next frame of a connection as a VOICE_STATE_UPDATE.
+func readVoiceState(t *testing.T, ws *websocket.Conn) models.VoiceState {
+	t.Helper()
+	frame := readFrame(t, ws)
+	require.Equal(t, gateway.EventVoiceStateUpdate, frame.Type)
+	var state models.VoiceState
+	require.NoError(t, json.Unmarshal(frame.Data, &state))
+	return state
+}
+
+// readError reads the next frame of a connection as an error and returns its message.
+func readError(t *testing.T, ws *websocket.Conn) string {
+	t.Helper()
+	frame := readFrame(t, ws)
+	require.Equal(t, gateway.OpError, frame.Op)
+	var data gateway.ErrorData
+	require.NoError(t, json.Unmarshal(frame.Data, &data))
+	return data.Message
+}
+
+// TestVoice tests joining voice channels, relaying signals between peers and leaving.
+func TestVoice(t *testing.T) {
+	hub, stub, url := newTestHub(t)
+	hub.SessionTimeout = 10 * time.Millisecond
+	hub.Voice = &voiceStub{limit: 2, states: make(map[uuid.UUID]models.VoiceState)}
+	stub.set(carol, gateway.Access{ServerIDs: []uuid.UUID{sharedServer}})
+
+	aliceWS, aliceReady := connect(t, url, alice)
+	bobWS, bobReady := connect(t, url, bob)
+	readFrame(t, aliceWS) // Bob comes online
+	carolWS, _ := connect(t, url, carol)
+	readFrame(t, aliceWS) // Carol comes online
+	readFrame(t, bobWS)
+
+	// Joining is announced to the server, with the flags of the client.
+	writeFrame(t, aliceWS, gateway.OpVoiceStateUpdate, gateway.VoiceStateUpdateData{ChannelID: &voiceChannel})
+	for _, ws := range []*websocket.Conn{aliceWS, bobWS, carolWS} {
+		state := readVoiceState(t, ws)
+		assert.Equal(t, alice, state.UserID)
+		assert.Equal(t, aliceReady.SessionID, state.SessionID)
+		assert.Equal(t, &voiceChannel, state.ChannelID)
+	}
+	writeFrame(t, bobWS, gateway.OpVoiceStateUpdate, gateway.VoiceStateUpdateData{ChannelID: &voiceChannel, Muted: true})
+	for _, ws := range []*websocket.Conn{aliceWS, bobWS, carolWS} {
+		state := readVoiceState(t, ws)
+		assert.Equal(t, bob, state.UserID)
+		assert.True(t, state.Muted)
+	}
+
+	// The channel is full.
+	writeFrame(t, carolWS, gateway.OpVoiceStateUpdate, gateway.VoiceStateUpdateData{ChannelID: &voiceChannel})
+	assert.Equal(t, gateway.ErrVoiceChannelFull.Error(), readError(t, carolWS))
+
+	// Signals only reach the addressed session, with the sender.
+	offer := json.RawMessage(`{"type":"offer","sdp":"v=0"}`)
+	writeFrame(t, aliceWS, gateway.OpVoiceSignal, gateway.VoiceSignalRequest{SessionID: bobReady.SessionID, Type: gateway.VoiceSignalOffer, Data: offer})
+	signal := readFrame(t, bobWS)
+	require.Equal(t, gateway.EventVoiceSignal, signal.Type)
+	var signalData gateway.VoiceSignalData
+	require.NoError(t, json.Unmarshal(signal.Data, &signalData))
+	assert.Equal(t, gateway.VoiceSignalData{
+		ChannelID: voiceChannel,
+		UserID:    alice,
+		SessionID: aliceReady.SessionID,
+		Type:      gateway.VoiceSignalOffer,
+		Data:      offer,
+	}, signalData)
+
+	// Peers outside of the channel cannot be reached, and sessions outside cannot signal.
+	writeFrame(t, bobWS, gateway.OpVoiceSignal, gateway.VoiceSignalRequest{SessionID: uuid.New(), Type: gateway.VoiceSignalAnswer, Data: offer})
+	assert.Equal(t, gateway.ErrVoicePeerNotFound.Error(), readError(t, bobWS))
+	writeFrame(t, carolWS, gateway.OpVoiceSignal, gateway.VoiceSignalRequest{SessionID: bobReady.SessionID, Type: gateway.VoiceSignalOffer, Data: offer})
+	assert.Equal(t, gateway.ErrVoiceNotConnected.Error(), readError(t, carolWS))
+	writeFrame(t, aliceWS, gateway.OpVoiceSignal, gateway.VoiceSignalRequest{SessionID: bobReady.SessionID, Type: "hangup", Data: offer})
+	assert.Equal(t, gateway.OpError, readFrame(t, aliceWS).Op)
+
+	// Leaving is announced with a null channel.
+	writeFrame(t, aliceWS, gateway.OpVoiceStateUpdate, gateway.VoiceStateUpdateData{})
+	for _, ws := range []*websocket.Conn{aliceWS, bobWS, carolWS} {
+		state := readVoiceState(t, ws)
+		assert.Equal(t, alice, state.UserID)
+		assert.Nil(t, state.ChannelID)
+	}
+
+	// An expired session leaves its voice channel.
+	require.NoError(t, bobWS.Close())
+	presence := readFrame(t, aliceWS)
+	require.Equal(t, gateway.EventPresenceUpdate, presence.Type)
+	state := readVoiceState(t, aliceWS)
+	assert.Equal(t, bob, state.UserID)
+	assert.Nil(t, state.ChannelID)
+}
+
+// TestVoiceUnavailable tests that hubs without a voice store reject voice frames.
+func TestVoiceUnavailable(t *testing.T) {
+	_, _, url := newTestHub(t)
+	ws, _ := connect(t, url, alice)
+	writeFrame(t, ws, gateway.OpVoiceStateUpdate, gateway.VoiceStateUpdateData{ChannelID: &voiceChannel})
+	assert.Equal(t, gateway.ErrVoiceUnavailable.Error(), readError(t, ws))
+}
@@ -0,0 +1,339 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/channeltree"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// channelCreateRequest is the expected JSON body of a channel creation request.
+type channelCreateRequest struct {
+	Name   string             `json:"name"`
+	Type   models.ChannelType `json:"type"`
+	Topic  *string            `json:"topic"`
+	Icon   *string            `json:"icon"`
+	Parent *uuid.UUID         `json:"parent"`
+	// UserLimit is the maximum number of users in a voice channel, 0 for no limit.
+	UserLimit int `json:"user_limit"`
+}
+
+// ChannelCreateHandler handles HTTP POST requests for creating a channel in a server.
+// It expects the server ID in the URL path and a JSON body with the channel data.
+// The caller must have the manage channels permission in the server.
+// Categories can only be created at the top level, other channels may be placed inside a category.
+func ChannelCreateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "channel_handler"
+		METHOD_NAME    string = "ChannelCreateHandler"
+		CONTEXT        string = "api/servers/{id}/channels"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.Channel]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing channel creation request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ChannelCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for channel creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the server ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	serverID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid server ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid server ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the permissions of the caller in the server.
+	perms, err := permissions.ForServer(db, serverID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, permissions.ErrServerNotFound):
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Server not found", nil)
+		case errors.Is(err, permissions.ErrNotMember):
+			apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a member of this server", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving server permissions", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "server_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve server permissions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !perms.Has(models.PermissionManageChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to manage channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request channelCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Params["name"] = request.Name
+	apiResponse.Params["type"] = request.Type
+	apiResponse.Params["parent"] = request.Parent
+
+	// --- VALIDATION SECTION ---
+	if !validation.ValidateChannelName(request.Name) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid channel name", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_name").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("name", request.Name).
+			Msg("Validation error: invalid channel name.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !validation.ValidateChannelType(request.Type) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid channel type", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_type").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("type", string(request.Type)).
+			Msg("Validation error: invalid channel type.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if request.Topic != nil && !validation.ValidateChannelTopic(*request.Topic) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid channel topic", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_topic").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Validation error: invalid channel topic.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !validation.ValidateChannelUserLimit(request.Type, request.UserLimit) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid user limit, only voice channels can be limited to 1-99 users", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_user_limit").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int("user_limit", request.UserLimit).
+			Msg("Validation error: invalid user limit.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	newChannel := models.Channel{
+		ServerID:  serverID,
+		Name:      request.Name,
+		Type:      request.Type,
+		Topic:     request.Topic,
+		Icon:      request.Icon,
+		Parent:    request.Parent,
+		UserLimit: request.UserLimit,
+	}
+
+	// Fetch the parent channel, if any, and check that the channel may be nested under it.
+	var parent *models.Channel
+	if request.Parent != nil {
+		var fetchedParent models.Channel
+		result := db.First(&fetchedParent, "id = ? AND server_id = ?", *request.Parent, serverID)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Parent channel not found in this server", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching parent channel", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "parent_channel_fetch_failed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("parent", request.Parent.String()).
+				Err(result.Error).
+				Msg("Error fetching parent channel.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		parent = &fetchedParent
+	}
+
+	if err := channeltree.ValidatePlacement(&newChannel, parent, false); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_parent").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Validation error: invalid channel placement.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	// Append the new channel to the end of its sibling group.
+	var siblings []models.Channel
+	if result := db.Select("id", "parent", "type", "position").Where("server_id = ?", serverID).Find(&siblings); result.Error != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching channels of the server", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_siblings").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(result.Error).
+			Msg("Database error fetching sibling channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	newChannel.Position = channeltree.NextPosition(siblings, &newChannel)
+
+	// Insert the new channel into the database.
+	if result := db.Create(&newChannel); result.Error != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating channel due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_creating_channel").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(result.Error).
+			Msg("Database error creating channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Channel created successfully."
+	apiResponse.Data = &models.ResponseData[models.Channel]{
+		Items: []models.Channel{newChannel},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "channel_created_success").
+		Str("channel_id", newChannel.ID.String()).
+		Str("server_id", serverID.String()).
+		Msg("Successfully created channel.")
+
+	gateway.Publish(gateway.Event{Type: gateway.EventChannelCreate, Topics: []uuid.UUID{serverID}, Data: newChannel})
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,211 @@
+package channel
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ChannelDeleteHandler handles HTTP DELETE requests for deleting a channel of a server.
+// It expects the server and channel IDs in the URL path.
+// The caller must have the manage channels permission in the server.
+// Deleting a category moves its channels to the top level instead of deleting them.
+func ChannelDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "channel_handler"
+		METHOD_NAME    string = "ChannelDeleteHandler"
+		CONTEXT        string = "api/servers/{id}/channels/{channelId}"
+		METHOD         string = "DELETE"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.Channel]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing channel deletion request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ChannelDeleteHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for channel deletion.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the server and channel IDs from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":        vars["id"],
+		"channelId": vars["channelId"],
+	}
+	serverID, serverErr := uuid.Parse(vars["id"])
+	channelID, channelErr := uuid.Parse(vars["channelId"])
+	if serverErr != nil || channelErr != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid server or channel ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Str("channel_id", vars["channelId"]).
+			Msg("Invalid server or channel ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the permissions of the caller in the server.
+	perms, err := permissions.ForServer(db, serverID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, permissions.ErrServerNotFound):
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Server not found", nil)
+		case errors.Is(err, permissions.ErrNotMember):
+			apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a member of this server", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving server permissions", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "server_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve server permissions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !perms.Has(models.PermissionManageChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to manage channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the channel to delete, scoped to the server from the path.
+	var existingChannel models.Channel
+	result := db.First(&existingChannel, "id = ? AND server_id = ?", channelID, serverID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Channel not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching channel for deletion", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Err(result.Error).
+			Msg("Error fetching channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Move the children of the channel to the top level and delete the channel with its read states
+	// in one transaction.
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Channel{}).Where("parent = ?", channelID).Update("parent", nil).Error; err != nil {
+			return err
+		}
+		// Read states reference the channel without a foreign key.
+		if err := tx.Where("channel_id = ?", channelID).Delete(&models.ReadState{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&existingChannel).Error
+	})
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error deleting channel due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_deleting_channel").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error deleting channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	deleted := true
+	apiResponse.Message = "Channel deleted successfully."
+	apiResponse.Data = &models.ResponseData[models.Channel]{
+		Deleted: &deleted,
+		Items:   []models.Channel{existingChannel},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "channel_deleted").
+		Str("channel_id", channelID.String()).
+		Msg("Channel deleted successfully.")
+
+	gateway.Publish(gateway.Event{Type: gateway.EventChannelDelete, Topics: []uuid.UUID{serverID}, Data: gateway.ChannelDeleteData{ID: channelID, ServerID: serverID}})
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,173 @@
+package channel
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/channeltree"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// ChannelListHandler handles HTTP GET requests for listing the channels of a server.
+// It returns the ordered channel tree in one response: top-level channels and categories
+// as items, with the channels of each category nested in its "children" field.
+// The caller must be able to view the channels of the server.
+func ChannelListHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "channel_handler"
+		METHOD_NAME    string = "ChannelListHandler"
+		CONTEXT        string = "api/servers/{id}/channels"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.Channel]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing channel list request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ChannelListHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for listing channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the server ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	serverID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid server ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid server ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the permissions of the caller in the server.
+	perms, err := permissions.ForServer(db, serverID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, permissions.ErrServerNotFound):
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Server not found", nil)
+		case errors.Is(err, permissions.ErrNotMember):
+			apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a member of this server", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving server permissions", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "server_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve server permissions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !perms.Has(models.PermissionViewChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing view channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to view channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch all channels of the server in one query and build the tree in memory.
+	var channels []models.Channel
+	if result := db.Where("server_id = ?", serverID).Find(&channels); result.Error != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching channels", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(result.Error).
+			Msg("Error fetching channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Data = &models.ResponseData[models.Channel]{
+		Pagination: &models.Pagination{TotalItems: len(channels)},
+		Items:      channeltree.Build(channels),
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "channels_fetched").
+		Str("server_id", serverID.String()).
+		Int("channel_count", len(channels)).
+		Msg("Channels fetched successfully.")
+
+	models.SendApiResponse(w, apiResponse)
+}
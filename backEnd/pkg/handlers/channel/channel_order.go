@@ -0,0 +1,287 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/channeltree"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// channelMoveRequest is one entry of the JSON array expected by the channel order endpoint.
+// If "parent" is omitted the channel stays under its current parent, null moves it to the top level.
+type channelMoveRequest struct {
+	ID       uuid.UUID                  `json:"id"`
+	Parent   models.Nullable[uuid.UUID] `json:"parent"`
+	Position *int                       `json:"position"`
+}
+
+// ChannelOrderHandler handles HTTP PATCH requests for reordering the channels of a server.
+// It expects the server ID in the URL path and a JSON array with a full or partial list of
+// {id, parent, position} moves. The moves are applied atomically in one transaction,
+// the positions of every sibling group are normalised, and the updated channel tree is returned.
+// The caller must have the manage channels permission in the server.
+func ChannelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "channel_handler"
+		METHOD_NAME    string = "ChannelOrderHandler"
+		CONTEXT        string = "api/servers/{id}/channels/order"
+		METHOD         string = "PATCH"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.Channel]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing channel reorder request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ChannelOrderHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for channel reordering.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the server ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	serverID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid server ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid server ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the permissions of the caller in the server.
+	perms, err := permissions.ForServer(db, serverID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, permissions.ErrServerNotFound):
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Server not found", nil)
+		case errors.Is(err, permissions.ErrNotMember):
+			apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a member of this server", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving server permissions", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "server_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve server permissions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !perms.Has(models.PermissionManageChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to manage channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request []channelMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data, expected an array of moves", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Params["moves"] = len(request)
+
+	if len(request) == 0 {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("At least one move is required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_no_moves").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Validation error: empty move list.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	moves := make([]channeltree.Move, 0, len(request))
+	for _, move := range request {
+		if move.Position == nil {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Every move requires a position", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_missing_position").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("channel_id", move.ID.String()).
+				Msg("Validation error: move without position.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		moves = append(moves, channeltree.Move{
+			ID:        move.ID,
+			ParentSet: move.Parent.Set,
+			Parent:    move.Parent.Value,
+			Position:  *move.Position,
+		})
+	}
+
+	// Lock the channels of the server, apply the moves and persist the changes in one transaction,
+	// so concurrent reorders cannot interleave and a failing move leaves the order untouched.
+	var channels []models.Channel
+	var moveErr error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("server_id = ?", serverID).Find(&channels).Error; err != nil {
+			return err
+		}
+
+		changed, err := channeltree.ApplyMoves(channels, moves)
+		if err != nil {
+			moveErr = err
+			return err
+		}
+
+		for _, channel := range changed {
+			updates := map[string]interface{}{
+				"parent":   channel.Parent,
+				"position": channel.Position,
+			}
+			if err := tx.Model(&models.Channel{}).Where("id = ?", channel.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("server_id = ?", serverID).Find(&channels).Error
+	})
+	if moveErr != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(moveErr.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_move").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(moveErr).
+			Msg("Validation error: invalid channel move.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error reordering channels due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_reordering_channels").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error reordering channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Channels reordered successfully."
+	apiResponse.Data = &models.ResponseData[models.Channel]{
+		Pagination: &models.Pagination{TotalItems: len(channels)},
+		Items:      channeltree.Build(channels),
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "channels_reordered").
+		Str("server_id", serverID.String()).
+		Int("move_count", len(moves)).
+		Msg("Channels reordered successfully.")
+
+	// A move can shift the positions of any channel, so every channel of the server is sent.
+	for _, channel := range channels {
+		gateway.Publish(gateway.Event{Type: gateway.EventChannelUpdate, Topics: []uuid.UUID{serverID}, Data: channel})
+	}
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,446 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/channeltree"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// channelUpdateRequest is the expected JSON body of a channel update request.
+// Omitted fields are left unchanged, "parent": null moves the channel to the top level.
+type channelUpdateRequest struct {
+	Name   *string                    `json:"name"`
+	Type   *models.ChannelType        `json:"type"`
+	Topic  *string                    `json:"topic"`
+	Icon   *string                    `json:"icon"`
+	Parent models.Nullable[uuid.UUID] `json:"parent"`
+	// DeniedPermissions replaces the permissions denied to the members in the channel.
+	DeniedPermissions *models.Permission `json:"denied_permissions"`
+	// UserLimit is the maximum number of users in a voice channel, 0 for no limit.
+	UserLimit *int `json:"user_limit"`
+}
+
+// ChannelUpdateHandler handles HTTP PATCH requests for updating a channel of a server.
+// It expects the server and channel IDs in the URL path and a JSON body with the fields to update.
+// The caller must have the manage channels permission in the server.
+// A channel can be switched between chat and voice, but never converted to or from a category.
+// Denied permissions, for example reactions, apply to every member except administrators.
+func ChannelUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "channel_handler"
+		METHOD_NAME    string = "ChannelUpdateHandler"
+		CONTEXT        string = "api/servers/{id}/channels/{channelId}"
+		METHOD         string = "PATCH"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.Channel]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing channel update request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ChannelUpdateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for channel update.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the server and channel IDs from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":        vars["id"],
+		"channelId": vars["channelId"],
+	}
+	serverID, serverErr := uuid.Parse(vars["id"])
+	channelID, channelErr := uuid.Parse(vars["channelId"])
+	if serverErr != nil || channelErr != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid server or channel ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Str("channel_id", vars["channelId"]).
+			Msg("Invalid server or channel ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the permissions of the caller in the server.
+	perms, err := permissions.ForServer(db, serverID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, permissions.ErrServerNotFound):
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Server not found", nil)
+		case errors.Is(err, permissions.ErrNotMember):
+			apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a member of this server", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving server permissions", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "server_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve server permissions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !perms.Has(models.PermissionManageChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to manage channels.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the existing channel, scoped to the server from the path.
+	var existingChannel models.Channel
+	result := db.First(&existingChannel, "id = ? AND server_id = ?", channelID, serverID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Channel not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching channel for update", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Err(result.Error).
+			Msg("Error fetching channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request channelUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data for update", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Prepare a map of fields to update for GORM and the resulting channel for validation.
+	updateParams := make(map[string]interface{})
+	updatedChannel := existingChannel
+
+	// --- VALIDATION SECTION ---
+	if request.Name != nil {
+		if !validation.ValidateChannelName(*request.Name) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid channel name", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_name").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("name", *request.Name).
+				Msg("Validation error: invalid channel name.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		updateParams["name"] = *request.Name
+		updatedChannel.Name = *request.Name
+	}
+
+	if request.Type != nil {
+		isCategory := existingChannel.Type == models.ChannelTypeCategory
+		becomesCategory := *request.Type == models.ChannelTypeCategory
+		if !validation.ValidateChannelType(*request.Type) || isCategory != becomesCategory {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid channel type, channels cannot be converted to or from categories", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_type").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("type", string(*request.Type)).
+				Msg("Validation error: invalid channel type.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		updateParams["type"] = *request.Type
+		updatedChannel.Type = *request.Type
+	}
+
+	if request.Topic != nil {
+		if !validation.ValidateChannelTopic(*request.Topic) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid channel topic", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_topic").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Msg("Validation error: invalid channel topic.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		updateParams["topic"] = *request.Topic
+	}
+
+	if request.Icon != nil {
+		updateParams["icon"] = *request.Icon
+	}
+
+	if request.DeniedPermissions != nil {
+		if !validation.ValidateChannelDeniedPermissions(*request.DeniedPermissions) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Channels can only deny the send messages, add reactions, connect and speak permissions", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_denied_permissions").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Int64("denied_permissions", int64(*request.DeniedPermissions)).
+				Msg("Validation error: invalid denied permissions.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		updateParams["denied_permissions"] = *request.DeniedPermissions
+	}
+
+	// The user limit is validated against the resulting type, channels converted from voice
+	// channels lose their limit.
+	if request.UserLimit != nil {
+		if !validation.ValidateChannelUserLimit(updatedChannel.Type, *request.UserLimit) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid user limit, only voice channels can be limited to 1-99 users", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_user_limit").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Int("user_limit", *request.UserLimit).
+				Msg("Validation error: invalid user limit.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		updateParams["user_limit"] = *request.UserLimit
+	} else if updatedChannel.Type != models.ChannelTypeVoice && updatedChannel.UserLimit != 0 {
+		updateParams["user_limit"] = 0
+	}
+
+	if request.Parent.Set {
+		var parent *models.Channel
+		if request.Parent.Value != nil {
+			var fetchedParent models.Channel
+			result := db.First(&fetchedParent, "id = ? AND server_id = ?", *request.Parent.Value, serverID)
+			if result.Error != nil {
+				if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+					apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Parent channel not found in this server", nil)
+				} else {
+					apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching parent channel", nil)
+				}
+				log.Warn().
+					Str("component", COMPONENT).
+					Str("method_name", METHOD_NAME).
+					Str("event", "parent_channel_fetch_failed").
+					Str("api_error_code", apiResponse.Error.Code).
+					Str("api_error_message", apiResponse.Error.Message).
+					Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+					Str("parent", request.Parent.Value.String()).
+					Err(result.Error).
+					Msg("Error fetching parent channel.")
+				models.SendApiResponse(w, apiResponse)
+				return
+			}
+			parent = &fetchedParent
+		}
+
+		// Count the children of the channel, a channel with children can never be nested.
+		var childCount int64
+		if result := db.Model(&models.Channel{}).Where("parent = ?", channelID).Count(&childCount); result.Error != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error counting child channels", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "database_error_counting_children").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Err(result.Error).
+				Msg("Database error counting child channels.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+
+		if err := channeltree.ValidatePlacement(&updatedChannel, parent, childCount > 0); err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_parent").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Err(err).
+				Msg("Validation error: invalid channel placement.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		// Append the channel to the end of its new sibling group when the parent changes.
+		if !sameParent(existingChannel.Parent, request.Parent.Value) {
+			var siblings []models.Channel
+			if result := db.Select("id", "parent", "type", "position").Where("server_id = ? AND id <> ?", serverID, channelID).Find(&siblings); result.Error != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching channels of the server", nil)
+				log.Error().
+					Str("component", COMPONENT).
+					Str("method_name", METHOD_NAME).
+					Str("event", "database_error_fetching_siblings").
+					Str("api_error_code", apiResponse.Error.Code).
+					Str("api_error_message", apiResponse.Error.Message).
+					Err(result.Error).
+					Msg("Database error fetching sibling channels.")
+				models.SendApiResponse(w, apiResponse)
+				return
+			}
+			updatedChannel.Parent = request.Parent.Value
+			updateParams["parent"] = request.Parent.Value
+			updateParams["position"] = channeltree.NextPosition(siblings, &updatedChannel)
+		}
+	}
+	// --- END VALIDATION SECTION ---
+
+	apiResponse.Params["updates"] = updateParams
+
+	// Perform the update only on the provided columns.
+	if len(updateParams) > 0 {
+		if result := db.Model(&existingChannel).Updates(updateParams); result.Error != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error updating channel due to a database issue", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "database_error_updating_channel").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Err(result.Error).
+				Msg("Database error updating channel.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Re-fetch the channel to return its latest state.
+	if result := db.First(&existingChannel, "id = ?", channelID); result.Error != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully updated channel but failed to re-fetch", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_channel").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(result.Error).
+			Msg("Database error re-fetching channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Channel updated successfully."
+	apiResponse.Data = &models.ResponseData[models.Channel]{
+		Items: []models.Channel{existingChannel},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "channel_updated").
+		Str("channel_id", channelID.String()).
+		Msg("Channel updated successfully.")
+
+	gateway.Publish(gateway.Event{Type: gateway.EventChannelUpdate, Topics: []uuid.UUID{serverID}, Data: existingChannel})
+
+	models.SendApiResponse(w, apiResponse)
+}
+
+// sameParent reports whether two parent references point to the same channel.
+func sameParent(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,72 @@
+package conversation
+
+import (
+	"errors"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// conversationAccessError maps the errors returned by permissions.ForConversation to the matching api error.
+func conversationAccessError(err error) *models.CustomError {
+	switch {
+	case errors.Is(err, permissions.ErrConversationNotFound):
+		return apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Conversation not found", nil)
+	case errors.Is(err, permissions.ErrNotParticipant):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a participant of this conversation", nil)
+	}
+	return apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving conversation", nil)
+}
+
+// directMessageError maps the errors returned by permissions.CanDirectMessage to the matching api error.
+func directMessageError(err error) *models.CustomError {
+	switch {
+	case errors.Is(err, permissions.ErrUserBlocked):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Direct messages are not possible with this user", nil)
+	case errors.Is(err, permissions.ErrDirectMessagesClosed):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("This user only accepts direct messages from friends", nil)
+	}
+	return apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error checking direct message settings", nil)
+}
+
+// preloadParticipants adds the participants of conversations to a query, with the public
+// columns of their users, in the order they joined.
+func preloadParticipants(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Participants", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("joined_at ASC, user_id ASC")
+		}).
+		Preload("Participants.User", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("id", "username", "profile_picture_asset_id")
+		})
+}
+
+// fetchConversation loads a conversation with its participants.
+func fetchConversation(db *gorm.DB, id uuid.UUID) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := preloadParticipants(db).First(&conversation, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// countParticipants returns the number of participants of a conversation.
+func countParticipants(db *gorm.DB, conversationID uuid.UUID) (int64, error) {
+	var count int64
+	err := db.Model(&models.ConversationParticipant{}).Where("conversation_id = ?", conversationID).Count(&count).Error
+	return count, err
+}
+
+// isOwnImage reports whether the media asset exists, was uploaded by the user and is an image
+// allowed for icons.
+func isOwnImage(db *gorm.DB, assetID uuid.UUID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.MediaAsset{}).
+		Where("id = ? AND uploaded_by_user_id = ? AND content_type IN ?", assetID, userID, mediatype.Allowlist(mediatype.ContextServerIcon)).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,359 @@
+package conversation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// conversationCreateRequest is the expected JSON body of a conversation creation request.
+// Direct conversations need RecipientID, group conversations need Participants.
+type conversationCreateRequest struct {
+	Type         models.ConversationType `json:"type"`
+	RecipientID  *uuid.UUID              `json:"recipient_id"`
+	Participants []uuid.UUID             `json:"participants"`
+	Name         *string                 `json:"name"`
+	IconAssetID  *uuid.UUID              `json:"icon_asset_id"`
+}
+
+// ConversationCreateHandler handles HTTP POST requests for opening a conversation.
+// For "direct" conversations it expects a recipient_id. Direct conversations are created
+// lazily and deduplicated per user pair: if one already exists it is returned with 200 OK.
+// For "group" conversations it expects the other participants and optionally a name and an
+// icon uploaded by the caller, who becomes the owner of the group.
+// Every contacted user must accept direct messages from the caller.
+func ConversationCreateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "conversation_handler"
+		METHOD_NAME    string = "ConversationCreateHandler"
+		CONTEXT        string = "api/conversations"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.ConversationPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing conversation creation request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ConversationCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for conversation creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request conversationCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Params = map[string]interface{}{
+		"type":          request.Type,
+		"recipient_id":  request.RecipientID,
+		"participants":  request.Participants,
+		"name":          request.Name,
+		"icon_asset_id": request.IconAssetID,
+	}
+
+	// --- VALIDATION SECTION ---
+	// Collect the users to contact, without duplicates and without the caller.
+	var others []uuid.UUID
+	switch request.Type {
+	case models.ConversationTypeDirect:
+		if request.RecipientID == nil || *request.RecipientID == userID || len(request.Participants) > 0 || request.Name != nil || request.IconAssetID != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Direct conversations need exactly one recipient other than yourself and no name or icon", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_direct_conversation").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Msg("Validation error: invalid direct conversation request.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		others = []uuid.UUID{*request.RecipientID}
+
+	case models.ConversationTypeGroup:
+		seen := map[uuid.UUID]bool{userID: true}
+		for _, participantID := range request.Participants {
+			if !seen[participantID] {
+				seen[participantID] = true
+				others = append(others, participantID)
+			}
+		}
+		if request.RecipientID != nil || !validation.ValidateParticipantCount(len(others)+1) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(fmt.Sprintf("Group conversations need between 2 and %d participants including yourself", validation.CONVERSATION_MAX_PARTICIPANTS), nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_participant_count").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Int("participant_count", len(others)+1).
+				Msg("Validation error: invalid number of participants.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		if request.Name != nil && !validation.ValidateConversationName(*request.Name) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid conversation name", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_name").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("name", *request.Name).
+				Msg("Validation error: invalid conversation name.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		if request.IconAssetID != nil {
+			valid, err := isOwnImage(db, *request.IconAssetID, userID)
+			if err != nil || !valid {
+				if err != nil {
+					apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching icon", nil)
+				} else {
+					apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The icon must be a PNG, JPEG, GIF or WebP image uploaded by you", nil)
+				}
+				log.Warn().
+					Str("component", COMPONENT).
+					Str("method_name", METHOD_NAME).
+					Str("event", "validation_failed_invalid_icon").
+					Str("api_error_code", apiResponse.Error.Code).
+					Str("api_error_message", apiResponse.Error.Message).
+					Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+					Err(err).
+					Msg("Validation error: invalid conversation icon.")
+				models.SendApiResponse(w, apiResponse)
+				return
+			}
+		}
+
+	default:
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid conversation type", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_type").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("type", string(request.Type)).
+			Msg("Validation error: invalid conversation type.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Every contacted user must exist and accept direct messages from the caller.
+	var recipients []models.User
+	if err := db.Select("id", "dm_privacy").Where("id IN ?", others).Find(&recipients).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching participants", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_participants").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching participants.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	if len(recipients) != len(others) {
+		apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("User not found", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "participant_not_found").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("One or more participants do not exist.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	for i := range recipients {
+		if err := permissions.CanDirectMessage(db, userID, &recipients[i]); err != nil {
+			apiResponse.Error = directMessageError(err)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "direct_message_not_allowed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("recipient_id", recipients[i].ID.String()).
+				Err(err).
+				Msg("Recipient does not accept direct messages from the user.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+	// --- END VALIDATION SECTION ---
+
+	newConversation := models.Conversation{Type: request.Type}
+	if request.Type == models.ConversationTypeDirect {
+		directKey := models.DirectConversationKey(userID, others[0])
+		newConversation.DirectKey = &directKey
+	} else {
+		newConversation.Name = request.Name
+		newConversation.IconAssetID = request.IconAssetID
+		newConversation.OwnerID = &userID
+	}
+
+	// Create the conversation and its participants in one transaction. A direct conversation
+	// that already exists is left untouched, so concurrent requests end up with the same one.
+	created := true
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "direct_key"}}, DoNothing: true}).
+			Omit("Participants", "Messages").
+			Create(&newConversation)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			created = false
+			return tx.Select("id").First(&newConversation, "direct_key = ?", *newConversation.DirectKey).Error
+		}
+		for _, participantID := range append([]uuid.UUID{userID}, others...) {
+			participant := models.ConversationParticipant{ConversationID: newConversation.ID, UserID: participantID}
+			if err := tx.Create(&participant).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating conversation due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_creating_conversation").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error creating conversation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Re-fetch the conversation with its participants for the response.
+	conversation, err := fetchConversation(db, newConversation.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Conversation not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully created conversation but failed to re-fetch", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_conversation").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching conversation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if created {
+		apiResponse.Message = "Conversation created successfully."
+	} else {
+		apiResponse.StatusCode = http.StatusOK
+		apiResponse.Message = "Conversation already exists."
+	}
+	apiResponse.Data = &models.ResponseData[models.ConversationPayload]{
+		Items: []models.ConversationPayload{models.NewConversationPayload(conversation)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "conversation_opened").
+		Str("conversation_id", conversation.ID.String()).
+		Str("type", string(conversation.Type)).
+		Bool("created", created).
+		Msg("Successfully opened conversation.")
+
+	// The participants follow the new conversation on their open gateway connections.
+	if created {
+		participantIDs := make([]uuid.UUID, 0, len(conversation.Participants))
+		for _, participant := range conversation.Participants {
+			participantIDs = append(participantIDs, participant.UserID)
+		}
+		gateway.Publish(gateway.Event{
+			Type:    gateway.EventConversationCreate,
+			Topics:  participantIDs,
+			Data:    apiResponse.Data.Items[0],
+			Refresh: participantIDs,
+		})
+	}
+
+	models.SendApiResponse(w, apiResponse)
+}
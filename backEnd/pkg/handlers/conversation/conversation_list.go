@@ -0,0 +1,140 @@
+package conversation
+
+import (
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/readstate"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ConversationListHandler handles HTTP GET requests for listing the conversations of the caller.
+// Conversations are sorted by their last activity, the most recent first, and carry the read
+// state of the caller with their unread message and mention counts.
+func ConversationListHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "conversation_handler"
+		METHOD_NAME    string = "ConversationListHandler"
+		CONTEXT        string = "api/user/me/conversations"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ConversationPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing conversation list request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ConversationListHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for conversation list.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the conversations the caller takes part in, most recently active first.
+	var conversations []models.Conversation
+	result := preloadParticipants(db).
+		Where("id IN (?)", db.Model(&models.ConversationParticipant{}).Select("conversation_id").Where("user_id = ?", userID)).
+		Order("last_message_at DESC, id DESC").
+		Find(&conversations)
+	if result.Error != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching conversations", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_conversations").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Str("user_id", userID.String()).
+			Err(result.Error).
+			Msg("Database error fetching conversations.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Count the unread messages of every conversation in one query.
+	conversationIDs := make([]uuid.UUID, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+	}
+	states, err := readstate.ForConversations(db, userID, conversationIDs)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error counting unread messages", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_counting_unread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Database error counting unread messages.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	payloads := make([]models.ConversationPayload, 0, len(conversations))
+	for i := range conversations {
+		payload := models.NewConversationPayload(&conversations[i])
+		state := states[conversations[i].ID]
+		state.ChannelID = conversations[i].ID
+		payload.ReadState = &state
+		payloads = append(payloads, payload)
+	}
+
+	apiResponse.Message = "Conversations retrieved successfully."
+	apiResponse.Data = &models.ResponseData[models.ConversationPayload]{
+		Pagination: &models.Pagination{TotalItems: len(payloads)},
+		Items:      payloads,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "conversations_retrieved").
+		Str("user_id", userID.String()).
+		Int("count", len(payloads)).
+		Msg("Successfully retrieved conversations.")
+
+	models.SendApiResponse(w, apiResponse)
+}
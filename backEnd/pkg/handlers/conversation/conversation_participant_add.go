@@ -0,0 +1,285 @@
+package conversation
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errConversationFull is returned inside the participant transaction when the group is full.
+var errConversationFull = errors.New("the conversation has reached the maximum number of participants")
+
+// ConversationParticipantAddHandler handles HTTP PUT requests for adding a user to a group conversation.
+// It expects the conversation and user IDs in the URL path. Any participant can add users,
+// as long as the group is not full and the added user accepts direct messages from the caller.
+// Adding a user that already takes part in the conversation has no effect.
+func ConversationParticipantAddHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "conversation_handler"
+		METHOD_NAME    string = "ConversationParticipantAddHandler"
+		CONTEXT        string = "api/conversations/{id}/participants/{userId}"
+		METHOD         string = "PUT"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ConversationPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing participant add request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ConversationParticipantAddHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for adding a participant.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the conversation and user IDs from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":     vars["id"],
+		"userId": vars["userId"],
+	}
+	conversationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid conversation ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid conversation ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	participantID, err := uuid.Parse(vars["userId"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid user ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_user_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("userId", vars["userId"]).
+			Err(err).
+			Msg("Invalid user ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the conversation and check that the caller takes part in it.
+	existingConversation, _, err := permissions.ForConversation(db, conversationID, userID)
+	if err != nil {
+		apiResponse.Error = conversationAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "conversation_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve conversation access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if existingConversation.Type != models.ConversationTypeGroup {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Participants can only be added to group conversations", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_not_group").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Msg("Validation error: conversation is not a group.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The added user must exist and accept direct messages from the caller.
+	var participant models.User
+	if err := db.Select("id", "dm_privacy").First(&participant, "id = ?", participantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("User not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching user", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "participant_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("participant_id", participantID.String()).
+			Err(err).
+			Msg("Could not fetch user to add.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	if participantID != userID {
+		if err := permissions.CanDirectMessage(db, userID, &participant); err != nil {
+			apiResponse.Error = directMessageError(err)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "direct_message_not_allowed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("participant_id", participantID.String()).
+				Err(err).
+				Msg("User does not accept direct messages from the caller.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Lock the conversation so concurrent additions cannot exceed the participant limit.
+	added := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked models.Conversation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", conversationID).Error; err != nil {
+			return err
+		}
+		count, err := countParticipants(tx, conversationID)
+		if err != nil {
+			return err
+		}
+		newParticipant := models.ConversationParticipant{ConversationID: conversationID, UserID: participantID}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newParticipant)
+		if result.Error != nil {
+			return result.Error
+		}
+		added = result.RowsAffected > 0
+		if added && !validation.ValidateParticipantCount(int(count)+1) {
+			return errConversationFull
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errConversationFull) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The conversation has reached the maximum number of participants", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error adding participant due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "participant_add_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Could not add participant.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Re-fetch the conversation with its participants for the response.
+	updatedConversation, err := fetchConversation(db, conversationID)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully added participant but failed to re-fetch", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_conversation").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching conversation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Participant added successfully."
+	apiResponse.Data = &models.ResponseData[models.ConversationPayload]{
+		Items: []models.ConversationPayload{models.NewConversationPayload(updatedConversation)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "participant_added").
+		Str("conversation_id", conversationID.String()).
+		Str("participant_id", participantID.String()).
+		Msg("Successfully added participant.")
+
+	// The other participants learn about the new one, who gets the conversation and follows it
+	// on their open gateway connections.
+	if added {
+		data := gateway.ParticipantData{ConversationID: conversationID, UserID: participantID}
+		for _, participant := range updatedConversation.Participants {
+			if participant.UserID == participantID {
+				data.User = models.NewPublicUser(&participant.User)
+			}
+		}
+		gateway.Publish(gateway.Event{Type: gateway.EventParticipantAdd, Topics: []uuid.UUID{conversationID}, Data: data})
+		gateway.Publish(gateway.Event{
+			Type:    gateway.EventConversationCreate,
+			Topics:  []uuid.UUID{participantID},
+			Data:    apiResponse.Data.Items[0],
+			Refresh: []uuid.UUID{participantID},
+		})
+	}
+
+	models.SendApiResponse(w, apiResponse)
+}
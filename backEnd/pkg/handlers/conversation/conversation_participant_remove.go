@@ -0,0 +1,267 @@
+package conversation
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errNotParticipant is returned inside the removal transaction when the user is not a participant.
+var errNotParticipant = errors.New("the user is not a participant of the conversation")
+
+// ConversationParticipantRemoveHandler handles HTTP DELETE requests for removing a user from a
+// group conversation. It expects the conversation and user IDs in the URL path.
+// Removing yourself leaves the conversation, removing anyone else requires being the owner.
+// When the owner leaves, ownership passes to the longest-standing participant, and the
+// conversation is deleted together with its messages once the last participant has left.
+// Direct conversations cannot be left.
+func ConversationParticipantRemoveHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "conversation_handler"
+		METHOD_NAME    string = "ConversationParticipantRemoveHandler"
+		CONTEXT        string = "api/conversations/{id}/participants/{userId}"
+		METHOD         string = "DELETE"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ConversationPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing participant removal request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ConversationParticipantRemoveHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for removing a participant.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the conversation and user IDs from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":     vars["id"],
+		"userId": vars["userId"],
+	}
+	conversationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid conversation ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid conversation ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	participantID, err := uuid.Parse(vars["userId"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid user ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_user_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("userId", vars["userId"]).
+			Err(err).
+			Msg("Invalid user ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	leaving := participantID == userID
+
+	// Resolve the conversation and check that the caller takes part in it.
+	existingConversation, _, err := permissions.ForConversation(db, conversationID, userID)
+	if err != nil {
+		apiResponse.Error = conversationAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "conversation_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve conversation access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if existingConversation.Type != models.ConversationTypeGroup {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Participants can only be removed from group conversations", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_not_group").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Msg("Validation error: conversation is not a group.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	isOwner := existingConversation.OwnerID != nil && *existingConversation.OwnerID == userID
+	if !leaving && !isOwner {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Only the owner can remove other participants", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to remove other participants.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Remove the participant, hand over ownership and delete the conversation when it becomes
+	// empty in one transaction. The conversation row is locked to serialise concurrent changes.
+	deletedConversation := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked models.Conversation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", conversationID).Error; err != nil {
+			return err
+		}
+		result := tx.Where("conversation_id = ? AND user_id = ?", conversationID, participantID).Delete(&models.ConversationParticipant{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNotParticipant
+		}
+		// Read states reference the conversation without a foreign key.
+		if err := tx.Where("channel_id = ? AND user_id = ?", conversationID, participantID).Delete(&models.ReadState{}).Error; err != nil {
+			return err
+		}
+
+		var successor models.ConversationParticipant
+		err := tx.Where("conversation_id = ?", conversationID).Order("joined_at ASC, user_id ASC").First(&successor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deletedConversation = true
+			return tx.Delete(&locked).Error
+		}
+		if err != nil {
+			return err
+		}
+		if locked.OwnerID != nil && *locked.OwnerID == participantID {
+			return tx.Model(&locked).Update("owner_id", successor.UserID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errNotParticipant) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("The user is not a participant of this conversation", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error removing participant due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "participant_remove_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("participant_id", participantID.String()).
+			Err(err).
+			Msg("Could not remove participant.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	deleted := true
+	apiResponse.Data = &models.ResponseData[models.ConversationPayload]{
+		Items: []models.ConversationPayload{},
+	}
+	switch {
+	case deletedConversation:
+		apiResponse.Message = "Left the conversation, the empty conversation was deleted."
+		apiResponse.Data.Deleted = &deleted
+	case leaving:
+		apiResponse.Message = "Left the conversation successfully."
+	default:
+		apiResponse.Message = "Participant removed successfully."
+		// The remaining participants get the updated conversation back.
+		if updatedConversation, err := fetchConversation(db, conversationID); err == nil {
+			apiResponse.Data.Items = append(apiResponse.Data.Items, models.NewConversationPayload(updatedConversation))
+		}
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "participant_removed").
+		Str("conversation_id", conversationID.String()).
+		Str("participant_id", participantID.String()).
+		Bool("left", leaving).
+		Bool("conversation_deleted", deletedConversation).
+		Msg("Successfully removed participant.")
+
+	// The removed user is told too, then stops following the conversation.
+	gateway.Publish(gateway.Event{
+		Type:    gateway.EventParticipantRemove,
+		Topics:  []uuid.UUID{conversationID, participantID},
+		Data:    gateway.ParticipantData{ConversationID: conversationID, UserID: participantID},
+		Refresh: []uuid.UUID{participantID},
+	})
+
+	models.SendApiResponse(w, apiResponse)
+}
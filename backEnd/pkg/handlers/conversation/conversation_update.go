@@ -0,0 +1,288 @@
+package conversation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// conversationUpdateRequest is the expected JSON body of a group conversation update request.
+// Omitted fields are left unchanged, null clears the name or the icon.
+type conversationUpdateRequest struct {
+	Name        models.Nullable[string]    `json:"name"`
+	IconAssetID models.Nullable[uuid.UUID] `json:"icon_asset_id"`
+	OwnerID     *uuid.UUID                 `json:"owner_id"`
+}
+
+// ConversationUpdateHandler handles HTTP PATCH requests for updating a group conversation.
+// It expects the conversation ID in the URL path and a JSON body with the new name, icon
+// or owner. Only the owner can update the group, and ownership can only be handed to
+// another participant.
+func ConversationUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "conversation_handler"
+		METHOD_NAME    string = "ConversationUpdateHandler"
+		CONTEXT        string = "api/conversations/{id}"
+		METHOD         string = "PATCH"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ConversationPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing conversation update request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ConversationUpdateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for conversation update.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the conversation ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	conversationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid conversation ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid conversation ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the conversation and check that the caller owns it.
+	existingConversation, _, err := permissions.ForConversation(db, conversationID, userID)
+	if err != nil {
+		apiResponse.Error = conversationAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "conversation_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve conversation access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if existingConversation.Type != models.ConversationTypeGroup || existingConversation.OwnerID == nil || *existingConversation.OwnerID != userID {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Only the owner of a group conversation can update it", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("conversation_id", conversationID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not the owner of the group conversation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request conversationUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data for update", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Prepare a map of fields to update for GORM.
+	updateParams := make(map[string]interface{})
+	if request.Name.Set {
+		updateParams["name"] = request.Name.Value
+	}
+	if request.IconAssetID.Set {
+		updateParams["icon_asset_id"] = request.IconAssetID.Value
+	}
+	if request.OwnerID != nil {
+		updateParams["owner_id"] = *request.OwnerID
+	}
+	for k, v := range updateParams {
+		apiResponse.Params[k] = v
+	}
+
+	// --- VALIDATION SECTION ---
+	if request.Name.Value != nil && !validation.ValidateConversationName(*request.Name.Value) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid conversation name", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_name").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("name", *request.Name.Value).
+			Msg("Validation error: invalid conversation name.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if request.IconAssetID.Value != nil {
+		valid, err := isOwnImage(db, *request.IconAssetID.Value, userID)
+		if err != nil || !valid {
+			if err != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching icon", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The icon must be a PNG, JPEG, GIF or WebP image uploaded by you", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_icon").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Err(err).
+				Msg("Validation error: invalid conversation icon.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	if request.OwnerID != nil {
+		var participantCount int64
+		result := db.Model(&models.ConversationParticipant{}).
+			Where("conversation_id = ? AND user_id = ?", conversationID, *request.OwnerID).
+			Count(&participantCount)
+		if result.Error != nil || participantCount == 0 {
+			if result.Error != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching new owner", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The new owner must be a participant of the conversation", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_owner").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("owner_id", request.OwnerID.String()).
+				Err(result.Error).
+				Msg("Validation error: invalid new owner.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+	// --- END VALIDATION SECTION ---
+
+	if len(updateParams) > 0 {
+		if err := db.Model(existingConversation).Updates(updateParams).Error; err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error updating conversation due to a database issue", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "database_error_updating_conversation").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Err(err).
+				Msg("Database error updating conversation.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Re-fetch the conversation with its participants for the response.
+	updatedConversation, err := fetchConversation(db, conversationID)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully updated conversation but failed to re-fetch", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_conversation").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching conversation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Conversation updated successfully."
+	apiResponse.Data = &models.ResponseData[models.ConversationPayload]{
+		Items: []models.ConversationPayload{models.NewConversationPayload(updatedConversation)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "conversation_updated_success").
+		Str("conversation_id", conversationID.String()).
+		Msg("Successfully updated conversation.")
+
+	gateway.Publish(gateway.Event{Type: gateway.EventConversationUpdate, Topics: []uuid.UUID{conversationID}, Data: apiResponse.Data.Items[0]})
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,54 @@
+package emoji
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// errEmojiLimitReached is returned inside the creation transaction when the server is full.
+var errEmojiLimitReached = errors.New("the server has reached the maximum number of emoji of this kind")
+
+// serverAccessError maps the errors returned by permissions.ForServer to the matching api error.
+func serverAccessError(err error) *models.CustomError {
+	switch {
+	case errors.Is(err, permissions.ErrServerNotFound):
+		return apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Server not found", nil)
+	case errors.Is(err, permissions.ErrNotMember):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a member of this server", nil)
+	}
+	return apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving server permissions", nil)
+}
+
+// isUniqueViolation reports whether the error is a PostgreSQL unique violation (23505),
+// which is raised when the server already has an emoji with the same name.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isAnimated reports whether an emoji image is animated, counting the frames of the stored file.
+// Assets created before the storage was introduced have no file, their GIF images count as animated.
+func isAnimated(ctx context.Context, store storage.Storage, asset *models.MediaAsset) (bool, error) {
+	if asset.StorageKey == "" || store == nil {
+		return asset.ContentType == "image/gif", nil
+	}
+	file, err := store.Open(ctx, asset.StorageKey)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, validation.STICKER_MAX_FILE_SIZE+1))
+	if err != nil {
+		return false, err
+	}
+	return imaging.Animated(asset.ContentType, data)
+}
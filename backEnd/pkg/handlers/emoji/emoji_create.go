@@ -0,0 +1,312 @@
+package emoji
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// emojiCreateRequest is the expected JSON body of an emoji creation request.
+// The kind defaults to emoji.
+type emojiCreateRequest struct {
+	Name         string            `json:"name"`
+	MediaAssetID uuid.UUID         `json:"media_asset_id"`
+	Kind         *models.EmojiKind `json:"kind"`
+}
+
+// EmojiCreateHandler handles HTTP POST requests for adding a custom emoji or sticker to a server.
+// It expects the server ID in the URL path and a JSON body with the name, the image and the kind.
+// The caller must have the manage emoji permission in the server. The image must be an image
+// uploaded by the caller within the size limit of the kind, and images with several frames are animated.
+// Servers can have a limited number of emoji and stickers.
+func EmojiCreateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "emoji_handler"
+		METHOD_NAME    string = "EmojiCreateHandler"
+		CONTEXT        string = "api/servers/{id}/emoji"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.ServerEmoji]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing emoji creation request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for EmojiCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for emoji creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the server ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	serverID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid server ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid server ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the permissions of the caller in the server.
+	perms, err := permissions.ForServer(db, serverID, userID)
+	if err != nil {
+		apiResponse.Error = serverAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "server_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve server permissions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !perms.Has(models.PermissionManageEmoji) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage emoji permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to manage emoji.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request emojiCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data for emoji", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	kind := models.EmojiKindEmoji
+	if request.Kind != nil {
+		kind = *request.Kind
+	}
+	apiResponse.Params["name"] = request.Name
+	apiResponse.Params["kind"] = kind
+
+	// --- VALIDATION SECTION ---
+	if !validation.ValidateEmojiName(request.Name) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Emoji names must be 2-32 letters, digits or underscores", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_name").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("name", request.Name).
+			Msg("Validation error: invalid emoji name.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !validation.ValidateEmojiKind(kind) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid emoji kind", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_kind").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("kind", string(kind)).
+			Msg("Validation error: invalid emoji kind.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	var asset models.MediaAsset
+	err = db.First(&asset, "id = ? AND uploaded_by_user_id = ? AND mime_type = ?", request.MediaAssetID, userID, models.AssetTypeImage).Error
+	if err != nil || !validation.ValidateEmojiFileSize(kind, asset.FileSize) {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The emoji must be an image uploaded by you", nil)
+		case err != nil:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching emoji image", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The emoji image exceeds the size limit", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_image").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("media_asset_id", request.MediaAssetID.String()).
+			Int("file_size", asset.FileSize).
+			Err(err).
+			Msg("Validation error: invalid emoji image.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	animated, err := isAnimated(r.Context(), storage.DefaultStorage, &asset)
+	if err != nil {
+		if errors.Is(err, imaging.ErrInvalidImage) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The emoji image is malformed", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error reading the emoji image", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "emoji_image_read_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("media_asset_id", asset.ID.String()).
+			Err(err).
+			Msg("Could not read the emoji image.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Lock the server so concurrent uploads cannot exceed the emoji limit.
+	newEmoji := models.ServerEmoji{
+		ServerID:     serverID,
+		Name:         request.Name,
+		Kind:         kind,
+		Animated:     animated,
+		MediaAssetID: asset.ID,
+		UploaderID:   userID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked models.Server
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", serverID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.ServerEmoji{}).Where("server_id = ? AND kind = ?", serverID, kind).Count(&count).Error; err != nil {
+			return err
+		}
+		if !validation.ValidateServerEmojiCount(kind, int(count)+1) {
+			return errEmojiLimitReached
+		}
+		return tx.Create(&newEmoji).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errEmojiLimitReached):
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The server has reached the maximum number of emoji of this kind", nil)
+		case isUniqueViolation(err):
+			apiResponse.Error = apierrors.ERROR_CODE_UNIQUE_KEY_VIOLATION.ApiErrorResponse("The server already has an emoji with this name", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating emoji due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "emoji_create_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("server_id", serverID.String()).
+			Err(err).
+			Msg("Could not create emoji.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Emoji created successfully."
+	apiResponse.Data = &models.ResponseData[models.ServerEmoji]{
+		Items: []models.ServerEmoji{newEmoji},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "emoji_created").
+		Str("emoji_id", newEmoji.ID.String()).
+		Str("server_id", serverID.String()).
+		Str("uploader_id", userID.String()).
+		Msg("Successfully created emoji.")
+
+	models.SendApiResponse(w, apiResponse)
+}
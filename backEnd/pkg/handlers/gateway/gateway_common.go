@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	jwt_token "github.com/413ksz/BlueFox/backEnd/pkg/token"
+	"github.com/google/uuid"
+)
+
+// authenticateToken verifies the JWT of a request from the Authorization header or, as browsers
+// cannot set headers on WebSocket and EventSource requests, from the token query parameter.
+// It returns the ID of the user, or the api error to answer with and the verification error.
+func authenticateToken(r *http.Request) (uuid.UUID, *models.CustomError, error) {
+	tokenString := middleware.ExtractBearerToken(r.Header.Get("Authorization"))
+	if tokenString == "" {
+		tokenString = r.URL.Query().Get("token")
+	}
+	if tokenString == "" {
+		return uuid.Nil, apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Missing bearer token", nil), nil
+	}
+
+	claims, err := jwt_token.VerifyJWTToken(tokenString)
+	var userID uuid.UUID
+	if err == nil {
+		userID, err = uuid.Parse(claims.Id)
+	}
+	if err != nil {
+		return uuid.Nil, apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Invalid or expired token", nil), err
+	}
+	return userID, nil, nil
+}
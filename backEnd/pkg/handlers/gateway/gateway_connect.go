@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/rs/zerolog/log"
+)
+
+// GatewayConnectHandler handles HTTP GET requests opening a WebSocket connection to the gateway.
+// The JWT is read from the Authorization header or, for browsers, from the token query parameter,
+// and verified before the connection is upgraded. The connection then receives the events of the
+// servers and conversations of the user until it closes, see the gateway package for the protocol.
+func GatewayConnectHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "gateway_handler"
+		METHOD_NAME    string = "GatewayConnectHandler"
+		CONTEXT        string = "api/gateway"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusSwitchingProtocols
+	)
+
+	apiResponse := &models.ApiResponse[any]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing gateway connection request.")
+
+	hub := gateway.DefaultHub
+	if hub == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Gateway not ready for GatewayConnectHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "gateway_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Gateway hub not initialized.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	userID, apiError, err := authenticateToken(r)
+	if apiError != nil {
+		apiResponse.Error = apiError
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_token").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Gateway connection rejected: missing or invalid bearer token.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The upgrader answers failed upgrades itself, such as plain HTTP requests or foreign origins.
+	ws, err := gateway.Upgrade(w, r)
+	if err != nil {
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upgrade_failed").
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not upgrade the gateway connection.")
+		return
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "gateway_connected").
+		Str("user_id", userID.String()).
+		Msg("Gateway connection opened.")
+
+	hub.Serve(ws, userID)
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "gateway_disconnected").
+		Str("user_id", userID.String()).
+		Msg("Gateway connection closed.")
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// GatewayPollHandler handles HTTP GET requests long polling the gateway frames, for clients that
+// can use neither WebSocket connections nor Server-Sent Events. Without the session_id query
+// parameter a new session is started and its READY frame returned right away. With session_id and
+// seq, the sequence number of the last dispatch received, the frames after seq are returned, waiting
+// up to gateway.POLL_TIMEOUT for the first one. A session that cannot be resumed is answered with
+// invalid_session followed by the READY frame of a new session.
+func GatewayPollHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "gateway_handler"
+		METHOD_NAME    string = "GatewayPollHandler"
+		CONTEXT        string = "api/events/poll"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[gateway.PollData]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing event poll request.")
+
+	hub := gateway.DefaultHub
+	if hub == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Gateway not ready for GatewayPollHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "gateway_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Gateway hub not initialized.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	query := r.URL.Query()
+	apiResponse.Params = map[string]interface{}{
+		"session_id": query.Get("session_id"),
+		"seq":        query.Get("seq"),
+	}
+
+	// --- VALIDATION SECTION ---
+	sessionID := uuid.Nil
+	var lastSeq uint64
+	if query.Get("session_id") != "" {
+		var err error
+		sessionID, err = uuid.Parse(query.Get("session_id"))
+		if err == nil {
+			lastSeq, err = strconv.ParseUint(query.Get("seq"), 10, 64)
+		}
+		if err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("session_id must be a UUID and seq a sequence number", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_session").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Err(err).
+				Msg("Validation failed: invalid session_id or seq.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+	// --- VALIDATION SECTION ---
+
+	stream, err := hub.OpenStream(userID, sessionID, lastSeq, false)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error loading subscriptions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "stream_open_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Could not open the event stream.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	poll := gateway.PollData{SessionID: stream.SessionID(), Frames: []json.RawMessage{}}
+	if poll.SessionID == sessionID {
+		poll.Seq = lastSeq
+	}
+	receive := func(frame gateway.StreamFrame) {
+		poll.Frames = append(poll.Frames, frame.Frame)
+		if frame.Seq > 0 {
+			poll.Seq = frame.Seq
+		}
+	}
+
+	timeout := time.NewTimer(gateway.POLL_TIMEOUT)
+	defer timeout.Stop()
+	select {
+	case frame := <-stream.Frames():
+		receive(frame)
+	case <-stream.Done():
+	case <-timeout.C:
+	case <-r.Context().Done():
+	}
+	// Frames queued together with the first one are returned with it.
+	for drained := false; !drained; {
+		select {
+		case frame := <-stream.Frames():
+			receive(frame)
+		default:
+			drained = true
+		}
+	}
+	// Frames dispatched from here on stay buffered in the session for the next poll.
+	stream.Close()
+
+	apiResponse.Message = "Events retrieved successfully."
+	apiResponse.Data = &models.ResponseData[gateway.PollData]{
+		Items: []gateway.PollData{poll},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "events_polled").
+		Str("user_id", userID.String()).
+		Str("session_id", poll.SessionID.String()).
+		Int("count", len(poll.Frames)).
+		Msg("Successfully polled events.")
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// GatewayStreamHandler handles HTTP GET requests streaming the gateway frames as Server-Sent Events,
+// for clients that cannot open WebSocket connections. Like the WebSocket gateway it reads the JWT
+// from the Authorization header or the token query parameter. Every frame is sent as the data of
+// an event, dispatches carry "<session_id>:<seq>" as their ID. The session given by the
+// Last-Event-ID header, or the last_event_id query parameter for the first request, is resumed,
+// otherwise a new one is started. The response ends after gateway.STREAM_TIMEOUT and EventSource
+// reconnects with the ID of the last event it received.
+func GatewayStreamHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "gateway_handler"
+		METHOD_NAME    string = "GatewayStreamHandler"
+		CONTEXT        string = "api/events/stream"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[any]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing event stream request.")
+
+	hub := gateway.DefaultHub
+	if hub == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Gateway not ready for GatewayStreamHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "gateway_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Gateway hub not initialized.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	userID, apiError, err := authenticateToken(r)
+	if apiError != nil {
+		apiResponse.Error = apiError
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_token").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Event stream rejected: missing or invalid bearer token.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// --- VALIDATION SECTION ---
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	apiResponse.Params = map[string]interface{}{
+		"last_event_id": lastEventID,
+	}
+
+	sessionID := uuid.Nil
+	var lastSeq uint64
+	if lastEventID != "" {
+		sessionID, lastSeq, err = gateway.ParseEventID(lastEventID)
+		if err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid Last-Event-ID", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_last_event_id").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Err(err).
+				Msg("Validation failed: invalid Last-Event-ID.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+	// --- VALIDATION SECTION ---
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Streaming is not supported", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "streaming_unsupported").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("The response writer cannot flush events.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	stream, err := hub.OpenStream(userID, sessionID, lastSeq, true)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error loading subscriptions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "stream_open_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Could not open the event stream.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Keeps reverse proxies from buffering the events.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(STATUS_DEFAULT)
+	fmt.Fprintf(w, "retry: %d\n\n", gateway.STREAM_RETRY.Milliseconds())
+	flusher.Flush()
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "stream_opened").
+		Str("user_id", userID.String()).
+		Str("session_id", stream.SessionID().String()).
+		Msg("Event stream opened.")
+
+	timeout := time.NewTimer(gateway.STREAM_TIMEOUT)
+	defer timeout.Stop()
+	// Comments keep idle connections from being closed by proxies.
+	keepAlive := time.NewTicker(gateway.HEARTBEAT_INTERVAL)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-stream.Done():
+			return
+		case <-timeout.C:
+			log.Info().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "stream_timeout").
+				Str("user_id", userID.String()).
+				Str("session_id", stream.SessionID().String()).
+				Msg("Event stream ended, the client reconnects.")
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case frame := <-stream.Frames():
+			if frame.Seq > 0 {
+				fmt.Fprintf(w, "id: %s\n", gateway.FormatEventID(stream.SessionID(), frame.Seq))
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", frame.Frame); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
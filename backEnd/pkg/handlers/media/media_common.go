@@ -0,0 +1,151 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/uploads"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errQuotaExceeded is returned inside the upload transactions when a file exceeds the quota of the user.
+	errQuotaExceeded = errors.New("the file exceeds the storage quota of the user")
+	// errChecksumMismatch is returned when a completed upload does not match its checksum.
+	errChecksumMismatch = errors.New("the received content does not match the checksum of the upload")
+	// errUploadNotFound is returned for uploads that do not exist, belong to another user or expired.
+	errUploadNotFound = errors.New("upload not found")
+	// errImageTooLarge is returned when a completed upload is an image larger than imaging.MAX_FILE_SIZE.
+	errImageTooLarge = errors.New("the image is too large to be processed")
+)
+
+// newMediaAsset creates the media asset of a stored file uploaded by a user, with the type sniffed
+// from its content. The ID is generated here as the URL path is derived from it. Images are pending
+// until the imaging pipeline has stripped their metadata.
+func newMediaAsset(filename string, contentType string, object storage.Object, userID uuid.UUID) models.MediaAsset {
+	asset := models.MediaAsset{
+		ID:               uuid.New(),
+		Filename:         filename,
+		FileSize:         int(object.Size),
+		MimeType:         mediatype.AssetType(contentType),
+		StorageKey:       object.Key,
+		ContentType:      contentType,
+		UploadedByUserID: &userID,
+		ProcessingStatus: models.ProcessingStatusReady,
+	}
+	if imaging.Supported(contentType) {
+		asset.ProcessingStatus = models.ProcessingStatusPending
+	}
+	asset.UrlPath = "/api/media/" + asset.ID.String()
+	return asset
+}
+
+// enqueueImage schedules the processing of a created media asset if it is a pending image. Without
+// a pipeline the asset stays pending until one recovers it.
+func enqueueImage(asset *models.MediaAsset) {
+	if asset.ProcessingStatus == models.ProcessingStatusPending && imaging.DefaultPipeline != nil {
+		imaging.DefaultPipeline.Enqueue(asset.ID)
+	}
+}
+
+// mediaTypeError maps the errors returned by mediatype.Check to the matching api error.
+// returns false for other errors.
+func mediaTypeError(err error, context mediatype.Context) (*models.CustomError, bool) {
+	switch {
+	case errors.Is(err, mediatype.ErrNotAllowed):
+		return apierrors.ERROR_CODE_UNSUPPORTED_MEDIA_TYPE.ApiErrorResponse("Files of this type cannot be uploaded as "+string(context), nil), true
+	case errors.Is(err, mediatype.ErrDeclaredMismatch), errors.Is(err, mediatype.ErrExtensionMismatch):
+		return apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil), true
+	}
+	return nil, false
+}
+
+// reserveQuota checks inside a transaction that a new file fits in the storage quota of a user.
+// The user is locked so concurrent uploads cannot exceed the quota together. The quota counts the
+// uploaded media assets and the full length of the uploads in progress.
+func reserveQuota(tx *gorm.DB, userID uuid.UUID, size int64) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	var assets, pending int64
+	err := tx.Model(&models.MediaAsset{}).
+		Select("COALESCE(SUM(file_size), 0)").
+		Where("uploaded_by_user_id = ?", userID).
+		Scan(&assets).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&models.Upload{}).
+		Select("COALESCE(SUM(length), 0)").
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Scan(&pending).Error
+	if err != nil {
+		return err
+	}
+	if !validation.ValidateMediaQuota(assets+pending, size) {
+		return errQuotaExceeded
+	}
+	return nil
+}
+
+// findUpload fetches an upload in progress of a user.
+func findUpload(db *gorm.DB, uploadID uuid.UUID, userID uuid.UUID) (*models.Upload, error) {
+	var upload models.Upload
+	err := db.First(&upload, "id = ? AND user_id = ? AND expires_at > ?", uploadID, userID, time.Now()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// setUploadHeaders sets the tus headers describing an upload at an offset.
+func setUploadHeaders(w http.ResponseWriter, upload *models.Upload, offset int64) {
+	header := w.Header()
+	header.Set("Tus-Resumable", uploads.TUS_VERSION)
+	header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	header.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	header.Set("Cache-Control", "no-store")
+}
+
+// pruneExpiredUploads removes the expired uploads of all users with their chunks. It runs when
+// uploads are created, so abandoned uploads do not pile up without a background job.
+func pruneExpiredUploads(db *gorm.DB, chunks *uploads.Chunks) {
+	var expired []models.Upload
+	err := db.Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&expired).Error
+	if err != nil {
+		log.Warn().
+			Str("component", "media_handler").
+			Str("event", "upload_prune_failed").
+			Err(err).
+			Msg("Could not remove expired uploads.")
+		return
+	}
+	for _, upload := range expired {
+		if err := chunks.Remove(upload.ID); err != nil {
+			log.Warn().
+				Str("component", "media_handler").
+				Str("event", "upload_chunks_remove_failed").
+				Str("upload_id", upload.ID.String()).
+				Err(err).
+				Msg("Could not remove the chunks of an expired upload.")
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/signedurl"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MediaDownloadHandler handles HTTP GET requests for downloading the file of a media asset.
+// It expects the media asset ID in the URL path. Storages that are downloaded from directly, like
+// S3, answer with a redirect to a temporary URL, other storages are served by the API with range
+// and conditional request support. Files never change under a key, so they are cached forever,
+// except message attachments, which are only served from signed URLs and cached privately until
+// the URL expires.
+// The size query parameter selects a thumbnail of an image, images are served once processed.
+// The route is public, as browsers load media in img and video tags without the token.
+func MediaDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "media_handler"
+		METHOD_NAME    string = "MediaDownloadHandler"
+		CONTEXT        string = "api/media/{id}"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MediaAssetPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance and the file storage.
+	db := database.DB
+	store := storage.DefaultStorage
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing media download request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MediaDownloadHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for media download.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Check if the file storage is initialized.
+	if store == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Storage not ready for MediaDownloadHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "storage_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Storage not initialized for media download.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the media asset ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	assetID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid media asset ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid media asset ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Assets created before the storage was introduced have no stored file.
+	var asset models.MediaAsset
+	err = db.First(&asset, "id = ?", assetID).Error
+	if err != nil || asset.StorageKey == "" {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Media asset not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching media asset", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "media_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("media_asset_id", assetID.String()).
+			Err(err).
+			Msg("Could not fetch media asset.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Attachments are private to the readers of their message, they are only served from the signed
+	// URLs of the message payloads and cached by the browser until the URL expires. Other media,
+	// such as avatars and server icons, is public and cached forever.
+	cacheControl := "public, max-age=31536000, immutable"
+	var attachmentCount int64
+	if err := db.Model(&models.MessageAttachment{}).Where("media_asset_id = ?", assetID).Count(&attachmentCount).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching media asset", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "attachment_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("media_asset_id", assetID.String()).
+			Err(err).
+			Msg("Could not check whether the media asset is an attachment.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	if attachmentCount > 0 {
+		expires, err := signedurl.Verify(asset.UrlPath, r.URL.Query(), time.Now())
+		if err != nil {
+			if errors.Is(err, signedurl.ErrExpired) {
+				apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("The media URL has expired", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Attachments are only served from signed URLs", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "media_signature_invalid").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("media_asset_id", assetID.String()).
+				Err(err).
+				Msg("Attachment requested without a valid signed URL.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		cacheControl = "private, max-age=" + strconv.Itoa(int(time.Until(expires).Seconds()))
+	}
+
+	// Images are only served once their metadata is stripped.
+	if asset.ProcessingStatus != models.ProcessingStatusReady {
+		if asset.ProcessingStatus == models.ProcessingStatusFailed {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("The image could not be processed", nil)
+		} else {
+			w.Header().Set("Retry-After", "1")
+			apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("The image is still being processed", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "media_not_processed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("media_asset_id", assetID.String()).
+			Str("processing_status", string(asset.ProcessingStatus)).
+			Msg("Media asset is not processed.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Serve a thumbnail for the size query parameter. Images smaller than a thumbnail size have no
+	// thumbnail of that size and are served as they are.
+	key, filename, contentType, fileSize := asset.StorageKey, asset.Filename, asset.ContentType, asset.FileSize
+	if query := r.URL.Query().Get("size"); query != "" {
+		apiResponse.Params["size"] = query
+		size, err := strconv.Atoi(query)
+		if err != nil || !slices.Contains(imaging.THUMBNAIL_SIZES, size) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The size must be one of the thumbnail sizes", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_size").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("size", query).
+				Msg("Validation error: invalid thumbnail size.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		var thumbnails []models.MediaThumbnail
+		if err := db.Where("media_asset_id = ? AND size = ?", assetID, size).Limit(1).Find(&thumbnails).Error; err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching thumbnail", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "thumbnail_fetch_failed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("media_asset_id", assetID.String()).
+				Err(err).
+				Msg("Could not fetch thumbnail.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		if len(thumbnails) > 0 {
+			thumbnail := thumbnails[0]
+			key, contentType, fileSize = thumbnail.StorageKey, thumbnail.ContentType, thumbnail.FileSize
+			filename = mediatype.WithExtension(strings.TrimSuffix(filename, filepath.Ext(filename)), contentType)
+		}
+	}
+
+	// Redirect to storages the file is downloaded from directly.
+	if redirector, ok := store.(storage.Redirector); ok {
+		location, err := redirector.URL(key, filename, contentType)
+		if err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error creating the download URL", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "media_url_failed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("media_asset_id", assetID.String()).
+				Err(err).
+				Msg("Could not create the download URL.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		// The URL expires, so the redirect must not be cached longer than it is valid.
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		http.Redirect(w, r, location, http.StatusFound)
+		log.Info().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "media_redirected").
+			Str("media_asset_id", assetID.String()).
+			Msg("Redirected media download to the storage.")
+		return
+	}
+
+	file, err := store.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Media file not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error opening the media file", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "media_open_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("media_asset_id", assetID.String()).
+			Str("storage_key", key).
+			Err(err).
+			Msg("Could not open media file.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	defer file.Close()
+
+	// The content type is the one stored at upload, browsers must not guess another one.
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", cacheControl)
+	header.Set("ETag", strconv.Quote(path.Base(key)))
+
+	// Seekable files are served with range and conditional requests.
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", asset.CreatedAt, seeker)
+	} else {
+		header.Set("Content-Length", strconv.Itoa(fileSize))
+		if _, err := io.Copy(w, file); err != nil {
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "media_copy_failed").
+				Str("media_asset_id", assetID.String()).
+				Err(err).
+				Msg("Media download was interrupted.")
+			return
+		}
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "media_served").
+		Str("media_asset_id", assetID.String()).
+		Msg("Successfully served media.")
+}
@@ -0,0 +1,263 @@
+package media
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MediaUploadHandler handles HTTP POST requests for uploading a media file.
+// The request body is the raw file, its filename is given by the filename query parameter, what it
+// is uploaded for by the context query parameter (attachment, avatar or server_icon) and its type
+// by the Content-Type header. The type is sniffed from the content and must be allowed in the
+// context, a contradicting declared type or extension is rejected. The file is streamed to the
+// storage without being held in memory, and files larger than validation.MEDIA_MAX_FILE_SIZE or
+// exceeding the quota of the user are rejected. Larger files are sent with resumable uploads.
+// Images are processed in the background, the asset is served once its processing status is ready.
+// The created media asset can be attached to messages or used as an emoji, an icon or a profile picture.
+func MediaUploadHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "media_handler"
+		METHOD_NAME    string = "MediaUploadHandler"
+		CONTEXT        string = "api/media"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.MediaAssetPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance and the file storage.
+	db := database.DB
+	store := storage.DefaultStorage
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing media upload request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MediaUploadHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for media upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Check if the file storage is initialized.
+	if store == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Storage not ready for MediaUploadHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "storage_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Storage not initialized for media upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The filename is sanitized and the declared type is only compared to the sniffed one.
+	filename := mediatype.SanitizeFilename(r.URL.Query().Get("filename"))
+	declared := mediatype.Normalize(r.Header.Get("Content-Type"))
+	context := mediatype.Context(r.URL.Query().Get("context"))
+	if context == "" {
+		context = mediatype.ContextAttachment
+	}
+	apiResponse.Params = map[string]interface{}{
+		"filename":     filename,
+		"content_type": declared,
+		"context":      context,
+	}
+
+	// --- VALIDATION SECTION ---
+	if !validation.ValidateMediaFilename(filename) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Filenames must be 1-255 characters without path separators", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_filename").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("filename", filename).
+			Msg("Validation error: invalid filename.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !mediatype.ValidContext(context) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The context must be attachment, avatar or server_icon", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_context").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("context", string(context)).
+			Msg("Validation error: invalid upload context.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if r.ContentLength > validation.MEDIA_MAX_FILE_SIZE {
+		apiResponse.Error = apierrors.ERROR_CODE_PAYLOAD_TOO_LARGE.ApiErrorResponse("Files can be at most 25 MiB", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_file_too_large").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int64("content_length", r.ContentLength).
+			Msg("Validation error: file too large.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Sniff the type from the first bytes, which stay buffered for the storage. Bodies without
+	// Content-Length are cut at the limit.
+	body := bufio.NewReaderSize(http.MaxBytesReader(w, r.Body, validation.MEDIA_MAX_FILE_SIZE), mediatype.SNIFF_LENGTH)
+	head, err := body.Peek(mediatype.SNIFF_LENGTH)
+	if err == io.EOF {
+		err = nil
+	}
+	sniffed := mediatype.Sniff(head)
+	if err == nil && len(head) > 0 {
+		err = mediatype.Check(context, filename, declared, sniffed)
+	}
+	if err != nil || len(head) == 0 {
+		if err == nil {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The file is empty", nil)
+		} else if typeErr, ok := mediaTypeError(err, context); ok {
+			apiResponse.Error = typeErr
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Error reading the file", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_media_type").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("declared", declared).
+			Str("sniffed", sniffed).
+			Err(err).
+			Msg("Validation error: invalid file type.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	filename = mediatype.WithExtension(filename, sniffed)
+	object, err := store.Put(r.Context(), body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apiResponse.Error = apierrors.ERROR_CODE_PAYLOAD_TOO_LARGE.ApiErrorResponse("Files can be at most 25 MiB", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error storing the file", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "media_store_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not store the uploaded file.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Create the media asset if the file fits in the quota of the user.
+	asset := newMediaAsset(filename, sniffed, object, userID)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := reserveQuota(tx, userID, object.Size); err != nil {
+			return err
+		}
+		return tx.Create(&asset).Error
+	})
+	if err != nil {
+		if errors.Is(err, errQuotaExceeded) {
+			apiResponse.Error = apierrors.ERROR_CODE_QUOTA_EXCEEDED.ApiErrorResponse("The file exceeds your storage quota", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating media asset due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "media_create_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("storage_key", object.Key).
+			Err(err).
+			Msg("Could not create media asset.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	enqueueImage(&asset)
+	apiResponse.Message = "Media uploaded successfully."
+	apiResponse.Data = &models.ResponseData[models.MediaAssetPayload]{
+		Items: []models.MediaAssetPayload{models.NewMediaAssetPayload(&asset)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "media_uploaded").
+		Str("media_asset_id", asset.ID.String()).
+		Str("storage_key", asset.StorageKey).
+		Int("file_size", asset.FileSize).
+		Str("user_id", userID.String()).
+		Msg("Successfully uploaded media.")
+
+	models.SendApiResponse(w, apiResponse)
+}
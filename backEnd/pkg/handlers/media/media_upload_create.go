@@ -0,0 +1,261 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/uploads"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MediaUploadCreateHandler handles HTTP POST requests for starting a resumable upload.
+// It expects the tus headers Upload-Length with the size of the file, Upload-Metadata with its
+// filename and optionally its filetype and context, and Upload-Checksum with the SHA-256 digest of
+// the whole file. The type is sniffed once the upload completes, as for MediaUploadHandler, and
+// images are limited to imaging.MAX_FILE_SIZE.
+// The full length is reserved from the storage quota of the user until the upload completes or
+// expires. The response carries the URL of the upload in the Location header, the content is then
+// sent to it in PATCH requests. Expired uploads of all users are removed here.
+func MediaUploadCreateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "media_handler"
+		METHOD_NAME    string = "MediaUploadCreateHandler"
+		CONTEXT        string = "api/media/uploads"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.UploadPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance and the chunk store.
+	db := database.DB
+	chunks := uploads.DefaultChunks
+	w.Header().Set("Tus-Resumable", uploads.TUS_VERSION)
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing upload creation request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MediaUploadCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for upload creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Check if the chunk store is initialized.
+	if chunks == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Uploads not ready for MediaUploadCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "chunks_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Chunk store not initialized for upload creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the tus headers.
+	length, lengthErr := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	metadata, metadataErr := uploads.ParseMetadata(r.Header.Get("Upload-Metadata"))
+	checksum, checksumErr := uploads.ParseChecksum(r.Header.Get("Upload-Checksum"))
+	if err := errors.Join(lengthErr, metadataErr, checksumErr); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid Upload-Length, Upload-Metadata or Upload-Checksum header", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_upload_headers").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Invalid tus headers.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// The filename is sanitized and the declared type is only compared to the sniffed one.
+	filename := mediatype.SanitizeFilename(metadata["filename"])
+	declared := mediatype.Normalize(metadata["filetype"])
+	context := mediatype.Context(metadata["context"])
+	if context == "" {
+		context = mediatype.ContextAttachment
+	}
+	apiResponse.Params = map[string]interface{}{
+		"filename":     filename,
+		"content_type": declared,
+		"context":      context,
+		"length":       length,
+	}
+
+	// --- VALIDATION SECTION ---
+	if !validation.ValidateMediaFilename(filename) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Filenames must be 1-255 characters without path separators", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_filename").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("filename", filename).
+			Msg("Validation error: invalid filename.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !mediatype.ValidContext(context) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The context must be attachment, avatar or server_icon", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_context").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("context", string(context)).
+			Msg("Validation error: invalid upload context.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The content is sniffed once complete, a declared type is checked before anything is sent.
+	if declared != mediatype.UNKNOWN {
+		if err := mediatype.Check(context, filename, declared, declared); err != nil {
+			apiResponse.Error, _ = mediaTypeError(err, context)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_media_type").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("declared", declared).
+				Err(err).
+				Msg("Validation error: invalid file type.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	if !validation.ValidateUploadLength(length) || (imaging.Supported(declared) && length > imaging.MAX_FILE_SIZE) {
+		if imaging.Supported(declared) && length > imaging.MAX_FILE_SIZE {
+			apiResponse.Error = apierrors.ERROR_CODE_PAYLOAD_TOO_LARGE.ApiErrorResponse("Images can be at most 25 MiB", nil)
+		} else if length > 0 {
+			apiResponse.Error = apierrors.ERROR_CODE_PAYLOAD_TOO_LARGE.ApiErrorResponse("Files can be at most 1 GiB", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The file is empty", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_length").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int64("length", length).
+			Msg("Validation error: invalid upload length.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	pruneExpiredUploads(db, chunks)
+
+	// Create the upload if the file fits in the quota of the user.
+	upload := models.Upload{
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: declared,
+		Context:     string(context),
+		Length:      length,
+		Checksum:    checksum,
+		ExpiresAt:   time.Now().Add(uploads.EXPIRY),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := reserveQuota(tx, userID, length); err != nil {
+			return err
+		}
+		return tx.Create(&upload).Error
+	})
+	if err != nil {
+		if errors.Is(err, errQuotaExceeded) {
+			apiResponse.Error = apierrors.ERROR_CODE_QUOTA_EXCEEDED.ApiErrorResponse("The file exceeds your storage quota", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating upload due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_create_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not create upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	w.Header().Set("Location", "/api/media/uploads/"+upload.ID.String())
+	setUploadHeaders(w, &upload, 0)
+	apiResponse.Message = "Upload created successfully."
+	apiResponse.Data = &models.ResponseData[models.UploadPayload]{
+		Items: []models.UploadPayload{models.NewUploadPayload(&upload, 0)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "upload_created").
+		Str("upload_id", upload.ID.String()).
+		Int64("length", upload.Length).
+		Str("user_id", userID.String()).
+		Msg("Successfully created upload.")
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,181 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/uploads"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// MediaUploadDeleteHandler handles HTTP DELETE requests for abandoning a resumable upload.
+// It expects the upload ID in the URL path. The received chunks are removed and the length of the
+// upload no longer counts against the storage quota of the user.
+func MediaUploadDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "media_handler"
+		METHOD_NAME    string = "MediaUploadDeleteHandler"
+		CONTEXT        string = "api/media/uploads/{id}"
+		METHOD         string = "DELETE"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.UploadPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance and the chunk store.
+	db := database.DB
+	chunks := uploads.DefaultChunks
+	w.Header().Set("Tus-Resumable", uploads.TUS_VERSION)
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing upload deletion request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MediaUploadDeleteHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for upload deletion.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Check if the chunk store is initialized.
+	if chunks == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Uploads not ready for MediaUploadDeleteHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "chunks_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Chunk store not initialized for upload deletion.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the upload ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	uploadID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid upload ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid upload ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Reserve the upload so no chunk is written while it is removed.
+	unlock, err := uploads.Lock(r.Context(), db, uploadID)
+	if err != nil {
+		if errors.Is(err, uploads.ErrLocked) {
+			apiResponse.Error = apierrors.ERROR_CODE_CONFLICT.ApiErrorResponse("The upload is being written by another request", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error locking upload", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_lock_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("upload_id", uploadID.String()).
+			Err(err).
+			Msg("Could not lock the upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	defer unlock()
+
+	upload, err := findUpload(db, uploadID, userID)
+	if err == nil {
+		err = db.Delete(upload).Error
+	}
+	if err == nil {
+		err = chunks.Remove(uploadID)
+	}
+	if err != nil {
+		if errors.Is(err, errUploadNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Upload not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error deleting upload", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_delete_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("upload_id", uploadID.String()).
+			Err(err).
+			Msg("Could not delete upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	deleted := true
+	apiResponse.Message = "Upload deleted successfully."
+	apiResponse.Data = &models.ResponseData[models.UploadPayload]{
+		Deleted: &deleted,
+		Items:   []models.UploadPayload{models.NewUploadPayload(upload, upload.Received)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "upload_deleted").
+		Str("upload_id", uploadID.String()).
+		Str("deleted_by", userID.String()).
+		Msg("Upload deleted successfully.")
+
+	models.SendApiResponse(w, apiResponse)
+}
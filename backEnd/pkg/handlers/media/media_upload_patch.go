@@ -0,0 +1,362 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/uploads"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MediaUploadPatchHandler handles HTTP PATCH requests for sending a chunk of a resumable upload.
+// It expects the upload ID in the URL path, the Upload-Offset header with the offset the chunk starts
+// at and the chunk as application/offset+octet-stream body. The offset must be the one reported by
+// the last response or the HEAD request, bytes of an interrupted request are kept. Every chunk extends
+// the expiry of the upload. When the last byte arrives the file is verified against the checksum
+// given at creation, its type is sniffed and checked like in MediaUploadHandler, and it is moved to
+// the storage. The response then carries the created media asset. A mismatching file is discarded
+// with its upload.
+func MediaUploadPatchHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "media_handler"
+		METHOD_NAME    string = "MediaUploadPatchHandler"
+		CONTEXT        string = "api/media/uploads/{id}"
+		METHOD         string = "PATCH"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.UploadPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance, the chunk store and the file storage.
+	db := database.DB
+	chunks := uploads.DefaultChunks
+	store := storage.DefaultStorage
+	w.Header().Set("Tus-Resumable", uploads.TUS_VERSION)
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing upload chunk request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MediaUploadPatchHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for upload chunk.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Check if the chunk store and the file storage are initialized.
+	if chunks == nil || store == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_SERVICE_UNAVAILABLE.ApiErrorResponse("Uploads not ready for MediaUploadPatchHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "storage_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Chunk store or storage not initialized for upload chunk.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the upload ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	uploadID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid upload ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid upload ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the tus headers of the chunk.
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 || mediatype.Normalize(r.Header.Get("Content-Type")) != uploads.CONTENT_TYPE {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Chunks need an Upload-Offset header and the application/offset+octet-stream content type", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_upload_headers").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("upload_offset", r.Header.Get("Upload-Offset")).
+			Str("content_type", r.Header.Get("Content-Type")).
+			Msg("Invalid tus headers.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	apiResponse.Params["offset"] = offset
+
+	// Reserve the upload so concurrent requests, on any instance, cannot interleave their chunks.
+	unlock, err := uploads.Lock(r.Context(), db, uploadID)
+	if err != nil {
+		if errors.Is(err, uploads.ErrLocked) {
+			apiResponse.Error = apierrors.ERROR_CODE_CONFLICT.ApiErrorResponse("The upload is being written by another request", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error locking upload", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_lock_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("upload_id", uploadID.String()).
+			Err(err).
+			Msg("Could not lock the upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	defer unlock()
+
+	upload, err := findUpload(db, uploadID, userID)
+	if err != nil {
+		if errors.Is(err, errUploadNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Upload not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching upload", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("upload_id", uploadID.String()).
+			Err(err).
+			Msg("Could not fetch upload.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Append the chunk, the bytes written before a failure count as received.
+	received, err := chunks.Append(uploadID, offset, r.Body, upload.Length-offset)
+	upload.Received = received
+	upload.ExpiresAt = time.Now().Add(uploads.EXPIRY)
+	if saveErr := db.Model(upload).Select("received", "expires_at").Updates(upload).Error; saveErr != nil {
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_progress_save_failed").
+			Str("upload_id", uploadID.String()).
+			Err(saveErr).
+			Msg("Could not save the progress of the upload.")
+	}
+	setUploadHeaders(w, upload, received)
+	if err != nil {
+		switch {
+		case errors.Is(err, uploads.ErrOffsetMismatch):
+			apiResponse.Error = apierrors.ERROR_CODE_CONFLICT.ApiErrorResponse("The offset does not match the received length of the upload", nil)
+		case errors.Is(err, uploads.ErrTooLarge):
+			apiResponse.Error = apierrors.ERROR_CODE_PAYLOAD_TOO_LARGE.ApiErrorResponse("The chunk exceeds the length of the upload", nil)
+		default:
+			apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error receiving the chunk", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_chunk_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("upload_id", uploadID.String()).
+			Int64("offset", offset).
+			Int64("received", received).
+			Err(err).
+			Msg("Could not append the chunk.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	payload := models.NewUploadPayload(upload, received)
+	apiResponse.Message = "Chunk received successfully."
+	if received == upload.Length {
+		asset, err := completeUpload(r.Context(), db, chunks, store, upload)
+		if err != nil {
+			typeErr, isTypeErr := mediaTypeError(err, mediatype.Context(upload.Context))
+			switch {
+			case errors.Is(err, errChecksumMismatch):
+				apiResponse.Error = apierrors.ERROR_CODE_CHECKSUM_MISMATCH.ApiErrorResponse("The file does not match the checksum, the upload was discarded", nil)
+			case isTypeErr:
+				apiResponse.Error = typeErr
+			case errors.Is(err, errImageTooLarge):
+				apiResponse.Error = apierrors.ERROR_CODE_PAYLOAD_TOO_LARGE.ApiErrorResponse("Images can be at most 25 MiB, the upload was discarded", nil)
+			case errors.Is(err, errUploadNotFound):
+				apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Upload not found", nil)
+			default:
+				apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Error completing the upload", nil)
+			}
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "upload_complete_failed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("upload_id", uploadID.String()).
+				Err(err).
+				Msg("Could not complete the upload.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		assetPayload := models.NewMediaAssetPayload(asset)
+		payload.MediaAsset = &assetPayload
+		apiResponse.Message = "Upload completed successfully."
+
+		log.Info().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "upload_completed").
+			Str("upload_id", uploadID.String()).
+			Str("media_asset_id", asset.ID.String()).
+			Str("storage_key", asset.StorageKey).
+			Msg("Successfully completed upload.")
+	}
+
+	apiResponse.Data = &models.ResponseData[models.UploadPayload]{
+		Items: []models.UploadPayload{payload},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "upload_chunk_received").
+		Str("upload_id", uploadID.String()).
+		Int64("offset", offset).
+		Int64("received", received).
+		Msg("Successfully received chunk.")
+
+	models.SendApiResponse(w, apiResponse)
+}
+
+// completeUpload verifies a fully received upload against its checksum and the type sniffed from its
+// content, and moves it to the storage as a media asset, which replaces the upload in the quota of
+// the user. Mismatching uploads are removed, the client starts a new one. Images are limited to
+// imaging.MAX_FILE_SIZE, as they are processed in memory.
+func completeUpload(ctx context.Context, db *gorm.DB, chunks *uploads.Chunks, store storage.Storage, upload *models.Upload) (*models.MediaAsset, error) {
+	digest, err := chunks.Digest(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := chunks.Open(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Sniff the type from the first bytes and rewind for the storage.
+	head := make([]byte, mediatype.SNIFF_LENGTH)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sniffed := mediatype.Sniff(head[:n])
+
+	invalid := mediatype.Check(mediatype.Context(upload.Context), upload.Filename, upload.ContentType, sniffed)
+	if invalid == nil && imaging.Supported(sniffed) && upload.Length > imaging.MAX_FILE_SIZE {
+		invalid = errImageTooLarge
+	}
+	if digest != upload.Checksum {
+		invalid = errChecksumMismatch
+	}
+	if invalid != nil {
+		if err := db.Delete(upload).Error; err != nil {
+			return nil, err
+		}
+		return nil, errors.Join(invalid, chunks.Remove(upload.ID))
+	}
+
+	object, err := store.Put(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	asset := newMediaAsset(mediatype.WithExtension(upload.Filename, sniffed), sniffed, object, upload.UserID)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		deleted := tx.Delete(upload)
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		if deleted.RowsAffected == 0 {
+			return errUploadNotFound
+		}
+		return tx.Create(&asset).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := chunks.Remove(upload.ID); err != nil {
+		log.Warn().
+			Str("component", "media_handler").
+			Str("event", "upload_chunks_remove_failed").
+			Str("upload_id", upload.ID.String()).
+			Err(err).
+			Msg("Could not remove the chunks of a completed upload.")
+	}
+	enqueueImage(&asset)
+	return &asset, nil
+}
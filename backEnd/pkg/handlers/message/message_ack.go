@@ -0,0 +1,255 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/readstate"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// messageAckRequest is the optional JSON body of an acknowledgement request.
+type messageAckRequest struct {
+	MessageID *uuid.UUID `json:"message_id"`
+}
+
+// MessageAckHandler handles HTTP POST requests for marking the messages of a channel as read.
+// Conversations and threads share the route, their ID can be used in place of a channel ID.
+// It expects the channel ID in the URL path and an optional JSON body with the ID of the last
+// read message, the newest message of the channel is used without it. The read position only
+// moves forward, acknowledging an older message keeps the current one. The response holds the
+// resulting read state with the remaining unread and mention counts.
+func MessageAckHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageAckHandler"
+		CONTEXT        string = "api/channels/{id}/ack"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ReadStatePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing channel acknowledgement request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageAckHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for acknowledging a channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the channel ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	channelID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid channel ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid channel ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel and the permissions of the caller in it.
+	target, err := permissions.ResolveTarget(db, channelID, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionViewChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing view channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to read this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the optional JSON request body.
+	var request messageAckRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data for acknowledgement", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	apiResponse.Params["message_id"] = request.MessageID
+
+	// The acknowledged message must belong to the channel, without one the newest message is used.
+	var lastRead models.Message
+	messageQuery := target.Scope(db).Select("id", "created_at")
+	if request.MessageID != nil {
+		err = messageQuery.First(&lastRead, "id = ?", *request.MessageID).Error
+	} else {
+		err = messageQuery.Order("created_at DESC, id DESC").First(&lastRead).Error
+	}
+	emptyChannel := request.MessageID == nil && errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !emptyChannel {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found in this channel", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Err(err).
+			Msg("Could not fetch the acknowledged message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Store the read position, an existing position is only replaced by a newer one.
+	// A channel without messages has nothing to acknowledge.
+	if !emptyChannel {
+		state := models.ReadState{
+			UserID:            userID,
+			ChannelID:         target.ID(),
+			LastReadMessageID: lastRead.ID,
+			LastReadAt:        lastRead.CreatedAt,
+		}
+		err = db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "channel_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_read_message_id", "last_read_at", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "(read_states.last_read_at, read_states.last_read_message_id) < (excluded.last_read_at, excluded.last_read_message_id)"},
+			}},
+		}).Create(&state).Error
+		if err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error storing read state due to a database issue", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "database_error_storing_read_state").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Err(err).
+				Msg("Database error storing read state.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Return the resulting read state with the remaining unread messages.
+	state, err := readstate.ForTarget(db, userID, target)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully acknowledged the channel but failed to count unread messages", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_counting_unread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error counting unread messages.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Channel acknowledged successfully."
+	apiResponse.Data = &models.ResponseData[models.ReadStatePayload]{
+		Items: []models.ReadStatePayload{state},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "channel_acknowledged").
+		Str("channel_id", channelID.String()).
+		Str("user_id", userID.String()).
+		Int("unread_count", state.UnreadCount).
+		Msg("Successfully acknowledged channel.")
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,373 @@
+package message
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/emoji"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mentions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/pagination"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SYSTEM_MESSAGE_PINNED is the content of the system message posted when a message is pinned.
+const SYSTEM_MESSAGE_PINNED = "pinned a message"
+
+// targetAccessError maps the errors returned by the permissions package when resolving
+// a channel or conversation to the matching api error.
+func targetAccessError(err error) *models.CustomError {
+	switch {
+	case errors.Is(err, permissions.ErrTargetNotFound):
+		return apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Channel not found", nil)
+	case errors.Is(err, permissions.ErrNotChatChannel):
+		return apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Messages can only be used in chat channels", nil)
+	case errors.Is(err, permissions.ErrNotMember):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You do not have access to this channel", nil)
+	case errors.Is(err, permissions.ErrNotParticipant):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a participant of this conversation", nil)
+	}
+	return apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving channel permissions", nil)
+}
+
+// directMessageError maps the errors returned by permissions.CanMessageConversation to the matching api error.
+func directMessageError(err error) *models.CustomError {
+	switch {
+	case errors.Is(err, permissions.ErrUserBlocked):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Direct messages are not possible with this user", nil)
+	case errors.Is(err, permissions.ErrDirectMessagesClosed):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("This user only accepts direct messages from friends", nil)
+	}
+	return apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error checking direct message settings", nil)
+}
+
+// preloadMessageRelations adds the relations included in message payloads to a query:
+// the public columns of the author, the attachments with their media assets and thumbnails, the
+// replied message with its author, including the tombstone of a deleted one, the
+// thread started from the message and its mentions.
+func preloadMessageRelations(query *gorm.DB) *gorm.DB {
+	publicUserColumns := func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id", "username", "profile_picture_asset_id")
+	}
+	return query.
+		Preload("Author", publicUserColumns).
+		Preload("Attachments.MediaAsset.Thumbnails", func(tx *gorm.DB) *gorm.DB { return tx.Order("size") }).
+		Preload("ReplyToMessage", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Preload("ReplyToMessage.Author", publicUserColumns).
+		Preload("Thread").
+		Preload("Mentions")
+}
+
+// fetchMessage loads a message with the relations included in message payloads.
+func fetchMessage(db *gorm.DB, id uuid.UUID) (*models.Message, error) {
+	var message models.Message
+	if err := preloadMessageRelations(db).First(&message, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// messageTypeFor derives the type of a new message from its content and attachments.
+// Messages without text take the type of their first attachment, documents are sent as text.
+func messageTypeFor(content string, assets []models.MediaAsset) models.MessageType {
+	if strings.TrimSpace(content) != "" || len(assets) == 0 {
+		return models.MessageTypeText
+	}
+	switch assets[0].MimeType {
+	case models.AssetTypeImage:
+		return models.MessageTypeImage
+	case models.AssetTypeVideo:
+		return models.MessageTypeVideo
+	case models.AssetTypeAudio:
+		return models.MessageTypeAudio
+	}
+	return models.MessageTypeText
+}
+
+// historyPage is one page of the message history of a channel, oldest message first.
+type historyPage struct {
+	Messages []models.Message
+	// HasOlder reports whether messages older than the page exist.
+	HasOlder bool
+	// HasNewer reports whether messages newer than the page exist.
+	HasNewer bool
+}
+
+// fetchHistory loads one page of the message history of a target with keyset pagination.
+// params:
+// - db: The GORM database instance.
+// - target: The channel or conversation to read.
+// - query: The parsed page request.
+// - anchor: The cursor message, loaded with its relations. Nil for pagination.DirectionLatest.
+// returns:
+// - historyPage: The page, with the messages ordered from oldest to newest.
+// - error: A database error.
+func fetchHistory(db *gorm.DB, target *permissions.Target, query pagination.Query, anchor *models.Message) (historyPage, error) {
+	scoped := func() *gorm.DB {
+		return preloadMessageRelations(target.Scope(db.Model(&models.Message{})))
+	}
+	var key pagination.Key
+	if anchor != nil {
+		key = pagination.Key{CreatedAt: anchor.CreatedAt, ID: anchor.ID}
+	}
+
+	// Each query asks for one extra row to find out whether more messages exist past the page.
+	var page historyPage
+	switch query.Direction {
+	case pagination.DirectionBefore, pagination.DirectionLatest:
+		var older []models.Message
+		var result *gorm.DB
+		if query.Direction == pagination.DirectionLatest {
+			result = pagination.Latest(scoped(), query.Limit+1).Find(&older)
+		} else {
+			result = pagination.Older(scoped(), key, query.Limit+1).Find(&older)
+		}
+		if result.Error != nil {
+			return historyPage{}, result.Error
+		}
+		page.HasOlder = len(older) > query.Limit
+		page.HasNewer = query.Direction == pagination.DirectionBefore
+		page.Messages = reverseMessages(truncateMessages(older, query.Limit))
+
+	case pagination.DirectionAfter:
+		var newer []models.Message
+		if err := pagination.Newer(scoped(), key, query.Limit+1).Find(&newer).Error; err != nil {
+			return historyPage{}, err
+		}
+		page.HasOlder = true
+		page.HasNewer = len(newer) > query.Limit
+		page.Messages = truncateMessages(newer, query.Limit)
+
+	case pagination.DirectionAround:
+		beforeCount, afterCount := query.AroundSplit()
+		var older, newer []models.Message
+		if err := pagination.Older(scoped(), key, beforeCount+1).Find(&older).Error; err != nil {
+			return historyPage{}, err
+		}
+		if err := pagination.Newer(scoped(), key, afterCount+1).Find(&newer).Error; err != nil {
+			return historyPage{}, err
+		}
+		page.HasOlder = len(older) > beforeCount
+		page.HasNewer = len(newer) > afterCount
+		page.Messages = reverseMessages(truncateMessages(older, beforeCount))
+		page.Messages = append(page.Messages, *anchor)
+		page.Messages = append(page.Messages, truncateMessages(newer, afterCount)...)
+	}
+
+	return page, nil
+}
+
+// truncateMessages drops the messages past the given count.
+func truncateMessages(messages []models.Message, count int) []models.Message {
+	if len(messages) > count {
+		return messages[:count]
+	}
+	return messages
+}
+
+// reverseMessages reverses the order of the messages in place and returns them.
+func reverseMessages(messages []models.Message) []models.Message {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+// reactionSummary is one row of the aggregated reactions of a message.
+type reactionSummary struct {
+	MessageID uuid.UUID
+	Emoji     string
+	Count     int
+	Me        bool
+}
+
+// attachReactions fills the aggregated reactions of message payloads. The emoji are ordered by
+// their first reaction and flagged when the user reacted with them.
+func attachReactions(db *gorm.DB, payloads []models.MessagePayload, userID uuid.UUID) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	positions := make(map[uuid.UUID]int, len(payloads))
+	messageIDs := make([]uuid.UUID, 0, len(payloads))
+	for i := range payloads {
+		positions[payloads[i].ID] = i
+		messageIDs = append(messageIDs, payloads[i].ID)
+	}
+
+	var summaries []reactionSummary
+	err := db.Model(&models.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS me", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("MIN(created_at) ASC, emoji ASC").
+		Scan(&summaries).Error
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		payload := &payloads[positions[summary.MessageID]]
+		payload.Reactions = append(payload.Reactions, models.ReactionPayload{
+			Emoji: summary.Emoji,
+			Count: summary.Count,
+			Me:    summary.Me,
+		})
+	}
+	return nil
+}
+
+// customEmojiUsable reports whether the user can react with the custom emoji: it must be an
+// emoji, not a sticker, of a server the user is a member of.
+func customEmojiUsable(db *gorm.DB, emojiID uuid.UUID, userID uuid.UUID) (bool, error) {
+	var serverEmoji models.ServerEmoji
+	err := db.Select("id", "server_id").First(&serverEmoji, "id = ? AND kind = ?", emojiID, models.EmojiKindEmoji).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, err = permissions.ForServer(db, serverEmoji.ServerID, userID)
+	if errors.Is(err, permissions.ErrNotMember) || errors.Is(err, permissions.ErrServerNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// resolveShortcodes replaces the :name: shortcodes in the content with the custom emoji of the
+// server the target belongs to. Content outside of server channels is returned unchanged.
+func resolveShortcodes(db *gorm.DB, target *permissions.Target, content string) (string, error) {
+	names := emoji.Shortcodes(content)
+	if target.ServerID == nil || len(names) == 0 {
+		return content, nil
+	}
+	var serverEmoji []models.ServerEmoji
+	err := db.Select("id", "name", "animated").
+		Where("server_id = ? AND kind = ? AND name IN ?", *target.ServerID, models.EmojiKindEmoji, names).
+		Find(&serverEmoji).Error
+	if err != nil {
+		return content, err
+	}
+	known := make(map[string]emoji.Custom, len(serverEmoji))
+	for _, e := range serverEmoji {
+		known[e.Name] = emoji.Custom{Name: e.Name, ID: e.ID, Animated: e.Animated}
+	}
+	return emoji.ReplaceShortcodes(content, known), nil
+}
+
+// lockTarget locks the row of the thread, channel or conversation messages are posted in,
+// serialising concurrent changes that are limited per target such as pins.
+func lockTarget(tx *gorm.DB, target *permissions.Target) error {
+	locking := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if target.ThreadID != nil {
+		return locking.Select("root_message_id").First(&models.Thread{}, "root_message_id = ?", *target.ThreadID).Error
+	}
+	if target.ChannelID != nil {
+		return locking.Select("id").First(&models.Channel{}, "id = ?", *target.ChannelID).Error
+	}
+	return locking.Select("id").First(&models.Conversation{}, "id = ?", *target.ConversationID).Error
+}
+
+// storeMentions replaces the recorded mentions of a message with the mentions of its content.
+// Only users with access to the target are recorded, and channel links only to channels of the
+// same server. @everyone and @here stay plain text unless the author has the mention everyone
+// permission. Servers have no roles, so role mentions are never recorded.
+func storeMentions(tx *gorm.DB, target *permissions.Target, messageID uuid.UUID, content string) error {
+	if err := tx.Where("message_id = ?", messageID).Delete(&models.MessageMention{}).Error; err != nil {
+		return err
+	}
+	parsed := mentions.Parse(content)
+	var rows []models.MessageMention
+
+	if len(parsed.Users) > 0 {
+		var allowed []uuid.UUID
+		var err error
+		if target.ServerID != nil {
+			err = tx.Raw(
+				"SELECT user_id FROM server_user_connects WHERE server_id = ? AND user_id IN ? UNION SELECT owner_id FROM servers WHERE id = ? AND owner_id IN ?",
+				*target.ServerID, parsed.Users, *target.ServerID, parsed.Users,
+			).Scan(&allowed).Error
+		} else {
+			err = tx.Model(&models.ConversationParticipant{}).
+				Where("conversation_id = ? AND user_id IN ?", *target.ConversationID, parsed.Users).
+				Pluck("user_id", &allowed).Error
+		}
+		if err != nil {
+			return err
+		}
+		for _, id := range parsed.Users {
+			if slices.Contains(allowed, id) {
+				rows = append(rows, models.MessageMention{MessageID: messageID, Kind: models.MentionKindUser, TargetID: id})
+			}
+		}
+	}
+
+	if len(parsed.Channels) > 0 && target.ServerID != nil {
+		var linked []uuid.UUID
+		if err := tx.Model(&models.Channel{}).Where("server_id = ? AND id IN ?", *target.ServerID, parsed.Channels).Pluck("id", &linked).Error; err != nil {
+			return err
+		}
+		for _, id := range parsed.Channels {
+			if slices.Contains(linked, id) {
+				rows = append(rows, models.MessageMention{MessageID: messageID, Kind: models.MentionKindChannel, TargetID: id})
+			}
+		}
+	}
+
+	if target.Permissions.Has(models.PermissionMentionEveryone) {
+		if parsed.Everyone {
+			rows = append(rows, models.MessageMention{MessageID: messageID, Kind: models.MentionKindEveryone, TargetID: uuid.Nil})
+		}
+		if parsed.Here {
+			rows = append(rows, models.MessageMention{MessageID: messageID, Kind: models.MentionKindHere, TargetID: uuid.Nil})
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
+
+// targetTopic returns the gateway topic the events of a target are published to: the server of
+// a channel or the conversation.
+func targetTopic(target *permissions.Target) uuid.UUID {
+	if target.ServerID != nil {
+		return *target.ServerID
+	}
+	return *target.ConversationID
+}
+
+// publishMessage publishes a created or updated message to the gateway. The me flag of the
+// reactions only applies to the caller, so it is cleared.
+func publishMessage(eventType gateway.EventType, target *permissions.Target, payload models.MessagePayload) {
+	if payload.Reactions != nil {
+		reactions := slices.Clone(payload.Reactions)
+		for i := range reactions {
+			reactions[i].Me = false
+		}
+		payload.Reactions = reactions
+	}
+	gateway.Publish(gateway.Event{Type: eventType, Topics: []uuid.UUID{targetTopic(target)}, Data: payload})
+}
+
+// publishReaction publishes an added or removed reaction to the gateway.
+func publishReaction(eventType gateway.EventType, target *permissions.Target, message *models.Message, userID uuid.UUID, emojiKey string) {
+	gateway.Publish(gateway.Event{
+		Type:   eventType,
+		Topics: []uuid.UUID{targetTopic(target)},
+		Data: gateway.ReactionData{
+			MessageID:      message.ID,
+			ChannelID:      message.ChannelID,
+			ConversationID: message.ConversationID,
+			ThreadID:       message.ThreadID,
+			UserID:         userID,
+			Emoji:          emojiKey,
+		},
+	})
+}
@@ -0,0 +1,402 @@
+package message
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/markup"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/typing"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// messageCreateRequest is the expected JSON body of a message creation request.
+type messageCreateRequest struct {
+	Content     string      `json:"content"`
+	ReplyTo     *uuid.UUID  `json:"reply_to"`
+	Attachments []uuid.UUID `json:"attachments"`
+}
+
+// MessageCreateHandler handles HTTP POST requests for sending a message to a channel.
+// Conversations and threads share the route, their ID can be used in place of a channel ID.
+// Replying in a thread unarchives it and makes the author follow it.
+// It expects the channel ID in the URL path and a JSON body with the message content,
+// an optional replied message from the same channel and optional media asset IDs
+// uploaded by the caller to attach. The caller must have the send messages permission, and in
+// direct conversations the other participant must still accept direct messages from the caller.
+// In server channels :name: shortcodes of the server's custom emoji are resolved to <:name:id>.
+func MessageCreateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageCreateHandler"
+		CONTEXT        string = "api/channels/{id}/messages"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing message creation request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for message creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the channel ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	channelID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid channel ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid channel ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel and the permissions of the caller in it.
+	target, err := permissions.ResolveTarget(db, channelID, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionSendMessages) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing send messages permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to send messages in this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Blocks and privacy settings also apply to direct conversations that already exist.
+	if target.Conversation != nil {
+		if err := permissions.CanMessageConversation(db, target.Conversation, userID); err != nil {
+			apiResponse.Error = directMessageError(err)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "direct_message_not_allowed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("conversation_id", target.Conversation.ID.String()).
+				Str("user_id", userID.String()).
+				Err(err).
+				Msg("Recipient does not accept direct messages from the user.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Decode the JSON request body.
+	var request messageCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Params["reply_to"] = request.ReplyTo
+	apiResponse.Params["attachments"] = request.Attachments
+
+	// Resolve the :name: shortcodes of the server's custom emoji before the content is validated,
+	// so the length limit applies to the stored content.
+	resolvedContent, err := resolveShortcodes(db, target, request.Content)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving custom emoji", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_resolving_emoji").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error resolving custom emoji shortcodes.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	request.Content = resolvedContent
+
+	// --- VALIDATION SECTION ---
+	if !validation.ValidateAttachmentCount(len(request.Attachments)) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Too many attachments", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_too_many_attachments").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int("attachment_count", len(request.Attachments)).
+			Msg("Validation error: too many attachments.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !validation.ValidateMessageContent(request.Content, len(request.Attachments) > 0) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid message content", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_content").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int("content_length", len(request.Content)).
+			Msg("Validation error: invalid message content.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Reject malformed or overlong formatting, such as unclosed code blocks or script links.
+	if err := markup.Validate(request.Content); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_markup").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Validation error: invalid message formatting.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The replied message must live in the same channel.
+	if request.ReplyTo != nil {
+		var replyCount int64
+		result := target.Scope(db.Model(&models.Message{})).Where("id = ?", *request.ReplyTo).Count(&replyCount)
+		if result.Error != nil || replyCount == 0 {
+			if result.Error != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching replied message", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Replied message not found in this channel", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_reply").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("reply_to", request.ReplyTo.String()).
+				Err(result.Error).
+				Msg("Validation error: invalid replied message.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Attachments must reference distinct media assets uploaded by the caller, of a type allowed for attachments.
+	var assets []models.MediaAsset
+	if len(request.Attachments) > 0 {
+		unique := make(map[uuid.UUID]bool, len(request.Attachments))
+		for _, assetID := range request.Attachments {
+			unique[assetID] = true
+		}
+		result := db.Where("id IN ? AND uploaded_by_user_id = ? AND content_type IN ?", request.Attachments, userID, mediatype.Allowlist(mediatype.ContextAttachment)).Find(&assets)
+		if result.Error != nil || len(unique) != len(request.Attachments) || len(assets) != len(request.Attachments) {
+			if result.Error != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching attachments", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Attachments must be distinct media assets of an allowed type uploaded by you", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_attachments").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Err(result.Error).
+				Msg("Validation error: invalid attachments.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+	// --- END VALIDATION SECTION ---
+
+	newMessage := models.Message{
+		AuthorID:    userID,
+		MessageType: messageTypeFor(request.Content, assets),
+		Content:     request.Content,
+		ReplyTo:     request.ReplyTo,
+	}
+	target.Assign(&newMessage)
+
+	// Create the message with its attachments and mentions in one transaction.
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("UpdatedAt").Create(&newMessage).Error; err != nil {
+			return err
+		}
+		for _, assetID := range request.Attachments {
+			attachment := models.MessageAttachment{MessageID: newMessage.ID, MediaAssetID: assetID}
+			if err := tx.Create(&attachment).Error; err != nil {
+				return err
+			}
+		}
+		if err := storeMentions(tx, target, newMessage.ID, newMessage.Content); err != nil {
+			return err
+		}
+		// A reply revives an archived thread and makes its author follow the thread.
+		if target.ThreadID != nil {
+			err := tx.Model(&models.Thread{}).Where("root_message_id = ?", *target.ThreadID).Updates(map[string]interface{}{
+				"reply_count":   gorm.Expr("reply_count + 1"),
+				"last_reply_at": newMessage.CreatedAt,
+				"archived_at":   nil,
+			}).Error
+			if err != nil {
+				return err
+			}
+			follower := models.ThreadFollower{ThreadID: *target.ThreadID, UserID: userID}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follower).Error; err != nil {
+				return err
+			}
+		}
+		// Conversations are listed by their last activity.
+		if target.ConversationID != nil {
+			return tx.Model(&models.Conversation{}).Where("id = ?", *target.ConversationID).Update("last_message_at", newMessage.CreatedAt).Error
+		}
+		return nil
+	})
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating message due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_creating_message").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error creating message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Re-fetch the message with its relations for the response.
+	createdMessage, err := fetchMessage(db, newMessage.ID)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully created message but failed to re-fetch", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_message").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Message created successfully."
+	apiResponse.Data = &models.ResponseData[models.MessagePayload]{
+		Items: []models.MessagePayload{models.NewMessagePayload(createdMessage)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "message_created_success").
+		Str("message_id", newMessage.ID.String()).
+		Str("channel_id", channelID.String()).
+		Msg("Successfully created message.")
+
+	// Sending the message ends the typing of the author.
+	typing.DefaultTracker.Stop(target.ID(), userID)
+	publishMessage(gateway.EventMessageCreate, target, apiResponse.Data.Items[0])
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,233 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MessageDeleteHandler handles HTTP DELETE requests for deleting a message.
+// It expects the message ID in the URL path.
+// A message can be deleted by its author or by users with the manage messages permission
+// in its channel. The message is kept as a tombstone without content, so replies to it show
+// that the original message was deleted, and its last content is kept as a revision for
+// moderators. The thread started from the message is kept, its replies belong to their authors.
+func MessageDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageDeleteHandler"
+		CONTEXT        string = "api/messages/{id}"
+		METHOD         string = "DELETE"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing message deletion request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageDeleteHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for message deletion.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the message ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	messageID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid message ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid message ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the message to delete.
+	var existingMessage models.Message
+	if err := db.First(&existingMessage, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message for deletion", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel of the message and the permissions of the caller in it.
+	target, err := permissions.ResolveMessageTarget(db, &existingMessage, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if existingMessage.AuthorID != userID && !target.Permissions.Has(models.PermissionManageMessages) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage messages permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to delete this message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Keep the last content as a revision, remove everything attached to the message and turn it
+	// into a tombstone in one transaction. A thread started from the message stays with the replies
+	// of the other users under the tombstone, deleting a thread message updates the reply count of
+	// its thread.
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		revision := models.MessageRevision{MessageID: messageID, Content: existingMessage.Content, ReplacedAt: now}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		for _, attached := range []interface{}{&models.MessageAttachment{}, &models.MessageReaction{}, &models.MessageMention{}} {
+			if err := tx.Where("message_id = ?", messageID).Delete(attached).Error; err != nil {
+				return err
+			}
+		}
+		if existingMessage.ThreadID != nil {
+			err := tx.Model(&models.Thread{}).Where("root_message_id = ?", *existingMessage.ThreadID).
+				Update("reply_count", gorm.Expr("GREATEST(reply_count - 1, 0)")).Error
+			if err != nil {
+				return err
+			}
+		}
+		// Replies keep referencing the tombstone, which is written without touching updated_at.
+		return tx.Model(&existingMessage).UpdateColumns(map[string]interface{}{
+			"content":       "",
+			"deleted_at":    now,
+			"deleted_by_id": userID,
+			"pinned_at":     nil,
+			"pinned_by_id":  nil,
+		}).Error
+	})
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error deleting message due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_deleting_message").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error deleting message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	deleted := true
+	apiResponse.Message = "Message deleted successfully."
+	apiResponse.Data = &models.ResponseData[models.MessagePayload]{
+		Deleted: &deleted,
+		Items:   []models.MessagePayload{models.NewMessagePayload(&existingMessage)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "message_deleted").
+		Str("message_id", messageID.String()).
+		Str("deleted_by", userID.String()).
+		Msg("Message deleted successfully.")
+
+	gateway.Publish(gateway.Event{
+		Type:   gateway.EventMessageDelete,
+		Topics: []uuid.UUID{targetTopic(target)},
+		Data: gateway.MessageDeleteData{
+			ID:             messageID,
+			ChannelID:      existingMessage.ChannelID,
+			ConversationID: existingMessage.ConversationID,
+			ThreadID:       existingMessage.ThreadID,
+		},
+	})
+
+	models.SendApiResponse(w, apiResponse)
+}
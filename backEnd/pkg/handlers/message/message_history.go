@@ -0,0 +1,203 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MessageHistoryHandler handles HTTP GET requests for the edit history of a message.
+// It expects the message ID in the URL path and returns the previous contents of the message,
+// oldest first. The history is available to the author of the message and to users with the
+// manage messages permission in its channel, or to every user who can view the channel when
+// MESSAGE_HISTORY_PUBLIC is set to true. The history of a deleted message, which ends with the
+// content it had when it was deleted, is only available to users with the manage messages permission.
+func MessageHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageHistoryHandler"
+		CONTEXT        string = "api/messages/{id}/history"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessageRevisionPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing message history request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageHistoryHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for message history.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the message ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	messageID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid message ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid message ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the message, including the tombstone of a deleted one.
+	var existingMessage models.Message
+	if err := db.Unscoped().First(&existingMessage, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel of the message and the permissions of the caller in it.
+	target, err := permissions.ResolveMessageTarget(db, &existingMessage, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The history is public to the viewers of the channel only when enabled, deleted messages
+	// are hidden from everyone but moderators.
+	isModerator := target.Permissions.Has(models.PermissionManageMessages)
+	canRead := target.Permissions.Has(models.PermissionViewChannels) &&
+		(isModerator || existingMessage.AuthorID == userID || os.Getenv("MESSAGE_HISTORY_PUBLIC") == "true")
+	if existingMessage.DeletedAt.Valid && !isModerator {
+		apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found", nil)
+	} else if !canRead {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing permission to view the message history", nil)
+	}
+	if apiResponse.Error != nil {
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to view the history of this message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	var revisions []models.MessageRevision
+	if err := db.Where("message_id = ?", messageID).Order("replaced_at ASC, id ASC").Find(&revisions).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message history", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_revisions").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching message history.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	payloads := make([]models.MessageRevisionPayload, 0, len(revisions))
+	for i := range revisions {
+		payloads = append(payloads, models.NewMessageRevisionPayload(&revisions[i]))
+	}
+
+	apiResponse.Message = "Message history retrieved successfully."
+	apiResponse.Data = &models.ResponseData[models.MessageRevisionPayload]{
+		Items: payloads,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "message_history_retrieved").
+		Str("message_id", messageID.String()).
+		Int("count", len(payloads)).
+		Msg("Successfully retrieved message history.")
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,253 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/pagination"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MessageListHandler handles HTTP GET requests for reading the message history of a channel.
+// Conversations and threads share the route, their ID can be used in place of a channel ID.
+// It expects the channel ID in the URL path and accepts one of the before, after and around
+// query parameters holding a message ID, plus an optional limit. Without a cursor the newest
+// messages are returned. Messages are always ordered from oldest to newest, and the pagination
+// links point to the previous (older) and next (newer) pages when they exist. Each message
+// carries its aggregated reactions, flagged with me when the caller reacted.
+func MessageListHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageListHandler"
+		CONTEXT        string = "api/channels/{id}/messages"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing message history request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageListHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for message history.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the channel ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	channelID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid channel ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid channel ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the cursor and the limit from the query string.
+	query, err := pagination.ParseQuery(r.URL.Query())
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_page_query").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("query", r.URL.RawQuery).
+			Err(err).
+			Msg("Validation error: invalid page query.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	apiResponse.Params["direction"] = query.Direction
+	apiResponse.Params["limit"] = query.Limit
+	if query.Direction != pagination.DirectionLatest {
+		apiResponse.Params["cursor"] = query.Cursor
+	}
+
+	// Resolve the channel and the permissions of the caller in it.
+	target, err := permissions.ResolveTarget(db, channelID, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionViewChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing view channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to read this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The cursor message must belong to the channel, its (created_at, id) is the page key.
+	var anchor *models.Message
+	if query.Direction != pagination.DirectionLatest {
+		var cursorMessage models.Message
+		err := preloadMessageRelations(target.Scope(db)).First(&cursorMessage, "id = ?", query.Cursor).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Cursor message not found in this channel", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching cursor message", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "cursor_fetch_failed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("cursor", query.Cursor.String()).
+				Err(err).
+				Msg("Could not fetch cursor message.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		anchor = &cursorMessage
+	}
+
+	page, err := fetchHistory(db, target, query, anchor)
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching messages", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_messages").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching message history.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	payloads := make([]models.MessagePayload, 0, len(page.Messages))
+	for i := range page.Messages {
+		payloads = append(payloads, models.NewMessagePayload(&page.Messages[i]))
+	}
+	if err := attachReactions(db, payloads, userID); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message reactions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_reactions").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching message reactions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Link to the neighbouring pages using the first and last message as cursors.
+	limit := query.Limit
+	pageInfo := &models.Pagination{TotalItems: len(payloads), ItemsPerPage: &limit}
+	if len(payloads) > 0 {
+		path := "/api/channels/" + channelID.String() + "/messages"
+		if page.HasOlder {
+			previous := pagination.Link(path, pagination.DirectionBefore, payloads[0].ID, limit)
+			pageInfo.PreviousLink = &previous
+		}
+		if page.HasNewer {
+			next := pagination.Link(path, pagination.DirectionAfter, payloads[len(payloads)-1].ID, limit)
+			pageInfo.NextLink = &next
+		}
+	}
+
+	apiResponse.Message = "Messages retrieved successfully."
+	apiResponse.Data = &models.ResponseData[models.MessagePayload]{
+		Pagination: pageInfo,
+		Items:      payloads,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "messages_retrieved").
+		Str("channel_id", channelID.String()).
+		Str("direction", string(query.Direction)).
+		Int("count", len(payloads)).
+		Msg("Successfully retrieved message history.")
+
+	models.SendApiResponse(w, apiResponse)
+}
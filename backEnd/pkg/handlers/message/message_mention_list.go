@@ -0,0 +1,212 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/pagination"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// errMentionCursor is returned when the mention inbox is requested with a cursor other than before.
+var errMentionCursor = errors.New("mentions can only be paginated with the before parameter")
+
+// MessageMentionListHandler handles HTTP GET requests for the mention inbox of the caller: the
+// messages of other users mentioning them directly or through @everyone and @here, across the
+// channels of every server they are a member of. It accepts a before query parameter holding the
+// last message ID of the previous page, plus an optional limit. Messages are ordered from the
+// newest one.
+func MessageMentionListHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageMentionListHandler"
+		CONTEXT        string = "api/user/me/mentions"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing mention inbox request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageMentionListHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for the mention inbox.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the cursor and the limit from the query string. The inbox is read from the newest
+	// mention, so only pages before a cursor can be requested.
+	query, err := pagination.ParseQuery(r.URL.Query())
+	if err == nil && query.Direction != pagination.DirectionLatest && query.Direction != pagination.DirectionBefore {
+		err = errMentionCursor
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_page_query").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("query", r.URL.RawQuery).
+			Err(err).
+			Msg("Validation error: invalid page query.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	apiResponse.Params = map[string]interface{}{
+		"limit": query.Limit,
+	}
+	if query.Direction == pagination.DirectionBefore {
+		apiResponse.Params["before"] = query.Cursor
+	}
+
+	inbox := mentionInbox(db, userID)
+
+	// The cursor message must be part of the inbox, its (created_at, id) is the page key.
+	if query.Direction == pagination.DirectionBefore {
+		var cursorMessage models.Message
+		if err := mentionInbox(db, userID).Select("id", "created_at").First(&cursorMessage, "id = ?", query.Cursor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Cursor message not found in your mentions", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching cursor message", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "cursor_fetch_failed").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("cursor", query.Cursor.String()).
+				Err(err).
+				Msg("Could not fetch cursor message.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+		key := pagination.Key{CreatedAt: cursorMessage.CreatedAt, ID: cursorMessage.ID}
+		inbox = pagination.Older(inbox, key, query.Limit+1)
+	} else {
+		inbox = inbox.Order("created_at DESC, id DESC").Limit(query.Limit + 1)
+	}
+
+	// Fetch one message more than requested to know whether an older page exists.
+	var messages []models.Message
+	if err := preloadMessageRelations(inbox).Find(&messages).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching mentions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_mentions").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching mentions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	hasOlder := len(messages) > query.Limit
+	if hasOlder {
+		messages = messages[:query.Limit]
+	}
+
+	payloads := make([]models.MessagePayload, 0, len(messages))
+	for i := range messages {
+		payloads = append(payloads, models.NewMessagePayload(&messages[i]))
+	}
+	if err := attachReactions(db, payloads, userID); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message reactions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_reactions").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching message reactions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	limit := query.Limit
+	pageInfo := &models.Pagination{TotalItems: len(payloads), ItemsPerPage: &limit}
+	if hasOlder {
+		next := pagination.Link("/api/user/me/mentions", pagination.DirectionBefore, payloads[len(payloads)-1].ID, limit)
+		pageInfo.NextLink = &next
+	}
+
+	apiResponse.Message = "Mentions retrieved successfully."
+	apiResponse.Data = &models.ResponseData[models.MessagePayload]{
+		Pagination: pageInfo,
+		Items:      payloads,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "mentions_retrieved").
+		Str("user_id", userID.String()).
+		Int("count", len(payloads)).
+		Msg("Successfully retrieved mentions.")
+
+	models.SendApiResponse(w, apiResponse)
+}
+
+// mentionInbox returns a query for the messages in the mention inbox of a user: messages of other
+// users in the channels of the servers the user owns or is a member of, mentioning the user
+// directly or through @everyone and @here.
+func mentionInbox(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	mentioned := db.Model(&models.MessageMention{}).Select("message_id").
+		Where("(kind = ? AND target_id = ?) OR kind IN ?", models.MentionKindUser, userID, []models.MentionKind{models.MentionKindEveryone, models.MentionKindHere})
+	return db.Model(&models.Message{}).
+		Where("channel_id IN (?)", permissions.ReadableChannels(db, userID)).
+		Where("id IN (?)", mentioned).
+		Where("author_id <> ?", userID)
+}
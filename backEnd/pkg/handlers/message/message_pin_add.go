@@ -0,0 +1,313 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// errTooManyPins is returned inside the pin transaction when the channel already has the
+// maximum number of pinned messages.
+var errTooManyPins = errors.New("the channel has reached the maximum number of pinned messages")
+
+// MessagePinAddHandler handles HTTP PUT requests for pinning a message to a channel.
+// Conversations and threads share the route, their ID can be used in place of a channel ID.
+// It expects the channel ID and the message ID in the URL path, the message must belong to the
+// channel. Pinning requires the pin messages permission and records who pinned the message and
+// when. A system message referencing the pinned message is posted in the channel. Pinning a
+// message that is already pinned has no effect.
+func MessagePinAddHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessagePinAddHandler"
+		CONTEXT        string = "api/channels/{id}/pins/{messageId}"
+		METHOD         string = "PUT"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing message pin request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessagePinAddHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for pinning a message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the channel and message IDs from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":        vars["id"],
+		"messageId": vars["messageId"],
+	}
+	channelID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid channel ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid channel ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	messageID, err := uuid.Parse(vars["messageId"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid message ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_message_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", vars["messageId"]).
+			Err(err).
+			Msg("Invalid message ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel and the permissions of the caller in it.
+	target, err := permissions.ResolveTarget(db, channelID, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionViewChannels | models.PermissionPinMessages) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing pin messages permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("channel_id", channelID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to pin messages in this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// The message must belong to the channel.
+	var existingMessage models.Message
+	if err := target.Scope(db).Select("id", "message_type").First(&existingMessage, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found in this channel", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// --- VALIDATION SECTION ---
+	if existingMessage.MessageType == models.MessageTypeSystem {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("System messages cannot be pinned", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_system_message").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Msg("Validation error: system messages cannot be pinned.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	// Pin the message and post the system message in one transaction. The channel row is locked
+	// so concurrent pins cannot exceed the limit.
+	alreadyPinned := false
+	var systemMessageID uuid.UUID
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := lockTarget(tx, target); err != nil {
+			return err
+		}
+		var current models.Message
+		if err := tx.Select("id", "pinned_at").First(&current, "id = ?", messageID).Error; err != nil {
+			return err
+		}
+		if current.PinnedAt != nil {
+			alreadyPinned = true
+			return nil
+		}
+		var pinCount int64
+		if err := target.Scope(tx.Model(&models.Message{})).Where("pinned_at IS NOT NULL").Count(&pinCount).Error; err != nil {
+			return err
+		}
+		if !validation.ValidatePinCount(int(pinCount) + 1) {
+			return errTooManyPins
+		}
+		now := time.Now()
+		// Pinning is not an edit, so the columns are written without touching updated_at.
+		err := tx.Model(&models.Message{}).Where("id = ?", messageID).UpdateColumns(map[string]interface{}{
+			"pinned_at":    now,
+			"pinned_by_id": userID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		systemMessage := models.Message{
+			AuthorID:    userID,
+			MessageType: models.MessageTypeSystem,
+			Content:     SYSTEM_MESSAGE_PINNED,
+			ReplyTo:     &messageID,
+		}
+		target.Assign(&systemMessage)
+		if err := tx.Omit("UpdatedAt").Create(&systemMessage).Error; err != nil {
+			return err
+		}
+		systemMessageID = systemMessage.ID
+		// Conversations are listed by their last activity.
+		if target.ConversationID != nil {
+			return tx.Model(&models.Conversation{}).Where("id = ?", *target.ConversationID).Update("last_message_at", systemMessage.CreatedAt).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errTooManyPins) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The channel has reached the maximum number of pinned messages", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error pinning message due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_pin_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not pin message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Re-fetch the message with its relations for the response.
+	pinnedMessage, err := fetchMessage(db, messageID)
+	if err == nil {
+		payloads := []models.MessagePayload{models.NewMessagePayload(pinnedMessage)}
+		err = attachReactions(db, payloads, userID)
+		apiResponse.Data = &models.ResponseData[models.MessagePayload]{Items: payloads}
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully pinned the message but failed to re-fetch it", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_message").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Message pinned successfully."
+	if alreadyPinned {
+		apiResponse.Message = "Message is already pinned."
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "message_pinned").
+		Str("channel_id", channelID.String()).
+		Str("message_id", messageID.String()).
+		Str("user_id", userID.String()).
+		Bool("already_pinned", alreadyPinned).
+		Msg("Successfully pinned message.")
+
+	if !alreadyPinned {
+		publishMessage(gateway.EventMessageUpdate, target, apiResponse.Data.Items[0])
+		if systemMessage, err := fetchMessage(db, systemMessageID); err == nil {
+			publishMessage(gateway.EventMessageCreate, target, models.NewMessagePayload(systemMessage))
+		}
+	}
+
+	models.SendApiResponse(w, apiResponse)
+}
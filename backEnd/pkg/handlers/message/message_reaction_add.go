@@ -0,0 +1,287 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/emoji"
+	"github.com/413ksz/BlueFox/backEnd/pkg/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errTooManyReactionEmoji is returned inside the reaction transaction when the message already
+// has the maximum number of distinct emoji.
+var errTooManyReactionEmoji = errors.New("the message has reached the maximum number of different reactions")
+
+// MessageReactionAddHandler handles HTTP PUT requests for reacting to a message.
+// It expects the message ID and the emoji in the URL path. The emoji is a Unicode emoji or a
+// custom emoji written as name:id from a server the caller is a member of. Reacting requires
+// the add reactions permission, which a channel can deny. Adding a reaction the caller already
+// has has no effect.
+func MessageReactionAddHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageReactionAddHandler"
+		CONTEXT        string = "api/messages/{id}/reactions/{emoji}"
+		METHOD         string = "PUT"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing reaction add request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageReactionAddHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for adding a reaction.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the message ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":    vars["id"],
+		"emoji": vars["emoji"],
+	}
+	messageID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid message ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid message ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the emoji from the URL path.
+	reaction, err := emoji.Parse(vars["emoji"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_emoji").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("emoji", vars["emoji"]).
+			Err(err).
+			Msg("Invalid emoji in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the message.
+	var existingMessage models.Message
+	if err := db.Select("id", "channel_id", "conversation_id", "thread_id").First(&existingMessage, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel of the message and the permissions of the caller in it.
+	target, err := permissions.ResolveMessageTarget(db, &existingMessage, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionViewChannels | models.PermissionAddReactions) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing add reactions permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to react in this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Custom emoji can be used from the servers the caller is a member of.
+	if reaction.IsCustom() {
+		usable, err := customEmojiUsable(db, *reaction.CustomID, userID)
+		if err != nil || !usable {
+			if err != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching custom emoji", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Unknown custom emoji", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_unknown_emoji").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("emoji", reaction.Key()).
+				Err(err).
+				Msg("Custom emoji cannot be used by the caller.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Add the reaction, a message can only be reacted with a limited number of distinct emoji.
+	// The message row is locked so concurrent reactions cannot exceed the limit.
+	added := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked models.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", messageID).Error; err != nil {
+			return err
+		}
+		var emojiKeys []string
+		if err := tx.Model(&models.MessageReaction{}).Where("message_id = ?", messageID).Distinct().Pluck("emoji", &emojiKeys).Error; err != nil {
+			return err
+		}
+		if !slices.Contains(emojiKeys, reaction.Key()) && !validation.ValidateReactionEmojiCount(len(emojiKeys)+1) {
+			return errTooManyReactionEmoji
+		}
+		newReaction := models.MessageReaction{MessageID: messageID, UserID: userID, Emoji: reaction.Key()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newReaction)
+		added = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		if errors.Is(err, errTooManyReactionEmoji) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The message has reached the maximum number of different reactions", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error adding reaction due to a database issue", nil)
+		}
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "reaction_add_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not add reaction.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Re-fetch the message with its reactions for the response.
+	updatedMessage, err := fetchMessage(db, messageID)
+	if err == nil {
+		payloads := []models.MessagePayload{models.NewMessagePayload(updatedMessage)}
+		err = attachReactions(db, payloads, userID)
+		apiResponse.Data = &models.ResponseData[models.MessagePayload]{Items: payloads}
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully added the reaction but failed to re-fetch the message", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_message").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	apiResponse.Message = "Reaction added successfully."
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "reaction_added").
+		Str("message_id", messageID.String()).
+		Str("user_id", userID.String()).
+		Str("emoji", reaction.Key()).
+		Msg("Successfully added reaction.")
+
+	if added {
+		publishReaction(gateway.EventReactionAdd, target, &existingMessage, userID, reaction.Key())
+	}
+
+	models.SendApiResponse(w, apiResponse)
+}
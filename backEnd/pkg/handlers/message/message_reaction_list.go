@@ -0,0 +1,265 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/emoji"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/pagination"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// errReactorCursor is returned when a reactor list is requested with a cursor other than after.
+var errReactorCursor = errors.New("reactors can only be paginated with the after parameter")
+
+// MessageReactionListHandler handles HTTP GET requests for listing the users who reacted to a
+// message with an emoji. It expects the message ID and the emoji in the URL path and accepts an
+// after query parameter holding the last user ID of the previous page, plus an optional limit.
+// Users are ordered by their ID, so pages stay stable while new reactions arrive.
+func MessageReactionListHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageReactionListHandler"
+		CONTEXT        string = "api/messages/{id}/reactions/{emoji}"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.PublicUser]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing reactor list request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageReactionListHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for listing reactors.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the message ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id":    vars["id"],
+		"emoji": vars["emoji"],
+	}
+	messageID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid message ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid message ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the emoji from the URL path.
+	reaction, err := emoji.Parse(vars["emoji"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_emoji").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("emoji", vars["emoji"]).
+			Err(err).
+			Msg("Invalid emoji in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the cursor and the limit from the query string. Reactors are ordered by user ID,
+	// so only pages after a cursor can be requested.
+	query, err := pagination.ParseQuery(r.URL.Query())
+	if err == nil && query.Direction != pagination.DirectionLatest && query.Direction != pagination.DirectionAfter {
+		err = errReactorCursor
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_page_query").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("query", r.URL.RawQuery).
+			Err(err).
+			Msg("Validation error: invalid page query.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	apiResponse.Params["limit"] = query.Limit
+	if query.Direction == pagination.DirectionAfter {
+		apiResponse.Params["after"] = query.Cursor
+	}
+
+	// Fetch the message.
+	var existingMessage models.Message
+	if err := db.Select("id", "channel_id", "conversation_id", "thread_id").First(&existingMessage, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel of the message and the permissions of the caller in it.
+	target, err := permissions.ResolveMessageTarget(db, &existingMessage, userID)
+	if err != nil {
+		apiResponse.Error = targetAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionViewChannels) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing view channels permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to read this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch one reaction more than requested to know whether a next page exists.
+	var reactions []models.MessageReaction
+	reactionQuery := db.
+		Preload("User", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("id", "username", "profile_picture_asset_id")
+		}).
+		Where("message_id = ? AND emoji = ?", messageID, reaction.Key())
+	if query.Direction == pagination.DirectionAfter {
+		reactionQuery = reactionQuery.Where("user_id > ?", query.Cursor)
+	}
+	if err := reactionQuery.Order("user_id ASC").Limit(query.Limit + 1).Find(&reactions).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching reactions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_reactions").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching reactions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	hasMore := len(reactions) > query.Limit
+	if hasMore {
+		reactions = reactions[:query.Limit]
+	}
+
+	users := make([]models.PublicUser, 0, len(reactions))
+	for i := range reactions {
+		users = append(users, *models.NewPublicUser(&reactions[i].User))
+	}
+
+	limit := query.Limit
+	pageInfo := &models.Pagination{TotalItems: len(users), ItemsPerPage: &limit}
+	if hasMore {
+		path := "/api/messages/" + messageID.String() + "/reactions/" + url.PathEscape(reaction.Key())
+		next := pagination.Link(path, pagination.DirectionAfter, users[len(users)-1].ID, limit)
+		pageInfo.NextLink = &next
+	}
+
+	apiResponse.Message = "Reactions retrieved successfully."
+	apiResponse.Data = &models.ResponseData[models.PublicUser]{
+		Pagination: pageInfo,
+		Items:      users,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "reactions_retrieved").
+		Str("message_id", messageID.String()).
+		Str("emoji", reaction.Key()).
+		Int("count", len(users)).
+		Msg("Successfully retrieved reactions.")
+
+	models.SendApiResponse(w, apiResponse)
+}
@@ -0,0 +1,256 @@
+package message
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/search"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchTextCondition matches the content of a message against the free text of a search query.
+const searchTextCondition = "content_tsv @@ websearch_to_tsquery('simple', ?)"
+
+// MessageSearchHandler handles HTTP GET requests for searching messages with full-text search.
+// It expects the query in the q query parameter, with the filters described in the search
+// package, and accepts a sort of relevance (default) or date, a zero-based page and a limit.
+// Only messages in the server channels and conversations the caller can read are returned,
+// system messages are never included.
+func MessageSearchHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "message_handler"
+		METHOD_NAME    string = "MessageSearchHandler"
+		CONTEXT        string = "api/search/messages"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.MessagePayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing message search request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for MessageSearchHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for message search.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Parse the query, the sort order and the page from the query string.
+	request, err := search.ParseRequest(r.URL.Query())
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse(err.Error(), nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_search").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("query", r.URL.RawQuery).
+			Err(err).
+			Msg("Validation error: invalid search request.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	apiResponse.Params = map[string]interface{}{
+		"q":     r.URL.Query().Get("q"),
+		"sort":  request.Sort,
+		"page":  request.Page,
+		"limit": request.Limit,
+	}
+
+	// Fetch one message more than requested to know whether a next page exists.
+	var messages []models.Message
+	found, err := searchQuery(db, userID, request.Query)
+	if err == nil {
+		err = preloadMessageRelations(orderSearch(found, request)).
+			Offset(request.Page * request.Limit).
+			Limit(request.Limit + 1).
+			Find(&messages).Error
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error searching messages", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_searching_messages").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error searching messages.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	hasMore := len(messages) > request.Limit
+	if hasMore {
+		messages = messages[:request.Limit]
+	}
+
+	payloads := make([]models.MessagePayload, 0, len(messages))
+	for i := range messages {
+		payloads = append(payloads, models.NewMessagePayload(&messages[i]))
+	}
+	if err := attachReactions(db, payloads, userID); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message reactions", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_reactions").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error fetching message reactions.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Link to the neighbouring pages with the same query.
+	page, limit := request.Page, request.Limit
+	pageInfo := &models.Pagination{TotalItems: len(payloads), ItemsPerPage: &limit, PageIndex: &page}
+	pageLink := func(index int) *string {
+		values := url.Values{}
+		values.Set("q", r.URL.Query().Get("q"))
+		values.Set("sort", string(request.Sort))
+		values.Set("page", strconv.Itoa(index))
+		values.Set("limit", strconv.Itoa(limit))
+		link := "/api/search/messages?" + values.Encode()
+		return &link
+	}
+	if page > 0 {
+		pageInfo.PreviousLink = pageLink(page - 1)
+	}
+	if hasMore {
+		pageInfo.NextLink = pageLink(page + 1)
+	}
+
+	apiResponse.Message = "Messages searched successfully."
+	apiResponse.Data = &models.ResponseData[models.MessagePayload]{
+		Pagination: pageInfo,
+		Items:      payloads,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "messages_searched").
+		Str("user_id", userID.String()).
+		Str("sort", string(request.Sort)).
+		Int("count", len(payloads)).
+		Msg("Successfully searched messages.")
+
+	models.SendApiResponse(w, apiResponse)
+}
+
+// searchQuery returns a query for the messages matching a search query among the messages the
+// user can read. Authors of from: filters are given by ID or username, unknown authors match nothing.
+func searchQuery(db *gorm.DB, userID uuid.UUID, query search.Query) (*gorm.DB, error) {
+	found := db.Model(&models.Message{}).
+		Where("(channel_id IN (?) OR conversation_id IN (?))", permissions.ReadableChannels(db, userID), permissions.ReadableConversations(db, userID)).
+		Where("message_type <> ?", models.MessageTypeSystem)
+
+	if query.Text != "" {
+		found = found.Where(searchTextCondition, query.Text)
+	}
+
+	if len(query.From) > 0 {
+		var authorIDs []uuid.UUID
+		var usernames []string
+		for _, author := range query.From {
+			if id, err := uuid.Parse(author); err == nil {
+				authorIDs = append(authorIDs, id)
+			} else {
+				usernames = append(usernames, author)
+			}
+		}
+		if len(usernames) > 0 {
+			var named []uuid.UUID
+			if err := db.Model(&models.User{}).Where("username IN ?", usernames).Pluck("id", &named).Error; err != nil {
+				return nil, err
+			}
+			authorIDs = append(authorIDs, named...)
+		}
+		found = found.Where("author_id IN ?", authorIDs)
+	}
+
+	if len(query.In) > 0 {
+		found = found.Where("(channel_id IN ? OR conversation_id IN ? OR thread_id IN ?)", query.In, query.In, query.In)
+	}
+
+	for _, has := range query.Has {
+		switch has {
+		case search.HasAttachment:
+			found = found.Where("EXISTS (SELECT 1 FROM message_attachments a WHERE a.message_id = messages.id)")
+		case search.HasImage:
+			found = found.Where("EXISTS (SELECT 1 FROM message_attachments a JOIN media_assets ma ON ma.id = a.media_asset_id WHERE a.message_id = messages.id AND ma.mime_type = ?)", models.AssetTypeImage)
+		case search.HasLink:
+			found = found.Where("content ~* ?", `https?://`)
+		}
+	}
+
+	if query.After != nil {
+		found = found.Where("created_at >= ?", *query.After)
+	}
+	if query.Before != nil {
+		found = found.Where("created_at < ?", *query.Before)
+	}
+	return found, nil
+}
+
+// orderSearch orders search results by the requested sort. Relevance falls back to the date
+// when the query has no free text to rank by. The ranked order is a single expression because
+// GORM drops an order expression when further columns are added.
+func orderSearch(found *gorm.DB, request search.Request) *gorm.DB {
+	if request.Sort == search.SortRelevance && request.Query.Text != "" {
+		return found.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(content_tsv, websearch_to_tsquery('simple', ?)) DESC, created_at DESC, id DESC",
+			Vars: []interface{}{request.Query.Text},
+		}})
+	}
+	return found.Order("created_at DESC, id DESC")
+}
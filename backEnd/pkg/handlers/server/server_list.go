@@ -0,0 +1,161 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/readstate"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ServerListHandler handles HTTP GET requests for listing the servers of the caller, the ones they
+// own or are a member of, sorted by title. Each server carries the read state of its chat channels
+// with their unread message and mention counts, and the totals of the server for its badge.
+func ServerListHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "server_handler"
+		METHOD_NAME    string = "ServerListHandler"
+		CONTEXT        string = "api/user/me/servers"
+		METHOD         string = "GET"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ServerPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing server list request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ServerListHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for server list.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the servers the caller owns or is a member of.
+	var servers []models.Server
+	result := db.
+		Where("id IN (?)", permissions.MemberServers(db, userID)).
+		Order("title ASC, id ASC").
+		Find(&servers)
+	if result.Error != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching servers", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_servers").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Str("user_id", userID.String()).
+			Err(result.Error).
+			Msg("Database error fetching servers.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the chat channels of every server and count their unread messages in one query each.
+	// Every member can view every channel, so no channel is filtered out.
+	serverIDs := make([]uuid.UUID, 0, len(servers))
+	for _, server := range servers {
+		serverIDs = append(serverIDs, server.ID)
+	}
+	var channels []models.Channel
+	var states map[uuid.UUID]models.ReadStatePayload
+	err := db.Select("id", "server_id").
+		Where("server_id IN ? AND type = ?", serverIDs, models.ChannelTypeChat).
+		Order("position ASC, id ASC").
+		Find(&channels).Error
+	if err == nil {
+		channelIDs := make([]uuid.UUID, 0, len(channels))
+		for _, channel := range channels {
+			channelIDs = append(channelIDs, channel.ID)
+		}
+		states, err = readstate.ForChannels(db, userID, channelIDs)
+	}
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error counting unread messages", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_counting_unread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Database error counting unread messages.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	payloads := make([]models.ServerPayload, 0, len(servers))
+	indexes := make(map[uuid.UUID]int, len(servers))
+	for i := range servers {
+		indexes[servers[i].ID] = i
+		payloads = append(payloads, models.NewServerPayload(&servers[i]))
+	}
+	for _, channel := range channels {
+		state := states[channel.ID]
+		state.ChannelID = channel.ID
+		payload := &payloads[indexes[channel.ServerID]]
+		payload.Channels = append(payload.Channels, state)
+		// The totals are capped like the counts of the channels.
+		payload.UnreadCount = min(payload.UnreadCount+state.UnreadCount, readstate.MAX_UNREAD_COUNT)
+		payload.MentionCount = min(payload.MentionCount+state.MentionCount, readstate.MAX_UNREAD_COUNT)
+	}
+
+	apiResponse.Message = "Servers retrieved successfully."
+	apiResponse.Data = &models.ResponseData[models.ServerPayload]{
+		Pagination: &models.Pagination{TotalItems: len(payloads)},
+		Items:      payloads,
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "servers_retrieved").
+		Str("user_id", userID.String()).
+		Int("count", len(payloads)).
+		Msg("Successfully retrieved servers.")
+
+	models.SendApiResponse(w, apiResponse)
+}
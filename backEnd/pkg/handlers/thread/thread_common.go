@@ -0,0 +1,41 @@
+package thread
+
+import (
+	"errors"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// threadAccessError maps the errors returned by the permissions package when resolving
+// a thread or the channel of its root message to the matching api error.
+func threadAccessError(err error) *models.CustomError {
+	switch {
+	case errors.Is(err, permissions.ErrTargetNotFound):
+		return apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Thread not found", nil)
+	case errors.Is(err, permissions.ErrNotChatChannel):
+		return apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Threads can only be used in chat channels", nil)
+	case errors.Is(err, permissions.ErrNotMember):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You do not have access to this channel", nil)
+	case errors.Is(err, permissions.ErrNotParticipant):
+		return apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("You are not a participant of this conversation", nil)
+	}
+	return apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error resolving thread permissions", nil)
+}
+
+// isFollowing reports whether the user follows the thread.
+func isFollowing(db *gorm.DB, threadID uuid.UUID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.ThreadFollower{}).Where("thread_id = ? AND user_id = ?", threadID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// threadPayloadFor creates the JSON representation of a thread as seen by the given user.
+func threadPayloadFor(thread *models.Thread, following bool) models.ThreadPayload {
+	payload := models.NewThreadPayload(thread)
+	payload.Following = &following
+	return *payload
+}
@@ -0,0 +1,320 @@
+package thread
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// threadCreateRequest is the expected JSON body of a thread creation request.
+// The body is optional, a thread without a name uses the default archive duration.
+type threadCreateRequest struct {
+	Name               *string `json:"name"`
+	AutoArchiveMinutes *int    `json:"auto_archive_minutes"`
+}
+
+// ThreadCreateHandler handles HTTP POST requests for starting a thread from a message.
+// It expects the ID of the root message in the URL path and an optional JSON body with the
+// name and the auto archive duration of the thread. Starting a thread requires the send
+// messages permission, and the creator follows the new thread. When the message already has
+// a thread, the existing thread is returned. Replies are sent and listed through
+// /api/channels/{threadId}/messages.
+func ThreadCreateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "thread_handler"
+		METHOD_NAME    string = "ThreadCreateHandler"
+		CONTEXT        string = "api/messages/{id}/thread"
+		METHOD         string = "POST"
+		STATUS_DEFAULT int    = http.StatusCreated
+	)
+
+	apiResponse := &models.ApiResponse[models.ThreadPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing thread creation request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ThreadCreateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for thread creation.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the message ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	messageID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid message ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid message ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the optional JSON request body.
+	var request threadCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data for thread", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Fetch the root message.
+	var rootMessage models.Message
+	if err := db.Select("id", "channel_id", "conversation_id", "thread_id").First(&rootMessage, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apiResponse.Error = apierrors.ERROR_CODE_NOT_FOUND.ApiErrorResponse("Message not found", nil)
+		} else {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching message", nil)
+		}
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "message_fetch_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch root message.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the channel of the message and the permissions of the caller in it.
+	target, err := permissions.ResolveMessageTarget(db, &rootMessage, userID)
+	if err != nil {
+		apiResponse.Error = threadAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "channel_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve channel access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if !target.Permissions.Has(models.PermissionViewChannels | models.PermissionSendMessages) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing send messages permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to start threads in this channel.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// --- VALIDATION SECTION ---
+	if rootMessage.ThreadID != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Threads cannot be started from a message inside a thread", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_nested_thread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("message_id", messageID.String()).
+			Msg("Validation error: message is part of a thread.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if request.Name != nil && !validation.ValidateThreadName(*request.Name) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid thread name", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_name").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("name", *request.Name).
+			Msg("Validation error: invalid thread name.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	autoArchiveMinutes := models.THREAD_DEFAULT_AUTO_ARCHIVE_MINUTES
+	if request.AutoArchiveMinutes != nil {
+		autoArchiveMinutes = *request.AutoArchiveMinutes
+	}
+	if !validation.ValidateAutoArchiveMinutes(autoArchiveMinutes) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Auto archive duration must be 60, 1440, 4320 or 10080 minutes", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_auto_archive").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int("auto_archive_minutes", autoArchiveMinutes).
+			Msg("Validation error: invalid auto archive duration.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	// Create the thread and let the creator follow it. A concurrent request for the same
+	// message leaves the first thread in place.
+	created := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		newThread := models.Thread{
+			RootMessageID:      messageID,
+			Name:               request.Name,
+			CreatorID:          userID,
+			AutoArchiveMinutes: autoArchiveMinutes,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newThread)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		created = true
+		follower := models.ThreadFollower{ThreadID: messageID, UserID: userID}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follower).Error
+	})
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error creating thread due to a database issue", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_creating_thread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Str("message_id", messageID.String()).
+			Err(err).
+			Msg("Database error creating thread.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Re-fetch the thread to include the database defaults in the response.
+	var thread models.Thread
+	if err := db.First(&thread, "root_message_id = ?", messageID).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully created thread but failed to re-fetch", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_thread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching thread.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	following, err := isFollowing(db, messageID, userID)
+	if err != nil {
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_follow_state").
+			Str("thread_id", messageID.String()).
+			Err(err).
+			Msg("Could not fetch the follow state of the thread.")
+	}
+
+	if created {
+		apiResponse.Message = "Thread created successfully."
+	} else {
+		apiResponse.StatusCode = http.StatusOK
+		apiResponse.Message = "The message already has a thread."
+	}
+	apiResponse.Data = &models.ResponseData[models.ThreadPayload]{
+		Items: []models.ThreadPayload{threadPayloadFor(&thread, following)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "thread_created").
+		Str("thread_id", messageID.String()).
+		Str("creator_id", userID.String()).
+		Bool("created", created).
+		Msg("Successfully resolved thread creation.")
+
+	models.SendApiResponse(w, apiResponse)
+}
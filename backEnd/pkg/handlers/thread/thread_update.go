@@ -0,0 +1,272 @@
+package thread
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// threadUpdateRequest is the expected JSON body of a thread update request.
+// Omitted fields are left unchanged, a null name removes the name of the thread.
+type threadUpdateRequest struct {
+	Name               models.Nullable[string] `json:"name"`
+	Archived           *bool                   `json:"archived"`
+	AutoArchiveMinutes *int                    `json:"auto_archive_minutes"`
+}
+
+// ThreadUpdateHandler handles HTTP PATCH requests for updating a thread.
+// It expects the thread ID in the URL path and a JSON body with the new name, archived state
+// or auto archive duration. The creator of the thread and users with the manage messages
+// permission can update it. Reopening an archived thread restarts its inactivity timer.
+func ThreadUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	const (
+		COMPONENT      string = "thread_handler"
+		METHOD_NAME    string = "ThreadUpdateHandler"
+		CONTEXT        string = "api/threads/{id}"
+		METHOD         string = "PATCH"
+		STATUS_DEFAULT int    = http.StatusOK
+	)
+
+	apiResponse := &models.ApiResponse[models.ThreadPayload]{}
+	apiResponse.Method = METHOD
+	apiResponse.Context = CONTEXT
+	apiResponse.StatusCode = STATUS_DEFAULT
+
+	// Get the GORM database instance.
+	db := database.DB
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("http_method", METHOD).
+		Str("path", CONTEXT).
+		Str("event", "http_request_received").
+		Msg("Processing thread update request.")
+
+	// Check if the database connection is initialized.
+	if db == nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_INITIALIZE.ApiErrorResponse("Database not ready for ThreadUpdateHandler", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "db_not_initialized").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Database not initialized for thread update.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Get the authenticated user set by the auth middleware.
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Authentication required", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "unauthenticated").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Msg("Missing authenticated user.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Extract and parse the thread ID from the URL path.
+	vars := mux.Vars(r)
+	apiResponse.Params = map[string]interface{}{
+		"id": vars["id"],
+	}
+	threadID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_INVALID_INPUT.ApiErrorResponse("Invalid thread ID", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "invalid_id").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("id", vars["id"]).
+			Err(err).
+			Msg("Invalid thread ID in path.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Resolve the thread and the permissions of the caller in it.
+	target, err := permissions.ResolveThread(db, threadID, userID)
+	if err != nil {
+		apiResponse.Error = threadAccessError(err)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "thread_access_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("thread_id", threadID.String()).
+			Str("user_id", userID.String()).
+			Err(err).
+			Msg("Could not resolve thread access.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if target.Thread.CreatorID != userID && !target.Permissions.Has(models.PermissionManageMessages) {
+		apiResponse.Error = apierrors.ERROR_CODE_FORBIDDEN.ApiErrorResponse("Missing manage messages permission", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "permission_denied").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("thread_id", threadID.String()).
+			Str("user_id", userID.String()).
+			Msg("User is not allowed to update this thread.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Decode the JSON request body.
+	var request threadUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_ENCODE_ERROR.ApiErrorResponse("Invalid JSON data for update", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "request_body_decode_failed").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Err(err).
+			Msg("Error decoding request body.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	// Prepare a map of fields to update for GORM.
+	updateParams := make(map[string]interface{})
+	if request.Name.Set {
+		updateParams["name"] = request.Name.Value
+	}
+	if request.AutoArchiveMinutes != nil {
+		updateParams["auto_archive_minutes"] = *request.AutoArchiveMinutes
+	}
+	for k, v := range updateParams {
+		apiResponse.Params[k] = v
+	}
+	if request.Archived != nil {
+		apiResponse.Params["archived"] = *request.Archived
+		now := time.Now()
+		wasArchived := target.Thread.IsArchived(now)
+		switch {
+		case *request.Archived && target.Thread.ArchivedAt == nil:
+			updateParams["archived_at"] = now
+		case !*request.Archived && wasArchived:
+			updateParams["archived_at"] = nil
+			updateParams["reopened_at"] = now
+		}
+	}
+
+	// --- VALIDATION SECTION ---
+	if request.Name.Value != nil && !validation.ValidateThreadName(*request.Name.Value) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid thread name", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_name").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("name", *request.Name.Value).
+			Msg("Validation error: invalid thread name.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
+	if request.AutoArchiveMinutes != nil && !validation.ValidateAutoArchiveMinutes(*request.AutoArchiveMinutes) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Auto archive duration must be 60, 1440, 4320 or 10080 minutes", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_auto_archive").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Int("auto_archive_minutes", *request.AutoArchiveMinutes).
+			Msg("Validation error: invalid auto archive duration.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	// --- END VALIDATION SECTION ---
+
+	if len(updateParams) > 0 {
+		if err := db.Model(target.Thread).Updates(updateParams).Error; err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error updating thread due to a database issue", nil)
+			log.Error().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "database_error_updating_thread").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Err(err).
+				Msg("Database error updating thread.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+
+	// Re-fetch the thread for the response.
+	var updatedThread models.Thread
+	if err := db.First(&updatedThread, "root_message_id = ?", threadID).Error; err != nil {
+		apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Successfully updated thread but failed to re-fetch", nil)
+		log.Error().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_re_fetching_thread").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Err(err).
+			Msg("Database error re-fetching thread.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+	following, err := isFollowing(db, threadID, userID)
+	if err != nil {
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "database_error_fetching_follow_state").
+			Str("thread_id", threadID.String()).
+			Err(err).
+			Msg("Could not fetch the follow state of the thread.")
+	}
+
+	apiResponse.Message = "Thread updated successfully."
+	apiResponse.Data = &models.ResponseData[models.ThreadPayload]{
+		Items: []models.ThreadPayload{threadPayloadFor(&updatedThread, following)},
+	}
+
+	log.Info().
+		Str("component", COMPONENT).
+		Str("method_name", METHOD_NAME).
+		Str("event", "thread_updated_success").
+		Str("thread_id", threadID.String()).
+		Msg("Successfully updated thread.")
+
+	models.SendApiResponse(w, apiResponse)
+}
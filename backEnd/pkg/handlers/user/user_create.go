@@ -142,6 +142,22 @@ func UserCreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The privacy setting is optional, the database default applies when it is empty.
+	if newUser.DMPrivacy != "" && !validation.ValidateDMPrivacy(newUser.DMPrivacy) {
+		apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid direct message privacy setting", nil)
+		log.Warn().
+			Str("component", COMPONENT).
+			Str("method_name", METHOD_NAME).
+			Str("event", "validation_failed_invalid_dm_privacy").
+			Str("api_error_code", apiResponse.Error.Code).
+			Str("api_error_message", apiResponse.Error.Message).
+			Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+			Str("dmPrivacy", string(newUser.DMPrivacy)).
+			Msg("Validation error: invalid direct message privacy setting.")
+		models.SendApiResponse(w, apiResponse)
+		return
+	}
+
 	passwordHash, err := passwordHashing.HashPassword(newUser.Password)
 	if err != nil {
 		apiResponse.Error = apierrors.ERROR_CODE_INTERNAL_SERVER.ApiErrorResponse("Failed to hash password", nil)
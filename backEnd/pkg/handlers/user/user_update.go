@@ -9,6 +9,7 @@ import (
 
 	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
 	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
 	"github.com/413ksz/BlueFox/backEnd/pkg/models"
 	passwordHashing "github.com/413ksz/BlueFox/backEnd/pkg/password_hashing"
 	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
@@ -174,6 +175,9 @@ func UserUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	if updates.ProfilePictureAssetID != nil {
 		updateParams["profile_picture_asset_id"] = *updates.ProfilePictureAssetID
 	}
+	if updates.DMPrivacy != "" {
+		updateParams["dm_privacy"] = updates.DMPrivacy
+	}
 	// The `IsVerified` field is intentionally not handled here,
 	// preventing its update via this endpoint.
 
@@ -295,6 +299,49 @@ func UserUpdateHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	// Validate the direct message privacy setting if it was provided.
+	if _, ok := updateParams["dm_privacy"]; ok {
+		if !validation.ValidateDMPrivacy(updates.DMPrivacy) {
+			apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("Invalid direct message privacy setting", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_dm_privacy").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("dmPrivacy", string(updates.DMPrivacy)).
+				Msg("Validation error: invalid direct message privacy setting.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
+	// Validate the profile picture if it was provided, it must be an image of the avatar allowlist uploaded by the user.
+	if updates.ProfilePictureAssetID != nil {
+		var count int64
+		err := db.Model(&models.MediaAsset{}).
+			Where("id = ? AND uploaded_by_user_id = ? AND content_type IN ?", *updates.ProfilePictureAssetID, existingUser.ID, mediatype.Allowlist(mediatype.ContextAvatar)).
+			Count(&count).Error
+		if err != nil || count == 0 {
+			if err != nil {
+				apiResponse.Error = apierrors.ERROR_CODE_DATABASE_ERROR.ApiErrorResponse("Error fetching profile picture", nil)
+			} else {
+				apiResponse.Error = apierrors.ERROR_CODE_VALIDATION_FAILED.ApiErrorResponse("The profile picture must be a PNG, JPEG, GIF or WebP image uploaded by you", nil)
+			}
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "validation_failed_invalid_profile_picture").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("profilePictureAssetId", updates.ProfilePictureAssetID.String()).
+				Err(err).
+				Msg("Validation error: invalid profile picture.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+	}
 	// --- END VALIDATION SECTION ---
 
 	// Perform the database update using GORM's Updates method with the map.
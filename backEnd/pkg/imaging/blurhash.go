@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+// blurhashCharacters is the base 83 alphabet of blurhash strings.
+const blurhashCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// BlurHash computes the blurhash of an image, a short string clients decode into a blurred
+// placeholder (https://blurha.sh). Landscape images use 4x3 components and portrait ones 3x4.
+// The cost grows with the pixels, so the image is downscaled first.
+// params:
+// - img: The image, usually downscaled with Fit.
+// returns:
+// - string: The blurhash of the image.
+func BlurHash(img image.Image) string {
+	src := toRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	xComponents, yComponents := 4, 3
+	if height > width {
+		xComponents, yComponents = 3, 4
+	}
+
+	// The pixels are converted to linear RGB once, transparent pixels count as black.
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := src.Pix[y*src.Stride+x*4:]
+			linear[y*width+x] = [3]float64{srgbToLinear(pixel[0]), srgbToLinear(pixel[1]), srgbToLinear(pixel[2])}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := normalisation * math.Cos(math.Pi*float64(i*x)/float64(width)) * math.Cos(math.Pi*float64(j*y)/float64(height))
+					for c := range factor {
+						factor[c] += basis * linear[y*width+x][c]
+					}
+				}
+			}
+			for c := range factor {
+				factor[c] /= float64(width * height)
+			}
+			factors = append(factors, factor)
+		}
+	}
+
+	var hash strings.Builder
+	encodeBase83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+
+	maximum := 1.0
+	if len(factors) > 1 {
+		actual := 0.0
+		for _, factor := range factors[1:] {
+			for _, value := range factor {
+				actual = math.Max(actual, math.Abs(value))
+			}
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actual*166-0.5))))
+		maximum = float64(quantised+1) / 166
+		encodeBase83(&hash, quantised, 1)
+	} else {
+		encodeBase83(&hash, 0, 1)
+	}
+
+	dc := factors[0]
+	encodeBase83(&hash, linearToSrgb(dc[0])<<16+linearToSrgb(dc[1])<<8+linearToSrgb(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		value := 0
+		for _, component := range factor {
+			quantised := int(math.Max(0, math.Min(18, math.Floor(signPow(component/maximum, 0.5)*9+9.5))))
+			value = value*19 + quantised
+		}
+		encodeBase83(&hash, value, 2)
+	}
+	return hash.String()
+}
+
+// encodeBase83 writes value as length base 83 digits.
+func encodeBase83(hash *strings.Builder, value int, length int) {
+	for i := 1; i <= length; i++ {
+		digit := value / int(math.Pow(83, float64(length-i))) % 83
+		hash.WriteByte(blurhashCharacters[digit])
+	}
+}
+
+// srgbToLinear converts an sRGB channel to linear light.
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSrgb converts a linear light channel to sRGB.
+func linearToSrgb(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// signPow raises the magnitude of value to exp, keeping its sign.
+func signPow(value float64, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
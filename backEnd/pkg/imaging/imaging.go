@@ -0,0 +1,220 @@
+// Package imaging processes uploaded images in pure Go.
+//
+// Process strips the metadata of an image, EXIF with its GPS position, XMP, comments and text
+// chunks, so published images do not reveal where or with what they were taken. Metadata is
+// removed at the container level and the image data is copied unchanged, except for JPEG images
+// rotated by their EXIF orientation, which are re-encoded upright as the orientation is removed.
+// It also measures the image, generates thumbnails and computes a blurhash placeholder clients
+// show while the image loads. The standard library has no WebP decoder, so WebP images are
+// stripped and measured from their headers but get no thumbnails or blurhash.
+//
+// Processing runs in the background through a Pipeline, so uploads return before it finishes.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // Registers the GIF decoder for image.Decode.
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// MAX_PIXELS is the largest number of pixels of an image that is decoded, so images declaring
+	// huge dimensions in a small file cannot exhaust the memory.
+	MAX_PIXELS = 40_000_000
+	// JPEG_QUALITY is the quality JPEG images are re-encoded with when their orientation is applied.
+	JPEG_QUALITY = 90
+	// THUMBNAIL_JPEG_QUALITY is the quality of the JPEG thumbnails.
+	THUMBNAIL_JPEG_QUALITY = 80
+	// BLURHASH_SOURCE_SIZE is the longest side of the downscaled image the blurhash is computed from.
+	BLURHASH_SOURCE_SIZE = 32
+)
+
+// THUMBNAIL_SIZES are the longest sides of the generated thumbnails in pixels. Only the sizes
+// smaller than the image are generated.
+var THUMBNAIL_SIZES = []int{160, 320, 640}
+
+var (
+	// ErrUnsupported is returned for content types that are not processed.
+	ErrUnsupported = errors.New("unsupported image type")
+	// ErrInvalidImage is returned when an image is malformed.
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrTooLarge is returned when an image has more than MAX_PIXELS pixels.
+	ErrTooLarge = errors.New("image too large")
+)
+
+// Thumbnail is a downscaled version of an image.
+type Thumbnail struct {
+	// Size is the entry of THUMBNAIL_SIZES the thumbnail was generated for.
+	Size        int
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+// Result is a processed image.
+type Result struct {
+	// Data is the image without metadata.
+	Data []byte
+	// ContentType is the type of Data, the type of the original image.
+	ContentType string
+	Width       int
+	Height      int
+	// BlurHash is the placeholder of the image, empty for WebP images.
+	BlurHash string
+	// Thumbnails are ordered by size, none for WebP images.
+	Thumbnails []Thumbnail
+}
+
+// Supported reports whether images of a content type are processed.
+// params:
+// - contentType: The sniffed MIME type of the image.
+// returns:
+// - bool: True for PNG, JPEG, GIF and WebP images.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Process strips the metadata of an image, measures it, generates its thumbnails and computes its
+// blurhash.
+// params:
+// - contentType: The sniffed MIME type of the image.
+// - data: The content of the image.
+// returns:
+// - *Result: The processed image.
+// - error: ErrUnsupported, ErrInvalidImage, ErrTooLarge or the error decoding or encoding it.
+func Process(contentType string, data []byte) (*Result, error) {
+	if contentType == "image/webp" {
+		stripped, err := stripWebP(data)
+		if err != nil {
+			return nil, err
+		}
+		width, height, err := webpSize(stripped)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Data: stripped, ContentType: contentType, Width: width, Height: height}, nil
+	}
+
+	stripped, orientation, err := strip(contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, errors.Join(ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > MAX_PIXELS {
+		return nil, ErrTooLarge
+	}
+	// Animated GIF images are decoded to their first frame.
+	img, _, err := image.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, errors.Join(ErrInvalidImage, err)
+	}
+
+	if orientation > 1 {
+		img = Orient(img, orientation)
+		var buffer bytes.Buffer
+		if err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: JPEG_QUALITY}); err != nil {
+			return nil, err
+		}
+		stripped = buffer.Bytes()
+	}
+
+	bounds := img.Bounds()
+	result := &Result{
+		Data:        stripped,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		BlurHash:    BlurHash(Fit(img, BLURHASH_SOURCE_SIZE)),
+	}
+	for _, size := range THUMBNAIL_SIZES {
+		if size >= max(result.Width, result.Height) {
+			break
+		}
+		thumbnail, err := encodeThumbnail(contentType, Fit(img, size))
+		if err != nil {
+			return nil, err
+		}
+		thumbnail.Size = size
+		result.Thumbnails = append(result.Thumbnails, thumbnail)
+	}
+	return result, nil
+}
+
+// strip removes the metadata of a PNG, JPEG or GIF image, returning the EXIF orientation of JPEG images.
+func strip(contentType string, data []byte) ([]byte, int, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		stripped, err := stripPNG(data)
+		return stripped, 1, err
+	case "image/gif":
+		stripped, err := stripGIF(data)
+		return stripped, 1, err
+	}
+	return nil, 0, ErrUnsupported
+}
+
+// encodeThumbnail encodes a thumbnail as JPEG for JPEG images and as PNG for the others, which
+// can be transparent.
+func encodeThumbnail(contentType string, img image.Image) (Thumbnail, error) {
+	var buffer bytes.Buffer
+	thumbnail := Thumbnail{Width: img.Bounds().Dx(), Height: img.Bounds().Dy(), ContentType: "image/png"}
+	var err error
+	if contentType == "image/jpeg" {
+		thumbnail.ContentType = "image/jpeg"
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: THUMBNAIL_JPEG_QUALITY})
+	} else {
+		err = png.Encode(&buffer, img)
+	}
+	thumbnail.Data = buffer.Bytes()
+	return thumbnail, err
+}
+
+// Animated reports whether an image has more than one frame: GIF images with several images,
+// animated PNG images and WebP images with the animation flag. The frames are counted from the
+// structure of the file without decoding them.
+// params:
+// - contentType: The sniffed MIME type of the image.
+// - data: The content of the image.
+// returns:
+// - bool: True if the image is animated.
+// - error: ErrInvalidImage if the image is malformed.
+func Animated(contentType string, data []byte) (bool, error) {
+	switch contentType {
+	case "image/gif":
+		_, blocks, err := gifBlocks(data)
+		if err != nil {
+			return false, err
+		}
+		frames := 0
+		for _, block := range blocks {
+			if block.Image {
+				frames++
+			}
+		}
+		return frames > 1, nil
+	case "image/png":
+		return apngFrames(data) > 1, nil
+	case "image/webp":
+		if len(data) < 21 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+			return false, ErrInvalidImage
+		}
+		return string(data[12:16]) == "VP8X" && data[20]&0x02 != 0, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,361 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// secret is the location stored in the metadata of the test images.
+const secret = "GPS 47.4979N 19.0402E"
+
+// testImage creates an image of the given size with a horizontal gradient.
+func testImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / width), G: 128, B: uint8(y * 255 / height), A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment creates a JPEG APP1 segment with an EXIF orientation and the secret location.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, secret...)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithExif encodes a JPEG image and inserts an EXIF segment and a comment after its start.
+func jpegWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buffer bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buffer, img, nil))
+	data := buffer.Bytes()
+	comment := append([]byte{0xFF, 0xFE, 0x00, byte(len(secret) + 2)}, secret...)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(orientation)...)
+	out = append(out, comment...)
+	return append(out, data[2:]...)
+}
+
+// TestProcessJPEG tests stripping the EXIF data of JPEG images and applying their orientation.
+func TestProcessJPEG(t *testing.T) {
+	tests := []struct {
+		name        string
+		orientation uint16
+		wantWidth   int
+		wantHeight  int
+	}{
+		{"Upright", 1, 800, 600},
+		{"Rotated clockwise", 6, 600, 800},
+		{"Rotated 180°", 3, 800, 600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := jpegWithExif(t, testImage(800, 600), tt.orientation)
+
+			result, err := imaging.Process("image/jpeg", data)
+			require.NoError(t, err)
+			assert.NotContains(t, string(result.Data), secret)
+			assert.NotContains(t, string(result.Data), "Exif")
+			assert.Equal(t, tt.wantWidth, result.Width)
+			assert.Equal(t, tt.wantHeight, result.Height)
+
+			config, err := jpeg.DecodeConfig(bytes.NewReader(result.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWidth, config.Width)
+			assert.Equal(t, tt.wantHeight, config.Height)
+
+			require.Len(t, result.Thumbnails, len(imaging.THUMBNAIL_SIZES))
+			for i, thumbnail := range result.Thumbnails {
+				assert.Equal(t, imaging.THUMBNAIL_SIZES[i], thumbnail.Size)
+				assert.Equal(t, thumbnail.Size, max(thumbnail.Width, thumbnail.Height))
+				assert.Equal(t, "image/jpeg", thumbnail.ContentType)
+				_, err := jpeg.Decode(bytes.NewReader(thumbnail.Data))
+				assert.NoError(t, err)
+			}
+			assert.Len(t, result.BlurHash, 28)
+		})
+	}
+}
+
+// TestProcessJPEGKeepsImageData tests that upright JPEG images are not re-encoded.
+func TestProcessJPEGKeepsImageData(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buffer, testImage(64, 48), nil))
+	original := buffer.Bytes()
+
+	result, err := imaging.Process("image/jpeg", jpegWithExif(t, testImage(64, 48), 1))
+	require.NoError(t, err)
+	assert.Equal(t, original, result.Data)
+	assert.Empty(t, result.Thumbnails)
+}
+
+// TestProcessPNG tests stripping the text chunks of PNG images.
+func TestProcessPNG(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, png.Encode(&buffer, testImage(400, 200)))
+	data := buffer.Bytes()
+	// Insert a text chunk after the header chunk, the CRC is not checked for dropped chunks.
+	text := binary.BigEndian.AppendUint32(nil, uint32(len(secret)+8))
+	text = append(text, "tEXtComment\x00"...)
+	text = append(text, secret...)
+	text = append(text, 0, 0, 0, 0)
+	headerEnd := 8 + 12 + 13
+	data = append(append(append([]byte{}, data[:headerEnd]...), text...), data[headerEnd:]...)
+
+	result, err := imaging.Process("image/png", data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Data), secret)
+	assert.Equal(t, buffer.Bytes(), result.Data)
+	assert.Equal(t, 400, result.Width)
+	assert.Equal(t, 200, result.Height)
+	require.Len(t, result.Thumbnails, 2)
+	assert.Equal(t, "image/png", result.Thumbnails[0].ContentType)
+	assert.Equal(t, 160, result.Thumbnails[0].Width)
+	assert.Equal(t, 80, result.Thumbnails[0].Height)
+}
+
+// TestProcessGIF tests stripping the comments of GIF images.
+func TestProcessGIF(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 200, 100), color.Palette{color.Black, color.White})
+	var buffer bytes.Buffer
+	require.NoError(t, gif.Encode(&buffer, img, nil))
+	data := buffer.Bytes()
+	comment := append([]byte{0x21, 0xFE, byte(len(secret))}, secret...)
+	comment = append(comment, 0)
+	data = append(append(append([]byte{}, data[:len(data)-1]...), comment...), 0x3B)
+
+	result, err := imaging.Process("image/gif", data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Data), secret)
+	assert.Equal(t, buffer.Bytes(), result.Data)
+	assert.Equal(t, 200, result.Width)
+	assert.Equal(t, 100, result.Height)
+	assert.Len(t, result.Thumbnails, 1)
+}
+
+// TestProcessWebP tests stripping the EXIF chunk of WebP images and reading their size.
+func TestProcessWebP(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		out := binary.LittleEndian.AppendUint32([]byte(fourCC), uint32(len(payload)))
+		out = append(out, payload...)
+		if len(payload)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	// Extended header with the EXIF flag and a 300x150 canvas.
+	vp8x := []byte{0x08, 0, 0, 0, 43, 1, 0, 149, 0, 0}
+	vp8l := []byte{0x2F, 0, 0, 0, 0}
+	body := append([]byte("WEBP"), chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", vp8l)...)
+	body = append(body, chunk("EXIF", []byte(secret))...)
+	data := append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+
+	result, err := imaging.Process("image/webp", data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Data), secret)
+	assert.Equal(t, byte(0), result.Data[20]&0x08)
+	assert.Equal(t, uint32(len(result.Data)-8), binary.LittleEndian.Uint32(result.Data[4:]))
+	assert.Equal(t, 300, result.Width)
+	assert.Equal(t, 150, result.Height)
+	assert.Empty(t, result.BlurHash)
+	assert.Empty(t, result.Thumbnails)
+}
+
+// TestProcessInvalid tests rejecting malformed and unsupported images.
+func TestProcessInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		want        error
+	}{
+		{"Truncated JPEG", "image/jpeg", "\xff\xd8\xff\xe1\x00\x40Exif", imaging.ErrInvalidImage},
+		{"Truncated PNG", "image/png", "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR", imaging.ErrInvalidImage},
+		{"Not a GIF", "image/gif", "GIF00a", imaging.ErrInvalidImage},
+		{"Not a WebP", "image/webp", "RIFF\x00\x00\x00\x00WAVE", imaging.ErrInvalidImage},
+		{"Unsupported type", "image/bmp", "BM", imaging.ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := imaging.Process(tt.contentType, []byte(tt.data))
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+// TestOrient tests applying EXIF orientations to a red and blue 2x1 image.
+func TestOrient(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		want        []color.RGBA
+		wantWidth   int
+	}{
+		{1, []color.RGBA{red, blue}, 2},
+		{2, []color.RGBA{blue, red}, 2},
+		{3, []color.RGBA{blue, red}, 2},
+		{4, []color.RGBA{red, blue}, 2},
+		{5, []color.RGBA{red, blue}, 1},
+		{6, []color.RGBA{red, blue}, 1},
+		{7, []color.RGBA{blue, red}, 1},
+		{8, []color.RGBA{blue, red}, 1},
+	}
+	for _, tt := range tests {
+		oriented := imaging.Orient(img, tt.orientation)
+		assert.Equal(t, tt.wantWidth, oriented.Bounds().Dx(), "orientation %d", tt.orientation)
+		var got []color.RGBA
+		for y := 0; y < oriented.Bounds().Dy(); y++ {
+			for x := 0; x < oriented.Bounds().Dx(); x++ {
+				got = append(got, color.RGBAModel.Convert(oriented.At(x, y)).(color.RGBA))
+			}
+		}
+		assert.Equal(t, tt.want, got, "orientation %d", tt.orientation)
+	}
+}
+
+// TestFit tests downscaling images to a longest side.
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height, size   int
+		wantWidth, wantHeight int
+	}{
+		{"Landscape", 1000, 500, 320, 320, 160},
+		{"Portrait", 300, 900, 160, 53, 160},
+		{"Smaller than the size", 100, 50, 160, 100, 50},
+		{"Thin", 2000, 1, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds := imaging.Fit(testImage(tt.width, tt.height), tt.size).Bounds()
+			assert.Equal(t, tt.wantWidth, bounds.Dx())
+			assert.Equal(t, tt.wantHeight, bounds.Dy())
+		})
+	}
+}
+
+// TestResizeAverages tests that downscaling averages the covered pixels.
+func TestResizeAverages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{A: 255})
+	img.Set(0, 1, color.RGBA{R: 255, A: 255})
+	img.Set(1, 1, color.RGBA{A: 255})
+
+	assert.Equal(t, color.RGBA{R: 128, A: 255}, imaging.Resize(img, 1, 1).RGBAAt(0, 0))
+}
+
+// TestBlurHash tests the structure of blurhashes and the encoding of the average color.
+func TestBlurHash(t *testing.T) {
+	const characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+	solid := func(width int, height int, c color.RGBA) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+		return img
+	}
+	decode := func(s string) int {
+		value := 0
+		for _, r := range s {
+			value = value*83 + strings.IndexRune(characters, r)
+		}
+		return value
+	}
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		wantSize byte
+		wantDC   int
+	}{
+		{"Landscape white", solid(32, 24, color.RGBA{255, 255, 255, 255}), 'L', 0xFFFFFF},
+		{"Portrait orange", solid(24, 32, color.RGBA{255, 128, 0, 255}), 'T', 0xFF8000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := imaging.BlurHash(tt.img)
+			assert.Len(t, hash, 28)
+			assert.Equal(t, tt.wantSize, hash[0])
+			assert.Equal(t, tt.wantDC, decode(hash[2:6]))
+			for _, r := range hash {
+				assert.Contains(t, characters, string(r))
+			}
+		})
+	}
+}
+
+// TestAnimated tests counting the frames of images without decoding them.
+func TestAnimated(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	encodeGIF := func(frames int) []byte {
+		animation := &gif.GIF{}
+		for n := 0; n < frames; n++ {
+			animation.Image = append(animation.Image, frame)
+			animation.Delay = append(animation.Delay, 10)
+		}
+		var buffer bytes.Buffer
+		require.NoError(t, gif.EncodeAll(&buffer, animation))
+		return buffer.Bytes()
+	}
+	var stillPNG bytes.Buffer
+	require.NoError(t, png.Encode(&stillPNG, frame))
+	// An animation control chunk with two frames inserted after the header chunk.
+	actl := append(binary.BigEndian.AppendUint32(nil, 8), "acTL\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00"...)
+	headerEnd := 8 + 12 + 13
+	animatedPNG := append(append(append([]byte{}, stillPNG.Bytes()[:headerEnd]...), actl...), stillPNG.Bytes()[headerEnd:]...)
+	webp := func(flags byte) []byte {
+		return []byte("RIFF\x16\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00" + string([]byte{flags}) + "\x00\x00\x00\x03\x00\x00\x03\x00\x00")
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+		want        bool
+	}{
+		{"Still GIF", "image/gif", encodeGIF(1), false},
+		{"Animated GIF", "image/gif", encodeGIF(3), true},
+		{"Still PNG", "image/png", stillPNG.Bytes(), false},
+		{"Animated PNG", "image/png", animatedPNG, true},
+		{"Still WebP", "image/webp", webp(0x00), false},
+		{"Animated WebP", "image/webp", webp(0x02), true},
+		{"JPEG", "image/jpeg", []byte("\xff\xd8"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := imaging.Animated(tt.contentType, tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
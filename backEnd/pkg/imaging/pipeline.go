@@ -0,0 +1,239 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/jobs"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/storage"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// WORKERS is the number of images processed at the same time by an instance.
+	WORKERS = 2
+	// QUEUE_SIZE is the number of images that can wait for a worker, the others wait for the
+	// recovery of the queue.
+	QUEUE_SIZE = 256
+	// RETRY_DELAY is how long a pending image waits after its upload or its last attempt before the
+	// recovery of the queue enqueues it again.
+	RETRY_DELAY = 30 * time.Second
+	// STALE_AFTER is how long an image can stay claimed before another worker retries it, in case
+	// the worker that claimed it stopped.
+	STALE_AFTER = 10 * time.Minute
+	// MAX_FILE_SIZE is the largest image processed in bytes, the whole image is held in memory.
+	MAX_FILE_SIZE = validation.MEDIA_MAX_FILE_SIZE
+)
+
+// DefaultPipeline is the pipeline the API handlers enqueue uploaded images to, nil until the
+// application sets it.
+var DefaultPipeline *Pipeline
+
+// Pipeline processes the images of media assets in the background. The processing status of the
+// asset is the durable state of the job: an asset is claimed by setting it to processing, so
+// instances sharing the database do not process it twice, and it is served once ready. Pending
+// assets are enqueued again every jobs.RECOVER_INTERVAL, as are the ones whose worker stopped. The
+// stripped image replaces the original file, which is deleted unless another asset references it.
+type Pipeline struct {
+	db    *gorm.DB
+	store storage.Storage
+	queue *jobs.Queue
+}
+
+// NewPipeline creates a pipeline, its workers are started with Start.
+// params:
+// - db: The GORM database instance holding the media assets.
+// - store: The storage holding their files.
+// returns:
+// - *Pipeline: The pipeline.
+func NewPipeline(db *gorm.DB, store storage.Storage) *Pipeline {
+	p := &Pipeline{db: db, store: store}
+	p.queue = jobs.NewQueue("imaging", QUEUE_SIZE, p.process)
+	p.queue.Pending = p.pending
+	return p
+}
+
+// Start starts the workers of the pipeline and the recovery of the pending images.
+// params:
+// - ctx: The context of the workers, processing stops when it is done.
+// - workers: The number of images processed at the same time, usually WORKERS.
+func (p *Pipeline) Start(ctx context.Context, workers int) {
+	p.queue.Start(ctx, workers)
+}
+
+// Enqueue schedules the processing of a media asset whose status is pending.
+// params:
+// - assetID: The ID of the media asset.
+func (p *Pipeline) Enqueue(assetID uuid.UUID) {
+	p.queue.Enqueue(assetID)
+}
+
+// pending lists the pending media assets and the ones whose worker stopped, left by a restart, a
+// full queue, a failed attempt or a serverless instance frozen before it finished. Pending assets
+// are only listed RETRY_DELAY after their upload or last attempt, so the ones in the queue are not
+// listed again right away and failing ones do not hold back the others.
+func (p *Pipeline) pending(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	now := time.Now()
+	var ids []uuid.UUID
+	err := p.db.WithContext(ctx).Model(&models.MediaAsset{}).
+		Where("(processing_status = ? AND COALESCE(processing_started_at, created_at) < ?) OR (processing_status = ? AND processing_started_at < ?)",
+			models.ProcessingStatusPending, now.Add(-RETRY_DELAY), models.ProcessingStatusProcessing, now.Add(-STALE_AFTER)).
+		Order("COALESCE(processing_started_at, created_at)").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// process claims and processes the image of a media asset. Images that cannot be processed or
+// whose file is missing are marked failed, the asset is released for a retry after RETRY_DELAY
+// when the database or the storage fails.
+func (p *Pipeline) process(ctx context.Context, assetID uuid.UUID) error {
+	now := time.Now()
+	claim := p.db.WithContext(ctx).Model(&models.MediaAsset{}).
+		Where("id = ? AND (processing_status = ? OR (processing_status = ? AND processing_started_at < ?))",
+			assetID, models.ProcessingStatusPending, models.ProcessingStatusProcessing, now.Add(-STALE_AFTER)).
+		Updates(map[string]interface{}{"processing_status": models.ProcessingStatusProcessing, "processing_started_at": now})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		// Processed already or claimed by another worker.
+		return nil
+	}
+
+	var asset models.MediaAsset
+	err := p.db.WithContext(ctx).First(&asset, "id = ?", assetID).Error
+	if err == nil {
+		err = p.run(ctx, &asset)
+	}
+	if err != nil {
+		status := models.ProcessingStatusPending
+		if errors.Is(err, ErrUnsupported) || errors.Is(err, ErrInvalidImage) || errors.Is(err, ErrTooLarge) || errors.Is(err, storage.ErrNotFound) {
+			status = models.ProcessingStatusFailed
+		}
+		if updateErr := p.db.Model(&models.MediaAsset{}).Where("id = ?", assetID).Update("processing_status", status).Error; updateErr != nil {
+			err = errors.Join(err, updateErr)
+		}
+		return err
+	}
+
+	log.Info().
+		Str("component", "imaging").
+		Str("method_name", "process").
+		Str("event", "image_processed").
+		Str("media_asset_id", asset.ID.String()).
+		Int("width", asset.Width).
+		Int("height", asset.Height).
+		Int("thumbnails", len(asset.Thumbnails)).
+		Msg("Successfully processed image.")
+	return nil
+}
+
+// run processes the image of a claimed media asset, stores the stripped image and its thumbnails
+// and marks the asset ready.
+func (p *Pipeline) run(ctx context.Context, asset *models.MediaAsset) error {
+	if !Supported(asset.ContentType) {
+		return ErrUnsupported
+	}
+	if asset.FileSize > MAX_FILE_SIZE {
+		return ErrTooLarge
+	}
+	file, err := p.store.Open(ctx, asset.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(file, MAX_FILE_SIZE+1))
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if len(data) > MAX_FILE_SIZE {
+		return ErrTooLarge
+	}
+
+	result, err := Process(asset.ContentType, data)
+	if err != nil {
+		return err
+	}
+	object, err := p.store.Put(ctx, bytes.NewReader(result.Data))
+	if err != nil {
+		return err
+	}
+	thumbnails := make([]models.MediaThumbnail, 0, len(result.Thumbnails))
+	for _, thumbnail := range result.Thumbnails {
+		stored, err := p.store.Put(ctx, bytes.NewReader(thumbnail.Data))
+		if err != nil {
+			return err
+		}
+		thumbnails = append(thumbnails, models.MediaThumbnail{
+			MediaAssetID: asset.ID,
+			Size:         thumbnail.Size,
+			Width:        thumbnail.Width,
+			Height:       thumbnail.Height,
+			FileSize:     int(stored.Size),
+			ContentType:  thumbnail.ContentType,
+			StorageKey:   stored.Key,
+		})
+	}
+
+	original := asset.StorageKey
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Thumbnails of an earlier attempt are replaced.
+		if err := tx.Where("media_asset_id = ?", asset.ID).Delete(&models.MediaThumbnail{}).Error; err != nil {
+			return err
+		}
+		if len(thumbnails) > 0 {
+			if err := tx.Create(&thumbnails).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(asset).Updates(map[string]interface{}{
+			"storage_key":           object.Key,
+			"file_size":             int(object.Size),
+			"width":                 result.Width,
+			"height":                result.Height,
+			"blur_hash":             result.BlurHash,
+			"processing_status":     models.ProcessingStatusReady,
+			"processing_started_at": nil,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	asset.Thumbnails = thumbnails
+
+	if original != object.Key {
+		p.deleteUnreferenced(ctx, original)
+	}
+	return nil
+}
+
+// deleteUnreferenced deletes the original file of a processed image unless an identical upload
+// references it. An identical upload racing with the check loses its file, its processing then
+// fails and it is not served, which is preferred to keeping the metadata around.
+func (p *Pipeline) deleteUnreferenced(ctx context.Context, key string) {
+	var references int64
+	err := p.db.WithContext(ctx).Model(&models.MediaAsset{}).Where("storage_key = ?", key).Count(&references).Error
+	if err == nil && references == 0 {
+		err = p.db.WithContext(ctx).Model(&models.MediaThumbnail{}).Where("storage_key = ?", key).Count(&references).Error
+	}
+	if err == nil && references == 0 {
+		err = p.store.Delete(ctx, key)
+	}
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Warn().
+			Str("component", "imaging").
+			Str("method_name", "deleteUnreferenced").
+			Str("event", "original_delete_failed").
+			Str("storage_key", key).
+			Err(err).
+			Msg("Could not delete the original file of a processed image.")
+	}
+}
@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Fit downscales an image so its longest side is at most size pixels, keeping its aspect ratio.
+// Each pixel is the average of the source pixels it covers. Smaller images are returned unchanged.
+// params:
+// - img: The image to downscale.
+// - size: The longest side of the result in pixels.
+// returns:
+// - image.Image: The downscaled image.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	return Resize(img, width, height)
+}
+
+// Resize downscales an image to the given dimensions with a box filter.
+// params:
+// - img: The image to downscale.
+// - width: The width of the result, at most the width of the image.
+// - height: The height of the result, at most the height of the image.
+// returns:
+// - *image.RGBA: The downscaled image.
+func Resize(img image.Image, width int, height int) *image.RGBA {
+	src := toRGBA(img)
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint64(pixel[0])
+					g += uint64(pixel[1])
+					b += uint64(pixel[2])
+					a += uint64(pixel[3])
+					n++
+				}
+			}
+			// The pixels are premultiplied by their alpha, so they are averaged directly.
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8((r + n/2) / n)
+			dst.Pix[offset+1] = uint8((g + n/2) / n)
+			dst.Pix[offset+2] = uint8((b + n/2) / n)
+			dst.Pix[offset+3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
+
+// Orient applies an EXIF orientation to an image, so it is displayed upright without it.
+// params:
+// - img: The image as stored.
+// - orientation: The EXIF orientation, 1 to 8.
+// returns:
+// - image.Image: The upright image, img itself for orientation 1.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	src := toRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	// Orientations 5 to 8 swap the sides.
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally.
+				sx, sy = width-1-x, y
+			case 3: // Rotated 180°.
+				sx, sy = width-1-x, height-1-y
+			case 4: // Mirrored vertically.
+				sx, sy = x, height-1-y
+			case 5: // Transposed.
+				sx, sy = y, x
+			case 6: // Rotated 90° clockwise.
+				sx, sy = y, height-1-x
+			case 7: // Transversed.
+				sx, sy = width-1-y, height-1-x
+			case 8: // Rotated 90° counterclockwise.
+				sx, sy = width-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:sy*src.Stride+sx*4+4])
+		}
+	}
+	return dst
+}
+
+// toRGBA converts an image to RGBA with its origin at 0,0.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
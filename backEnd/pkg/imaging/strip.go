@@ -0,0 +1,308 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// jpegKeptApps are the application segments kept in JPEG files, identified by their marker and
+// the prefix of their data: the JFIF header, ICC color profiles and the Adobe color transform.
+var jpegKeptApps = map[byte][]byte{
+	0xE0: []byte("JFIF\x00"),
+	0xE2: []byte("ICC_PROFILE\x00"),
+	0xEE: []byte("Adobe"),
+}
+
+// stripJPEG removes the EXIF, XMP, comment and other application segments of a JPEG file without
+// re-encoding it, and returns the EXIF orientation the image is displayed with.
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, ErrInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientation := 1
+
+	for i := 2; ; {
+		// Markers can be preceded by any number of fill bytes.
+		for i < len(data) && data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0xFF {
+			i++
+		}
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, 0, ErrInvalidImage
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, 0, ErrInvalidImage
+		}
+		segment := data[i : i+2+length]
+		payload := segment[4:]
+
+		switch {
+		case marker == 0xDA:
+			// The entropy-coded data follows the start of scan, the rest of the file is image data.
+			return append(out, data[i:]...), orientation, nil
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")):
+			orientation = exifOrientation(payload[6:])
+		case marker >= 0xE0 && marker <= 0xEF:
+			if prefix, ok := jpegKeptApps[marker]; ok && bytes.HasPrefix(payload, prefix) {
+				out = append(out, segment...)
+			}
+		case marker == 0xFE:
+			// Comments are dropped.
+		default:
+			out = append(out, segment...)
+		}
+		i += 2 + length
+	}
+}
+
+// exifOrientation reads the orientation tag of the first IFD of EXIF data, 1 if it is missing.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// The orientation is a SHORT stored in the value field of its entry.
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// pngDroppedChunks are the PNG chunks holding metadata: EXIF, text and the modification time.
+var pngDroppedChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG removes the metadata chunks of a PNG file, the other chunks are copied unchanged.
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, ErrInvalidImage
+		}
+		chunk := string(data[i+4 : i+8])
+		if !pngDroppedChunks[chunk] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if chunk == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, ErrInvalidImage
+}
+
+// gifKeptApplications are the application extensions kept in GIF files, which set the loop count.
+var gifKeptApplications = [][]byte{[]byte("NETSCAPE2.0"), []byte("ANIMEXTS1.0")}
+
+// gifBlock is a block of a GIF file: an image with its descriptor, or an extension.
+type gifBlock struct {
+	// Data is the whole block, from its introducer to its terminator.
+	Data []byte
+	// Image is set for images, Label is the label of extensions.
+	Image bool
+	Label byte
+}
+
+// gifBlocks splits a GIF file into its header, with the logical screen descriptor and the global
+// color table, and its blocks, without the trailer.
+func gifBlocks(data []byte) ([]byte, []gifBlock, error) {
+	if len(data) < 13 || !(bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))) {
+		return nil, nil, ErrInvalidImage
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+	if i > len(data) {
+		return nil, nil, ErrInvalidImage
+	}
+	header := data[:i]
+
+	var blocks []gifBlock
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case 0x3B:
+			return header, blocks, nil
+		case 0x2C:
+			// Image descriptor, local color table, LZW minimum code size and image data sub-blocks.
+			if i+10 > len(data) {
+				return nil, nil, ErrInvalidImage
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			end, ok := skipSubBlocks(data, i+1)
+			if !ok {
+				return nil, nil, ErrInvalidImage
+			}
+			blocks = append(blocks, gifBlock{Data: data[start:end], Image: true})
+			i = end
+		case 0x21:
+			if i+2 > len(data) {
+				return nil, nil, ErrInvalidImage
+			}
+			end, ok := skipSubBlocks(data, i+2)
+			if !ok {
+				return nil, nil, ErrInvalidImage
+			}
+			blocks = append(blocks, gifBlock{Data: data[start:end], Label: data[i+1]})
+			i = end
+		default:
+			return nil, nil, ErrInvalidImage
+		}
+	}
+	return nil, nil, ErrInvalidImage
+}
+
+// stripGIF removes the comment and application extensions of a GIF file, except the ones setting
+// the loop count of animations.
+func stripGIF(data []byte) ([]byte, error) {
+	header, blocks, err := gifBlocks(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, header...)
+	for _, block := range blocks {
+		keep := block.Image || (block.Label != 0xFE && block.Label != 0xFF)
+		if block.Label == 0xFF && len(block.Data) > 3 {
+			identifier := block.Data[3:min(3+int(block.Data[2]), len(block.Data))]
+			for _, kept := range gifKeptApplications {
+				keep = keep || bytes.Equal(identifier, kept)
+			}
+		}
+		if keep {
+			out = append(out, block.Data...)
+		}
+	}
+	return append(out, 0x3B), nil
+}
+
+// skipSubBlocks returns the index after the data sub-blocks starting at i and their terminator.
+func skipSubBlocks(data []byte, i int) (int, bool) {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, true
+		}
+		i += size
+	}
+	return 0, false
+}
+
+// stripWebP removes the EXIF and XMP chunks of a WebP file and clears their flags in the extended
+// header, the image chunks are copied unchanged.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size&1
+		if end > len(data) || end < i {
+			return nil, ErrInvalidImage
+		}
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// webpSize reads the dimensions of a WebP image from its first image or extended header chunk.
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, ErrInvalidImage
+	}
+	payload := data[20:]
+	switch string(data[12:16]) {
+	case "VP8X":
+		width := int(payload[4]) | int(payload[5])<<8 | int(payload[6])<<16
+		height := int(payload[7]) | int(payload[8])<<8 | int(payload[9])<<16
+		return width + 1, height + 1, nil
+	case "VP8 ":
+		if payload[3] != 0x9D || payload[4] != 0x01 || payload[5] != 0x2A {
+			return 0, 0, ErrInvalidImage
+		}
+		width := int(binary.LittleEndian.Uint16(payload[6:])) & 0x3FFF
+		height := int(binary.LittleEndian.Uint16(payload[8:])) & 0x3FFF
+		return width, height, nil
+	case "VP8L":
+		if payload[0] != 0x2F {
+			return 0, 0, ErrInvalidImage
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, nil
+	}
+	return 0, 0, ErrInvalidImage
+}
+
+// apngFrames returns the number of frames of an animated PNG image from its animation control
+// chunk, 1 for still images.
+func apngFrames(data []byte) int {
+	for i := 8; i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunk := string(data[i+4 : i+8])
+		if chunk == "acTL" && length >= 8 && i+16 <= len(data) {
+			return int(binary.BigEndian.Uint32(data[i+8:]))
+		}
+		if chunk == "IDAT" || length < 0 || i+12+length > len(data) {
+			return 1
+		}
+		i += 12 + length
+	}
+	return 1
+}
@@ -0,0 +1,171 @@
+// Package jobs runs background work outside of the request that triggered it.
+//
+// A Queue holds the IDs of pending jobs in memory and a fixed number of workers run them, so
+// handlers enqueue slow work and respond at once. The queue is not durable: the state of each job
+// is kept in the database by its handler. A queue with a Pending function lists the jobs pending
+// there when it starts and every RecoverInterval, and enqueues them again, so the jobs left by a
+// restart, dropped by a full queue or released after a failure are run eventually.
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// RECOVER_INTERVAL is how often a queue enqueues its pending jobs again by default.
+const RECOVER_INTERVAL = time.Minute
+
+// Handler runs the job with an ID. The errors it returns are logged.
+type Handler func(ctx context.Context, id uuid.UUID) error
+
+// Lister returns the IDs of at most limit jobs pending in the database, the longest waiting first.
+type Lister func(ctx context.Context, limit int) ([]uuid.UUID, error)
+
+// Queue runs jobs with a fixed number of workers.
+type Queue struct {
+	// Pending lists the pending jobs to enqueue again, nil if the jobs are only run once enqueued.
+	// It is only listed up to the free space of the queue.
+	Pending Lister
+	// RecoverInterval is how often the pending jobs are enqueued again.
+	RecoverInterval time.Duration
+
+	name    string
+	handler Handler
+	pending chan uuid.UUID
+
+	mu      sync.Mutex
+	started bool
+	wg      sync.WaitGroup
+}
+
+// NewQueue creates a queue, its workers are started with Start.
+// params:
+// - name: The name of the queue, for the logs.
+// - size: The number of jobs that can wait for a worker.
+// - handler: The function running a job.
+// returns:
+// - *Queue: The queue.
+func NewQueue(name string, size int, handler Handler) *Queue {
+	return &Queue{
+		RecoverInterval: RECOVER_INTERVAL,
+		name:            name,
+		handler:         handler,
+		pending:         make(chan uuid.UUID, size),
+	}
+}
+
+// Start starts the workers, which run jobs until the context is done, and the recovery of the
+// pending jobs if Pending is set. Starting a started queue does nothing.
+// params:
+// - ctx: The context of the workers, passed to the handler.
+// - workers: The number of jobs run at the same time.
+func (q *Queue) Start(ctx context.Context, workers int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+	for n := 0; n < workers; n++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	if q.Pending != nil {
+		q.wg.Add(1)
+		go q.recover(ctx)
+	}
+}
+
+// Enqueue adds a job without blocking.
+// params:
+// - id: The ID of the job.
+// returns:
+// - bool: False if the queue is full, the job stays pending until it is enqueued again.
+func (q *Queue) Enqueue(id uuid.UUID) bool {
+	select {
+	case q.pending <- id:
+		return true
+	default:
+		log.Warn().
+			Str("component", "jobs").
+			Str("method_name", "Enqueue").
+			Str("event", "queue_full").
+			Str("queue", q.name).
+			Str("job_id", id.String()).
+			Msg("Job queue is full, the job stays pending.")
+		return false
+	}
+}
+
+// Wait blocks until the workers have stopped after their context is done.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// work runs the pending jobs one at a time until the context is done.
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-q.pending:
+			if err := q.handler(ctx, id); err != nil {
+				log.Error().
+					Str("component", "jobs").
+					Str("method_name", "work").
+					Str("event", "job_failed").
+					Str("queue", q.name).
+					Str("job_id", id.String()).
+					Err(err).
+					Msg("Job failed.")
+			}
+		}
+	}
+}
+
+// recover enqueues the pending jobs right away and every RecoverInterval until the context is done.
+func (q *Queue) recover(ctx context.Context) {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.RecoverInterval)
+	defer ticker.Stop()
+	for {
+		q.enqueuePending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueuePending enqueues as many pending jobs as the queue has room for. Jobs that are queued
+// already can be listed again, their handler runs them once.
+func (q *Queue) enqueuePending(ctx context.Context) {
+	free := cap(q.pending) - len(q.pending)
+	if free == 0 {
+		return
+	}
+	ids, err := q.Pending(ctx, free)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().
+				Str("component", "jobs").
+				Str("method_name", "recover").
+				Str("event", "pending_list_failed").
+				Str("queue", q.name).
+				Err(err).
+				Msg("Could not list the pending jobs.")
+		}
+		return
+	}
+	for _, id := range ids {
+		if !q.Enqueue(id) {
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/jobs"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestQueueRunsJobs tests that the workers run every enqueued job, including failing ones.
+func TestQueueRunsJobs(t *testing.T) {
+	var mu sync.Mutex
+	ran := make(map[uuid.UUID]bool)
+	done := make(chan struct{}, 10)
+	queue := jobs.NewQueue("test", 10, func(ctx context.Context, id uuid.UUID) error {
+		mu.Lock()
+		ran[id] = true
+		mu.Unlock()
+		done <- struct{}{}
+		return errors.New("failed")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.Start(ctx, 3)
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range ids {
+		assert.True(t, queue.Enqueue(id))
+	}
+	for range ids {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("jobs were not run")
+		}
+	}
+	cancel()
+	queue.Wait()
+
+	for _, id := range ids {
+		assert.True(t, ran[id], "job %s was not run", id)
+	}
+}
+
+// TestQueueFull tests that Enqueue does not block when no worker takes the jobs.
+func TestQueueFull(t *testing.T) {
+	queue := jobs.NewQueue("test", 2, func(ctx context.Context, id uuid.UUID) error { return nil })
+
+	assert.True(t, queue.Enqueue(uuid.New()))
+	assert.True(t, queue.Enqueue(uuid.New()))
+	assert.False(t, queue.Enqueue(uuid.New()))
+}
+
+// pendingStub keeps the state of jobs like a database, a job stays pending until it succeeds.
+type pendingStub struct {
+	mu       sync.Mutex
+	pending  []uuid.UUID
+	attempts map[uuid.UUID]int
+}
+
+func (s *pendingStub) list(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uuid.UUID(nil), s.pending[:min(limit, len(s.pending))]...), nil
+}
+
+// run fails the first attempt of every job, releasing it, and completes the second one.
+func (s *pendingStub) run(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.pending, id) {
+		return nil
+	}
+	s.attempts[id]++
+	if s.attempts[id] == 1 {
+		return errors.New("transient failure")
+	}
+	s.pending = slices.DeleteFunc(s.pending, func(pending uuid.UUID) bool { return pending == id })
+	return nil
+}
+
+// TestQueueRecoversPendingJobs tests that released jobs and jobs dropped by a full queue are run again.
+func TestQueueRecoversPendingJobs(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	stub := &pendingStub{pending: slices.Clone(ids), attempts: make(map[uuid.UUID]int)}
+	queue := jobs.NewQueue("test", 2, stub.run)
+	queue.Pending = stub.list
+	queue.RecoverInterval = 10 * time.Millisecond
+
+	// The queue only holds two of the jobs, the others are left to the recovery.
+	assert.True(t, queue.Enqueue(ids[0]))
+	assert.True(t, queue.Enqueue(ids[1]))
+	assert.False(t, queue.Enqueue(ids[2]))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.Start(ctx, 1)
+	assert.Eventually(t, func() bool {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		return len(stub.pending) == 0
+	}, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	queue.Wait()
+
+	for _, id := range ids {
+		assert.Equal(t, 2, stub.attempts[id], "job %s was not run again after failing", id)
+	}
+}
@@ -0,0 +1,245 @@
+package markup
+
+import (
+	"strings"
+)
+
+// escapable are the characters a backslash writes literally.
+const escapable = "\\`*_|[]()>"
+
+// autolinkTrailing are the characters trimmed from the end of a link written as is, as they
+// usually belong to the surrounding sentence.
+const autolinkTrailing = ".,:;!?'\")]>"
+
+// inlineParser parses the inline formatting of one text. Every delimiter is searched for from
+// left to right, so once no closer is found after a position, none will be found after a later
+// one. Remembering these misses keeps parsing linear for each delimiter however many openers
+// are left unclosed.
+type inlineParser struct {
+	*parser
+	text  string
+	depth int
+	// missing holds the delimiters known to have no closer in the rest of the text.
+	missing map[string]bool
+}
+
+// inline parses the inline nodes of text nested depth levels deep.
+func (p *parser) inline(text string, depth int) []Node {
+	ip := &inlineParser{parser: p, text: text, depth: depth, missing: map[string]bool{}}
+	return ip.parse()
+}
+
+// parse turns the text into nodes, merging literal text into text nodes.
+func (ip *inlineParser) parse() []Node {
+	var nodes []Node
+	var literal strings.Builder
+	text := ip.text
+	for i := 0; i < len(text); {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte(escapable, text[i+1]) >= 0 {
+			literal.WriteByte(text[i+1])
+			i += 2
+			continue
+		}
+		node, next := ip.token(i)
+		if node == nil {
+			literal.WriteString(text[i:next])
+			i = next
+			continue
+		}
+		if literal.Len() > 0 {
+			nodes = append(nodes, Node{Type: NodeText, Text: literal.String()})
+			literal.Reset()
+		}
+		nodes = append(nodes, *node)
+		i = next
+	}
+	if literal.Len() > 0 {
+		nodes = append(nodes, Node{Type: NodeText, Text: literal.String()})
+	}
+	return nodes
+}
+
+// token parses the construct starting at position i. It returns the node and the position after
+// it, or no node and the end of the literal text to write when nothing starts at i.
+func (ip *inlineParser) token(i int) (*Node, int) {
+	text := ip.text
+	switch text[i] {
+	case '`':
+		return ip.code(i)
+	case '*':
+		if strings.HasPrefix(text[i:], "**") {
+			return ip.span(i, "**", NodeBold)
+		}
+		return ip.span(i, "*", NodeItalic)
+	case '_':
+		// Double underscores, as in __init__, are never formatting.
+		if strings.HasPrefix(text[i:], "__") {
+			return nil, i + 2
+		}
+		// Underscores inside a word, as in snake_case, are not formatting either.
+		if i > 0 && isWordChar(rune(text[i-1])) {
+			return nil, i + 1
+		}
+		return ip.span(i, "_", NodeItalic)
+	case '|':
+		if strings.HasPrefix(text[i:], "||") {
+			return ip.span(i, "||", NodeSpoiler)
+		}
+	case '[':
+		return ip.link(i)
+	case 'h':
+		return ip.autolink(i)
+	}
+	return nil, i + 1
+}
+
+// code parses inline code opened by a run of backticks and closed by a run of the same length.
+// The content is kept as is.
+func (ip *inlineParser) code(i int) (*Node, int) {
+	text := ip.text
+	run := backtickRun(text, i)
+	delimiter := text[i : i+run]
+	if ip.missing[delimiter] {
+		return nil, i + run
+	}
+	for j := i + run; j < len(text); {
+		if text[j] != '`' {
+			j++
+			continue
+		}
+		closing := backtickRun(text, j)
+		if closing == run {
+			return &Node{Type: NodeCode, Text: text[i+run : j]}, j + run
+		}
+		j += closing
+	}
+	ip.missing[delimiter] = true
+	return nil, i + run
+}
+
+// span parses formatting enclosed in a delimiter. Bold and italic must not start or end with a
+// space, so multiplication such as 2 * 3 * 4 stays text.
+func (ip *inlineParser) span(i int, delimiter string, nodeType NodeType) (*Node, int) {
+	text := ip.text
+	start := i + len(delimiter)
+	emphasis := nodeType != NodeSpoiler
+	if start >= len(text) || (emphasis && isSpace(text[start])) {
+		return nil, start
+	}
+	end := ip.closer(start, delimiter, emphasis)
+	if end < 0 || end == start {
+		return nil, start
+	}
+	if ip.depth >= MAX_DEPTH {
+		ip.fail(ErrNestingTooDeep)
+		return nil, start
+	}
+	children := ip.parser.inline(text[start:end], ip.depth+1)
+	return &Node{Type: nodeType, Children: children}, end + len(delimiter)
+}
+
+// closer returns the position of the delimiter closing a span whose content starts at start,
+// or -1. Escaped characters are skipped, and so are double delimiters when looking for a single
+// one, which lets italic text hold bold text.
+func (ip *inlineParser) closer(start int, delimiter string, emphasis bool) int {
+	if ip.missing[delimiter] {
+		return -1
+	}
+	text := ip.text
+	for j := start; j < len(text); j++ {
+		if text[j] == '\\' {
+			j++
+			continue
+		}
+		if !strings.HasPrefix(text[j:], delimiter) {
+			continue
+		}
+		if len(delimiter) == 1 && j+1 < len(text) && text[j+1] == delimiter[0] {
+			j++
+			continue
+		}
+		if emphasis && isSpace(text[j-1]) {
+			continue
+		}
+		if delimiter == "_" && j+1 < len(text) && isWordChar(rune(text[j+1])) {
+			continue
+		}
+		return j
+	}
+	ip.missing[delimiter] = true
+	return -1
+}
+
+// link parses a link with a label, [label](url). Links to other schemes than http and https
+// and overlong links are reported and kept as text.
+func (ip *inlineParser) link(i int) (*Node, int) {
+	text := ip.text
+	if ip.inLink || ip.missing["]("] {
+		return nil, i + 1
+	}
+	middle := strings.Index(text[i+1:], "](")
+	if middle < 0 {
+		ip.missing["]("] = true
+		return nil, i + 1
+	}
+	middle += i + 1
+	end := strings.IndexByte(text[middle+2:], ')')
+	if end < 0 {
+		ip.missing["]("] = true
+		return nil, i + 1
+	}
+	end += middle + 2
+	label, target := text[i+1:middle], text[middle+2:end]
+	if label == "" || target == "" || strings.ContainsAny(target, " \t\n") {
+		return nil, i + 1
+	}
+	if err := checkLink(target); err != nil {
+		ip.fail(err)
+		return nil, i + 1
+	}
+	if ip.depth >= MAX_DEPTH {
+		ip.fail(ErrNestingTooDeep)
+		return nil, i + 1
+	}
+	ip.inLink = true
+	children := ip.parser.inline(label, ip.depth+1)
+	ip.inLink = false
+	return &Node{Type: NodeLink, URL: target, Children: children}, end + 1
+}
+
+// autolink parses a link written as is, which starts with http:// or https:// outside of a word
+// and ends before the next whitespace.
+func (ip *inlineParser) autolink(i int) (*Node, int) {
+	text := ip.text
+	rest := text[i:]
+	if ip.inLink || (i > 0 && isWordChar(rune(text[i-1]))) ||
+		(!strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://")) {
+		return nil, i + 1
+	}
+	end := strings.IndexAny(rest, " \t\n")
+	if end < 0 {
+		end = len(rest)
+	}
+	target := strings.TrimRight(rest[:end], autolinkTrailing)
+	if err := checkLink(target); err != nil {
+		if err == ErrLinkTooLong {
+			ip.fail(err)
+		}
+		return nil, i + len(target)
+	}
+	return &Node{Type: NodeLink, URL: target, Children: []Node{{Type: NodeText, Text: target}}}, i + len(target)
+}
+
+// backtickRun returns the number of consecutive backticks from position i.
+func backtickRun(text string, i int) int {
+	run := 0
+	for i+run < len(text) && text[i+run] == '`' {
+		run++
+	}
+	return run
+}
+
+// isSpace reports whether a byte is whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
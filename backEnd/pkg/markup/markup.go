@@ -0,0 +1,201 @@
+// Package markup parses the formatting of BlueFox messages into a typed syntax tree.
+// Message content is split into blocks, then the text of paragraphs and quotes into inline nodes:
+//
+//	```lang        a code block, closed by a line holding only ```
+//	> text         a quote, one per line
+//	**text**       bold
+//	*text* _text_  italic
+//	||text||       spoiler
+//	`code`         inline code, also with longer backtick runs such as ``a ` b``
+//	[text](url)    a link with a label, only to http and https URLs
+//	https://...    a link written as is
+//
+// A backslash before a formatting character writes it literally. Formatting that is not closed is
+// kept as text, so parsing never fails, but Parse reports the constructs Validate rejects: unclosed
+// code blocks, formatting nested deeper than MAX_DEPTH levels and invalid or overlong links.
+package markup
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const (
+	// MAX_DEPTH is the deepest nesting of formatting, quotes included.
+	MAX_DEPTH = 5
+	// MAX_LINK_LENGTH is the maximum length of a link URL in bytes.
+	MAX_LINK_LENGTH = 2048
+)
+
+var (
+	// ErrUnclosedCodeBlock is returned when a code block is opened but never closed.
+	ErrUnclosedCodeBlock = errors.New("a code block is not closed")
+	// ErrNestingTooDeep is returned when formatting is nested deeper than MAX_DEPTH levels.
+	ErrNestingTooDeep = errors.New("formatting is nested too deeply")
+	// ErrInvalidLink is returned when a link does not point to an http or https URL.
+	ErrInvalidLink = errors.New("links must point to an http or https URL")
+	// ErrLinkTooLong is returned when a link URL exceeds MAX_LINK_LENGTH bytes.
+	ErrLinkTooLong = errors.New("a link is too long")
+)
+
+// NodeType is the type of a syntax tree node.
+type NodeType string
+
+const (
+	// Block nodes
+	NodeParagraph NodeType = "paragraph"
+	NodeQuote     NodeType = "quote"
+	NodeCodeBlock NodeType = "code_block"
+
+	// Inline nodes
+	NodeText    NodeType = "text"
+	NodeBold    NodeType = "bold"
+	NodeItalic  NodeType = "italic"
+	NodeSpoiler NodeType = "spoiler"
+	NodeCode    NodeType = "code"
+	NodeLink    NodeType = "link"
+)
+
+// Node is a node of the syntax tree of a message.
+type Node struct {
+	Type NodeType `json:"type"`
+	// Text is the content of text, code and code block nodes.
+	Text string `json:"text,omitempty"`
+	// Language is the language of a code block, empty when it is not given.
+	Language string `json:"language,omitempty"`
+	// URL is the target of a link node.
+	URL string `json:"url,omitempty"`
+	// Children are the nodes of paragraphs, quotes, formatting and link labels.
+	Children []Node `json:"children,omitempty"`
+}
+
+// Parse parses message content into block nodes. Blank lines between blocks are dropped.
+// params:
+// - content: The message content.
+// returns:
+// - []Node: The blocks of the content, malformed constructs kept as text.
+// - error: The first of ErrUnclosedCodeBlock, ErrNestingTooDeep, ErrInvalidLink or ErrLinkTooLong found.
+func Parse(content string) ([]Node, error) {
+	p := &parser{}
+	return p.blocks(content), p.err
+}
+
+// Validate checks that message content has no malformed or overlong construct.
+// params:
+// - content: The message content.
+// returns:
+// - error: The first error reported by Parse.
+func Validate(content string) error {
+	_, err := Parse(content)
+	return err
+}
+
+// parser keeps the state shared by the blocks and the inline text of one content.
+type parser struct {
+	// err is the first problem found.
+	err error
+	// inLink is set while parsing a link label, links cannot be nested.
+	inLink bool
+}
+
+// fail records a problem, only the first one is reported.
+func (p *parser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// blocks splits content into code blocks, quotes and paragraphs of consecutive lines.
+func (p *parser) blocks(content string) []Node {
+	var nodes []Node
+	var kind NodeType
+	var pending []string
+	flush := func() {
+		text := strings.Join(pending, "\n")
+		pending = nil
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		depth := 0
+		if kind == NodeQuote {
+			depth = 1
+		}
+		nodes = append(nodes, Node{Type: kind, Children: p.inline(text, depth)})
+	}
+	add := func(lineKind NodeType, line string) {
+		if lineKind != kind {
+			flush()
+			kind = lineKind
+		}
+		pending = append(pending, line)
+	}
+
+	lines := strings.Split(content, "\n")
+	// Once a fence is left open, no later fence can be closed either.
+	unclosed := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if language, ok := openingFence(line); ok && !unclosed {
+			if end := closingFence(lines, i+1); end >= 0 {
+				flush()
+				nodes = append(nodes, Node{Type: NodeCodeBlock, Language: language, Text: strings.Join(lines[i+1:end], "\n")})
+				i = end
+				continue
+			}
+			unclosed = true
+			p.fail(ErrUnclosedCodeBlock)
+		}
+		if line == ">" || strings.HasPrefix(line, "> ") {
+			add(NodeQuote, strings.TrimPrefix(line[1:], " "))
+			continue
+		}
+		add(NodeParagraph, line)
+	}
+	flush()
+	return nodes
+}
+
+// openingFence reports whether a line opens a code block: three backticks followed by nothing
+// but an optional language name.
+func openingFence(line string) (string, bool) {
+	rest, found := strings.CutPrefix(line, "```")
+	if !found {
+		return "", false
+	}
+	language := strings.TrimSpace(rest)
+	for _, c := range language {
+		if !isWordChar(c) && !strings.ContainsRune("+-.#", c) {
+			return "", false
+		}
+	}
+	return language, true
+}
+
+// closingFence returns the index of the first line from start that closes a code block, or -1.
+func closingFence(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "```" {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkLink validates the URL of a link.
+func checkLink(raw string) error {
+	if len(raw) > MAX_LINK_LENGTH {
+		return ErrLinkTooLong
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidLink
+	}
+	return nil
+}
+
+// isWordChar reports whether a character is part of a word. Every non-ASCII character counts as
+// one, so formatting next to letters of any script behaves the same.
+func isWordChar(c rune) bool {
+	return c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
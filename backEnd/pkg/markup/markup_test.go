@@ -0,0 +1,250 @@
+package markup_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/markup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// text returns a text node.
+func text(value string) markup.Node {
+	return markup.Node{Type: markup.NodeText, Text: value}
+}
+
+// node returns a node with children.
+func node(nodeType markup.NodeType, children ...markup.Node) markup.Node {
+	return markup.Node{Type: nodeType, Children: children}
+}
+
+// paragraph returns a paragraph block holding inline nodes.
+func paragraph(children ...markup.Node) []markup.Node {
+	return []markup.Node{node(markup.NodeParagraph, children...)}
+}
+
+// TestParse tests parsing message content into a syntax tree.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []markup.Node
+	}{
+		{
+			name:    "Empty content",
+			content: "",
+			want:    nil,
+		},
+		{
+			name:    "Plain text",
+			content: "Hello there!",
+			want:    paragraph(text("Hello there!")),
+		},
+		{
+			name:    "Bold, italic and spoiler",
+			content: "**bold** *italic* _also italic_ ||secret||",
+			want: paragraph(
+				node(markup.NodeBold, text("bold")), text(" "),
+				node(markup.NodeItalic, text("italic")), text(" "),
+				node(markup.NodeItalic, text("also italic")), text(" "),
+				node(markup.NodeSpoiler, text("secret")),
+			),
+		},
+		{
+			name:    "Nested formatting",
+			content: "*a **b** c*",
+			want:    paragraph(node(markup.NodeItalic, text("a "), node(markup.NodeBold, text("b")), text(" c"))),
+		},
+		{
+			name:    "Unclosed formatting is text",
+			content: "**bold ||spoiler",
+			want:    paragraph(text("**bold ||spoiler")),
+		},
+		{
+			name:    "Arithmetic and identifiers are text",
+			content: "2 * 3 * 4 in snake_case_name and __init__",
+			want:    paragraph(text("2 * 3 * 4 in snake_case_name and __init__")),
+		},
+		{
+			name:    "Escaped delimiters",
+			content: `\*not italic\* and \\`,
+			want:    paragraph(text(`*not italic* and \`)),
+		},
+		{
+			name:    "Inline code keeps its content",
+			content: "run `**go** test` or ``a ` b``",
+			want: paragraph(
+				text("run "), markup.Node{Type: markup.NodeCode, Text: "**go** test"},
+				text(" or "), markup.Node{Type: markup.NodeCode, Text: "a ` b"},
+			),
+		},
+		{
+			name:    "Links",
+			content: "see [the **docs**](https://example.com/docs) or https://example.com.",
+			want: paragraph(
+				text("see "),
+				markup.Node{Type: markup.NodeLink, URL: "https://example.com/docs", Children: []markup.Node{text("the "), node(markup.NodeBold, text("docs"))}},
+				text(" or "),
+				markup.Node{Type: markup.NodeLink, URL: "https://example.com", Children: []markup.Node{text("https://example.com")}},
+				text("."),
+			),
+		},
+		{
+			name:    "Code block, quote and paragraph",
+			content: "```go\nfmt.Println(\"**hi**\")\n```\n> quoted *text*\n> second line\nafter",
+			want: []markup.Node{
+				{Type: markup.NodeCodeBlock, Language: "go", Text: "fmt.Println(\"**hi**\")"},
+				node(markup.NodeQuote, text("quoted "), node(markup.NodeItalic, text("text")), text("\nsecond line")),
+				node(markup.NodeParagraph, text("after")),
+			},
+		},
+		{
+			name:    "Quote marker without a space is text",
+			content: ">not a quote",
+			want:    paragraph(text(">not a quote")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := markup.Parse(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestValidate tests rejecting malformed and overlong constructs.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{
+			name:    "Valid content",
+			content: "**hello** [docs](http://example.com)\n```\ncode\n```",
+		},
+		{
+			name:    "Deepest nesting",
+			content: "**||_*[x](https://example.com)*_||**",
+		},
+		{
+			name:    "Error: Unclosed code block",
+			content: "```go\nfmt.Println()",
+			wantErr: markup.ErrUnclosedCodeBlock,
+		},
+		{
+			name:    "Error: Nesting too deep",
+			content: "> **||_*[x](https://example.com)*_||**",
+			wantErr: markup.ErrNestingTooDeep,
+		},
+		{
+			name:    "Error: Script link",
+			content: "[click](javascript:alert(1))",
+			wantErr: markup.ErrInvalidLink,
+		},
+		{
+			name:    "Error: Link too long",
+			content: "https://example.com/" + strings.Repeat("a", markup.MAX_LINK_LENGTH),
+			wantErr: markup.ErrLinkTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := markup.Validate(tt.content)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestPlainText tests rendering parsed content without formatting.
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Formatting is removed",
+			content: "**bold** *italic* `code`",
+			want:    "bold italic code",
+		},
+		{
+			name:    "Spoilers are hidden",
+			content: "the answer is ||42||",
+			want:    "the answer is " + markup.SPOILER_PLACEHOLDER,
+		},
+		{
+			name:    "Link labels are followed by their URL",
+			content: "[docs](https://example.com) https://example.org",
+			want:    "docs (https://example.com) https://example.org",
+		},
+		{
+			name:    "Blocks on separate lines",
+			content: "> quote\n```\ncode\n```\ntext",
+			want:    "quote\ncode\ntext",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := markup.Parse(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, markup.PlainText(nodes))
+		})
+	}
+}
+
+// TestParsePathological tests that content built to trigger backtracking is parsed quickly.
+func TestParsePathological(t *testing.T) {
+	contents := []string{
+		strings.Repeat("*a", 2000),
+		strings.Repeat("**", 2000),
+		strings.Repeat("[a](", 1000),
+		strings.Repeat("`", 4000),
+		strings.Repeat("```\n", 1000) + "x",
+		strings.Repeat("||*_", 1000),
+	}
+	for _, content := range contents {
+		start := time.Now()
+		nodes, _ := markup.Parse(content)
+		markup.PlainText(nodes)
+		assert.Less(t, time.Since(start), time.Second)
+	}
+}
+
+// FuzzParse checks that parsing never panics and that the plain text of valid UTF-8 content is
+// valid UTF-8.
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"",
+		"**bold** *italic* _italic_ ||spoiler||",
+		"*a **b** c*",
+		"`code` ``a ` b``",
+		"[label](https://example.com) https://example.com/path?q=1.",
+		"```go\ncode\n```\n> quote",
+		"```\nunclosed",
+		`\*\_\|\\`,
+		"[x](javascript:alert(1))",
+		"||||**__**||",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, content string) {
+		nodes, _ := markup.Parse(content)
+		plain := markup.PlainText(nodes)
+		if utf8.ValidString(content) && !utf8.ValidString(plain) {
+			t.Errorf("plain text of %q is not valid UTF-8: %q", content, plain)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package markup
+
+import (
+	"strings"
+)
+
+// SPOILER_PLACEHOLDER replaces the content of spoilers in plain text, so notifications do not reveal it.
+const SPOILER_PLACEHOLDER = "[spoiler]"
+
+// PlainText renders parsed content without formatting, for notifications and previews. Blocks
+// are written on separate lines, spoilers are hidden and links with a label are followed by
+// their URL, so the target of a link is never hidden behind its label.
+// params:
+// - nodes: The blocks returned by Parse.
+// returns:
+// - string: The plain text.
+func PlainText(nodes []Node) string {
+	var b strings.Builder
+	for i := range nodes {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		writePlainText(&b, &nodes[i])
+	}
+	return b.String()
+}
+
+// writePlainText writes a node and its children without formatting.
+func writePlainText(b *strings.Builder, node *Node) {
+	switch node.Type {
+	case NodeText, NodeCode, NodeCodeBlock:
+		b.WriteString(node.Text)
+	case NodeSpoiler:
+		b.WriteString(SPOILER_PLACEHOLDER)
+	case NodeLink:
+		var label strings.Builder
+		for i := range node.Children {
+			writePlainText(&label, &node.Children[i])
+		}
+		b.WriteString(label.String())
+		if label.String() != node.URL {
+			b.WriteString(" (" + node.URL + ")")
+		}
+	default:
+		for i := range node.Children {
+			writePlainText(b, &node.Children[i])
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package mediatype identifies the MIME type of uploaded files from their content.
+//
+// The type declared by the client and the extension of the filename are not trusted: the type is
+// sniffed from the first bytes of the file, and the upload is rejected when the declared type or
+// the extension contradicts it. Each context a file is uploaded for has an allowlist of types, so
+// avatars and icons are images and no context accepts HTML or scripts that would run when the
+// file is opened from the API.
+package mediatype
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+)
+
+const (
+	// SNIFF_LENGTH is the number of bytes the type of a file is sniffed from.
+	SNIFF_LENGTH = 512
+	// MAX_FILENAME_LENGTH is the maximum length of a sanitized filename in characters.
+	MAX_FILENAME_LENGTH = 255
+	// UNKNOWN is the type of files that are not recognised, and the type declared by clients that
+	// do not know it.
+	UNKNOWN = "application/octet-stream"
+)
+
+// Context is what a file is uploaded for, which decides the types it can have.
+type Context string
+
+const (
+	ContextAttachment Context = "attachment"
+	ContextAvatar     Context = "avatar"
+	ContextServerIcon Context = "server_icon"
+)
+
+var (
+	// ErrNotAllowed is returned when the content of a file is not allowed in its context.
+	ErrNotAllowed = errors.New("the file type is not allowed here")
+	// ErrDeclaredMismatch is returned when the declared type contradicts the content of a file.
+	ErrDeclaredMismatch = errors.New("the declared type does not match the content of the file")
+	// ErrExtensionMismatch is returned when the extension of a filename contradicts the content of a file.
+	ErrExtensionMismatch = errors.New("the file extension does not match the content of the file")
+)
+
+// extensions lists the file extensions of the types allowed in any context, the first one is
+// appended to filenames without extension.
+var extensions = map[string][]string{
+	"image/png":       {".png"},
+	"image/jpeg":      {".jpg", ".jpeg", ".jfif"},
+	"image/gif":       {".gif"},
+	"image/webp":      {".webp"},
+	"video/mp4":       {".mp4", ".m4v"},
+	"video/webm":      {".webm"},
+	"video/quicktime": {".mov"},
+	"audio/mpeg":      {".mp3"},
+	"audio/ogg":       {".ogg", ".oga", ".opus"},
+	"audio/wave":      {".wav"},
+	"audio/mp4":       {".m4a"},
+	"audio/flac":      {".flac"},
+	"application/pdf": {".pdf"},
+	"application/zip": {".zip"},
+	"text/plain":      {".txt", ".md", ".csv", ".log", ".json"},
+}
+
+// images are the types allowed for avatars and icons.
+var images = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// allowlists holds the types allowed in each context.
+var allowlists = map[Context][]string{
+	ContextAvatar:     images,
+	ContextServerIcon: images,
+	ContextAttachment: {
+		"image/png", "image/jpeg", "image/gif", "image/webp",
+		"video/mp4", "video/webm", "video/quicktime",
+		"audio/mpeg", "audio/ogg", "audio/wave", "audio/mp4", "audio/flac",
+		"application/pdf", "application/zip", "text/plain",
+	},
+}
+
+// aliases maps the types clients declare to the type sniffed from the same content.
+var aliases = map[string]string{
+	"image/jpg":                    "image/jpeg",
+	"image/pjpeg":                  "image/jpeg",
+	"audio/mp3":                    "audio/mpeg",
+	"audio/wav":                    "audio/wave",
+	"audio/x-wav":                  "audio/wave",
+	"audio/vnd.wave":               "audio/wave",
+	"audio/opus":                   "audio/ogg",
+	"application/ogg":              "audio/ogg",
+	"audio/x-m4a":                  "audio/mp4",
+	"audio/x-flac":                 "audio/flac",
+	"application/x-zip-compressed": "application/zip",
+	"text/markdown":                "text/plain",
+	"text/csv":                     "text/plain",
+	"application/json":             "text/plain",
+}
+
+// Sniff returns the MIME type of a file from its first bytes, without parameters. It recognises
+// the types of http.DetectContentType and a few audio and video formats it does not.
+// params:
+// - head: The first SNIFF_LENGTH bytes of the file, or the whole file if it is shorter.
+// returns:
+// - string: The MIME type, UNKNOWN if it is not recognised.
+func Sniff(head []byte) string {
+	// ISO media files are told apart by the major brand, http.DetectContentType reports them all as video/mp4.
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		switch string(head[8:12]) {
+		case "qt  ":
+			return "video/quicktime"
+		case "M4A ", "M4B ":
+			return "audio/mp4"
+		}
+	}
+	if bytes.HasPrefix(head, []byte("fLaC")) {
+		return "audio/flac"
+	}
+	// MP3 files without ID3 tag start with an MPEG audio layer III frame.
+	if len(head) >= 2 && head[0] == 0xFF && (head[1] == 0xFB || head[1] == 0xF3 || head[1] == 0xF2) {
+		return "audio/mpeg"
+	}
+	return Normalize(http.DetectContentType(head))
+}
+
+// Normalize returns the type a MIME type is sniffed as, without parameters.
+// params:
+// - mediaType: The MIME type, as declared by a client.
+// returns:
+// - string: The normalized type, UNKNOWN if it is empty or malformed.
+func Normalize(mediaType string) string {
+	parsed, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return UNKNOWN
+	}
+	if alias, ok := aliases[parsed]; ok {
+		return alias
+	}
+	return parsed
+}
+
+// ValidContext checks that a context is known.
+// params:
+// - context: The context to check.
+// returns:
+// - bool: True if the context is known.
+func ValidContext(context Context) bool {
+	_, ok := allowlists[context]
+	return ok
+}
+
+// Allowlist returns the types allowed in a context.
+// params:
+// - context: The context.
+// returns:
+// - []string: The allowed MIME types, none for unknown contexts.
+func Allowlist(context Context) []string {
+	return slices.Clone(allowlists[context])
+}
+
+// Check verifies a file uploaded for a context against the type sniffed from its content.
+// params:
+// - context: The context the file is uploaded for.
+// - filename: The sanitized filename, the extension can be left out.
+// - declared: The type declared by the client, UNKNOWN if it did not declare one.
+// - sniffed: The type sniffed from the content.
+// returns:
+// - error: ErrNotAllowed, ErrDeclaredMismatch or ErrExtensionMismatch.
+func Check(context Context, filename string, declared string, sniffed string) error {
+	if !slices.Contains(allowlists[context], sniffed) {
+		return ErrNotAllowed
+	}
+	if declared := Normalize(declared); declared != UNKNOWN && declared != sniffed {
+		return ErrDeclaredMismatch
+	}
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" && !slices.Contains(extensions[sniffed], ext) {
+		return ErrExtensionMismatch
+	}
+	return nil
+}
+
+// AssetType returns the asset type of a MIME type, files that are not images, videos or audio are
+// documents.
+// params:
+// - mediaType: The MIME type.
+// returns:
+// - models.AssetType: The asset type.
+func AssetType(mediaType string) models.AssetType {
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return models.AssetTypeImage
+	case strings.HasPrefix(mediaType, "video/"):
+		return models.AssetTypeVideo
+	case strings.HasPrefix(mediaType, "audio/"):
+		return models.AssetTypeAudio
+	}
+	return models.AssetTypeDocument
+}
+
+// SanitizeFilename makes a client filename safe to store and to serve in a Content-Disposition
+// header. Directories, control characters and characters reserved by common filesystems are
+// removed, leading dots and surrounding spaces are trimmed, and long names are shortened keeping
+// their extension.
+// params:
+// - filename: The filename sent by the client.
+// returns:
+// - string: The sanitized filename, empty if nothing is left.
+func SanitizeFilename(filename string) string {
+	filename = strings.ToValidUTF8(filename, "")
+	filename = filename[strings.LastIndexAny(filename, `/\`)+1:]
+	filename = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, filename)
+	filename = strings.TrimRight(strings.TrimLeft(filename, ". "), ". ")
+
+	if utf8.RuneCountInString(filename) > MAX_FILENAME_LENGTH {
+		ext := filepath.Ext(filename)
+		if utf8.RuneCountInString(ext) > MAX_FILENAME_LENGTH/2 {
+			ext = ""
+		}
+		base := []rune(strings.TrimSuffix(filename, ext))
+		filename = strings.TrimRight(string(base[:MAX_FILENAME_LENGTH-utf8.RuneCountInString(ext)]), ". ") + ext
+	}
+	return filename
+}
+
+// WithExtension appends the extension of a type to a filename without one.
+// params:
+// - filename: The sanitized filename.
+// - mediaType: The type sniffed from the content.
+// returns:
+// - string: The filename with an extension if the type has one.
+func WithExtension(filename string, mediaType string) string {
+	exts := extensions[mediaType]
+	if filepath.Ext(filename) != "" || len(exts) == 0 {
+		return filename
+	}
+	if runes := []rune(filename); len(runes)+len(exts[0]) > MAX_FILENAME_LENGTH {
+		filename = string(runes[:MAX_FILENAME_LENGTH-len(exts[0])])
+	}
+	return filename + exts[0]
+}
@@ -0,0 +1,113 @@
+package mediatype_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/mediatype"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSniff tests recognising file types from their first bytes.
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"PNG", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"JPEG", "\xff\xd8\xff\xe0\x00\x10JFIF", "image/jpeg"},
+		{"GIF", "GIF89a\x01\x00\x01\x00", "image/gif"},
+		{"WebP", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"MP4", "\x00\x00\x00\x20ftypisom\x00\x00\x02\x00isomiso2avc1mp41", "video/mp4"},
+		{"QuickTime", "\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00qt  ", "video/quicktime"},
+		{"M4A", "\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00M4A mp42isom", "audio/mp4"},
+		{"MP3 with ID3 tag", "ID3\x04\x00\x00\x00\x00\x00\x00", "audio/mpeg"},
+		{"MP3 without tag", "\xff\xfb\x90\x64\x00\x00", "audio/mpeg"},
+		{"Ogg", "OggS\x00\x02\x00\x00", "audio/ogg"},
+		{"FLAC", "fLaC\x00\x00\x00\x22", "audio/flac"},
+		{"PDF", "%PDF-1.7\n", "application/pdf"},
+		{"Text", "Shopping list: milk, eggs", "text/plain"},
+		{"HTML", "<!DOCTYPE html><script>alert(1)</script>", "text/html"},
+		{"Executable", "MZ\x90\x00\x03\x00\x00\x00\x04\x00", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mediatype.Sniff([]byte(tt.head)))
+		})
+	}
+}
+
+// TestCheck tests verifying the declared type and the extension against the content.
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		context  mediatype.Context
+		filename string
+		declared string
+		sniffed  string
+		want     error
+	}{
+		{"Valid: Matching image", mediatype.ContextAvatar, "me.png", "image/png", "image/png", nil},
+		{"Valid: Upper case extension", mediatype.ContextAvatar, "ME.JPG", "image/jpeg", "image/jpeg", nil},
+		{"Valid: Alias of the declared type", mediatype.ContextAttachment, "song.wav", "audio/x-wav", "audio/wave", nil},
+		{"Valid: Undeclared type", mediatype.ContextAttachment, "clip.mp4", "application/octet-stream", "video/mp4", nil},
+		{"Valid: No extension", mediatype.ContextAttachment, "notes", "text/plain; charset=utf-8", "text/plain", nil},
+		{"Valid: Markdown", mediatype.ContextAttachment, "README.md", "text/markdown", "text/plain", nil},
+		{"Invalid: Video avatar", mediatype.ContextAvatar, "me.mp4", "video/mp4", "video/mp4", mediatype.ErrNotAllowed},
+		{"Invalid: HTML attachment", mediatype.ContextAttachment, "page.html", "text/html", "text/html", mediatype.ErrNotAllowed},
+		{"Invalid: Unknown content", mediatype.ContextAttachment, "setup.exe", "application/octet-stream", "application/octet-stream", mediatype.ErrNotAllowed},
+		{"Invalid: Unknown context", "banner", "me.png", "image/png", "image/png", mediatype.ErrNotAllowed},
+		{"Invalid: Declared image with PDF content", mediatype.ContextAttachment, "cat.pdf", "image/png", "application/pdf", mediatype.ErrDeclaredMismatch},
+		{"Invalid: Image extension with PDF content", mediatype.ContextAttachment, "cat.png", "application/pdf", "application/pdf", mediatype.ErrExtensionMismatch},
+		{"Invalid: Script extension with text content", mediatype.ContextAttachment, "run.js", "text/plain", "text/plain", mediatype.ErrExtensionMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mediatype.Check(tt.context, tt.filename, tt.declared, tt.sniffed))
+		})
+	}
+}
+
+// TestSanitizeFilename tests making client filenames safe to store and serve.
+func TestSanitizeFilename(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"Unchanged", "nyaralás 2024.jpg", "nyaralás 2024.jpg"},
+		{"Path", "../../etc/passwd", "passwd"},
+		{"Windows path", `C:\Users\me\cat.png`, "cat.png"},
+		{"Control characters", "cat\r\n.png", "cat.png"},
+		{"Reserved characters", `what?"now".txt`, "what__now_.txt"},
+		{"Hidden file", ".htaccess", "htaccess"},
+		{"Trailing dots and spaces", " cat.png. ", "cat.png"},
+		{"Invalid UTF-8", "cat\xff.png", "cat.png"},
+		{"Long name keeps its extension", long + ".png", strings.Repeat("a", mediatype.MAX_FILENAME_LENGTH-4) + ".png"},
+		{"Nothing left", "../..", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mediatype.SanitizeFilename(tt.filename))
+		})
+	}
+}
+
+// TestWithExtension tests appending the extension of the content to filenames without one.
+func TestWithExtension(t *testing.T) {
+	assert.Equal(t, "cat.jpg", mediatype.WithExtension("cat", "image/jpeg"))
+	assert.Equal(t, "cat.jpeg", mediatype.WithExtension("cat.jpeg", "image/jpeg"))
+	assert.Equal(t, "cat", mediatype.WithExtension("cat", "application/octet-stream"))
+	assert.Len(t, mediatype.WithExtension(strings.Repeat("a", mediatype.MAX_FILENAME_LENGTH), "image/png"), mediatype.MAX_FILENAME_LENGTH)
+}
+
+// TestAssetType tests mapping MIME types to asset types.
+func TestAssetType(t *testing.T) {
+	assert.Equal(t, models.AssetTypeImage, mediatype.AssetType("image/webp"))
+	assert.Equal(t, models.AssetTypeVideo, mediatype.AssetType("video/quicktime"))
+	assert.Equal(t, models.AssetTypeAudio, mediatype.AssetType("audio/flac"))
+	assert.Equal(t, models.AssetTypeDocument, mediatype.AssetType("application/pdf"))
+}
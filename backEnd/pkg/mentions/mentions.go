@@ -0,0 +1,62 @@
+// Package mentions extracts the mentions written in message content.
+// Users are mentioned as <@id>, roles as <@&id> and channels are linked as <#id>. The plain
+// words @everyone and @here mention every member of a server, or the members currently online.
+package mentions
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// tokenRegex matches user, role and channel mentions, capturing their ID, and @everyone and
+// @here when they are not part of a longer word or an e-mail address.
+var tokenRegex = regexp.MustCompile(`<@!?([0-9a-fA-F-]{36})>|<@&([0-9a-fA-F-]{36})>|<#([0-9a-fA-F-]{36})>|(?:^|[^A-Za-z0-9_@.])@(everyone|here)\b`)
+
+// Mentions are the mentions found in a message, each ID listed once in order of its first appearance.
+type Mentions struct {
+	Users    []uuid.UUID
+	Roles    []uuid.UUID
+	Channels []uuid.UUID
+	// Everyone is set when the content mentions @everyone.
+	Everyone bool
+	// Here is set when the content mentions @here.
+	Here bool
+}
+
+// Empty reports whether no mention was found.
+func (m Mentions) Empty() bool {
+	return len(m.Users) == 0 && len(m.Roles) == 0 && len(m.Channels) == 0 && !m.Everyone && !m.Here
+}
+
+// Parse extracts the mentions of a message content. Tokens with a malformed ID are ignored.
+// params:
+// - content: The message content.
+// returns:
+// - Mentions: The distinct mentions of the content.
+func Parse(content string) Mentions {
+	var mentions Mentions
+	add := func(list *[]uuid.UUID, raw string) {
+		id, err := uuid.Parse(raw)
+		if err != nil || slices.Contains(*list, id) {
+			return
+		}
+		*list = append(*list, id)
+	}
+	for _, match := range tokenRegex.FindAllStringSubmatch(content, -1) {
+		switch {
+		case match[1] != "":
+			add(&mentions.Users, match[1])
+		case match[2] != "":
+			add(&mentions.Roles, match[2])
+		case match[3] != "":
+			add(&mentions.Channels, match[3])
+		case match[4] == "everyone":
+			mentions.Everyone = true
+		case match[4] == "here":
+			mentions.Here = true
+		}
+	}
+	return mentions
+}
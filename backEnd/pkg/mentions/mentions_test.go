@@ -0,0 +1,72 @@
+package mentions_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/mentions"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParse tests extracting mentions from message content.
+func TestParse(t *testing.T) {
+	userID := uuid.MustParse("0b7f6c2e-3f0a-4a53-9d2b-4c0f4f3f9a10")
+	otherUserID := uuid.MustParse("5d1e0c8a-7b2f-4e6d-8a9c-1f2e3d4c5b6a")
+	roleID := uuid.MustParse("9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d")
+	channelID := uuid.MustParse("1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+
+	tests := []struct {
+		name    string
+		content string
+		want    mentions.Mentions
+	}{
+		{
+			name:    "No mentions",
+			content: "Hello there!",
+			want:    mentions.Mentions{},
+		},
+		{
+			name:    "User mentions in order, duplicates removed",
+			content: "<@" + userID.String() + "> and <@!" + otherUserID.String() + "> and <@" + userID.String() + ">",
+			want:    mentions.Mentions{Users: []uuid.UUID{userID, otherUserID}},
+		},
+		{
+			name:    "Role mention and channel link",
+			content: "<@&" + roleID.String() + "> see <#" + channelID.String() + ">",
+			want:    mentions.Mentions{Roles: []uuid.UUID{roleID}, Channels: []uuid.UUID{channelID}},
+		},
+		{
+			name:    "Everyone and here",
+			content: "@everyone @here, meeting now",
+			want:    mentions.Mentions{Everyone: true, Here: true},
+		},
+		{
+			name:    "Everyone after punctuation",
+			content: "(@everyone)",
+			want:    mentions.Mentions{Everyone: true},
+		},
+		{
+			name:    "Not a mention: e-mail address",
+			content: "write to admin@everyone.example",
+			want:    mentions.Mentions{},
+		},
+		{
+			name:    "Not a mention: longer word",
+			content: "@everyones @hereby",
+			want:    mentions.Mentions{},
+		},
+		{
+			name:    "Not a mention: malformed ID",
+			content: "<@0b7f6c2e-3f0a-4a53-9d2b-4c0f4f3f9a1g>",
+			want:    mentions.Mentions{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mentions.Parse(tt.content)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.Empty(), got.Empty())
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/apierrors"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	jwt_token "github.com/413ksz/BlueFox/backEnd/pkg/token"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// contextKey is an unexported type for context keys defined in this package,
+// preventing collisions with keys defined in other packages.
+type contextKey string
+
+// claimsContextKey is the context key under which the verified JWT claims are stored.
+const claimsContextKey contextKey = "jwt_claims"
+
+// AuthMiddleware verifies the JWT token sent in the Authorization header and stores
+// the verified claims in the request context for the wrapped handler.
+// Both the "Bearer <token>" and the "Bearer: <token>" header formats are accepted,
+// the latter being the format returned by the login handler.
+// Requests without a valid token are rejected with an UNAUTHORIZED api response.
+func AuthMiddleware(next http.Handler) http.Handler {
+	const (
+		COMPONENT   string = "auth_middleware"
+		METHOD_NAME string = "AuthMiddleware"
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiResponse := &models.ApiResponse[any]{}
+		apiResponse.Method = r.Method
+		apiResponse.Context = r.URL.Path
+
+		tokenString := ExtractBearerToken(r.Header.Get("Authorization"))
+		if tokenString == "" {
+			apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Missing bearer token", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "missing_token").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("path", r.URL.Path).
+				Msg("Request rejected: missing bearer token.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+
+		claims, err := jwt_token.VerifyJWTToken(tokenString)
+		if err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Invalid or expired token", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "invalid_token").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("path", r.URL.Path).
+				Err(err).
+				Msg("Request rejected: invalid bearer token.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+
+		if _, err := uuid.Parse(claims.Id); err != nil {
+			apiResponse.Error = apierrors.ERROR_CODE_UNAUTHORIZED.ApiErrorResponse("Token does not identify a user", nil)
+			log.Warn().
+				Str("component", COMPONENT).
+				Str("method_name", METHOD_NAME).
+				Str("event", "invalid_token_subject").
+				Str("api_error_code", apiResponse.Error.Code).
+				Str("api_error_message", apiResponse.Error.Message).
+				Int("api_error_status", apiResponse.Error.HTTPStatusCode).
+				Str("path", r.URL.Path).
+				Err(err).
+				Msg("Request rejected: token user id is not a valid UUID.")
+			models.SendApiResponse(w, apiResponse)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ExtractBearerToken returns the token part of an Authorization header value,
+// or an empty string if the header does not carry a bearer token.
+func ExtractBearerToken(header string) string {
+	if !strings.HasPrefix(header, "Bearer") {
+		return ""
+	}
+	token := strings.TrimPrefix(header, "Bearer")
+	token = strings.TrimPrefix(token, ":")
+	return strings.TrimSpace(token)
+}
+
+// ClaimsFromContext returns the JWT claims stored by AuthMiddleware.
+func ClaimsFromContext(ctx context.Context) (*models.MyClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*models.MyClaims)
+	return claims, ok
+}
+
+// UserIDFromContext returns the ID of the authenticated user stored by AuthMiddleware.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
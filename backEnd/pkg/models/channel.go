@@ -4,20 +4,29 @@ import (
 	"github.com/google/uuid"
 )
 
+// Channel table gorm model
 type Channel struct {
 	// Base Fields
-	ID       uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	ServerID uuid.UUID `gorm:"not null;type:uuid"`
-	Name     string
-	Icon     *string
-	Type     ChannelType
-	Topic    *string
+	ID       uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ServerID uuid.UUID   `json:"server_id" gorm:"not null;type:uuid"`
+	Name     string      `json:"name"`
+	Icon     *string     `json:"icon"`
+	Type     ChannelType `json:"type"`
+	Topic    *string     `json:"topic"`
+	Position int         `json:"position" gorm:"not null;default:0"` // Sort order among the channels sharing the same parent
+
+	// UserLimit is the maximum number of users connected to a voice channel, 0 for no limit
+	UserLimit int `json:"user_limit" gorm:"not null;default:0"`
+
+	// DeniedPermissions are removed from the permissions of every non-administrator member in this channel
+	DeniedPermissions Permission `json:"denied_permissions" gorm:"not null;default:0"`
 
 	// Foreign Key for Parent Channel (for nested channels/categories)
-	Parent *uuid.UUID `gorm:"type:uuid"` // Can be null for top-level channels
+	Parent *uuid.UUID `json:"parent" gorm:"type:uuid"` // Can be null for top-level channels
 
 	// Relations
-	Server        Server    `gorm:"foreignKey:ServerID"` // Relation: A channel belongs to one server
-	ParentChannel *Channel  `gorm:"foreignKey:Parent"`   // Relation: A channel can have a parent channel
-	ChildChannels []Channel `gorm:"foreignKey:Parent"`   // Relation: A channel can have many child channels
+	Server        Server    `json:"-" gorm:"foreignKey:ServerID"`                              // Relation: A channel belongs to one server
+	ParentChannel *Channel  `json:"-" gorm:"foreignKey:Parent"`                                // Relation: A channel can have a parent channel
+	ChildChannels []Channel `json:"children,omitempty" gorm:"foreignKey:Parent"`               // Relation: A channel can have many child channels
+	Messages      []Message `json:"-" gorm:"foreignKey:ChannelID;constraint:OnDelete:CASCADE"` // Relation: A chat channel has many messages
 }
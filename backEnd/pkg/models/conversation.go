@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Conversation table gorm model
+// A conversation is a private message container outside of servers, either a
+// one-to-one direct message or a group direct message.
+// Direct conversations are deduplicated per user pair through DirectKey, group
+// conversations have an owner and optionally a name and an icon.
+type Conversation struct {
+	// Base Fields
+	ID            uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Type          ConversationType `json:"type" gorm:"not null"`
+	Name          *string          `json:"name"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	LastMessageAt time.Time        `json:"last_message_at" gorm:"not null;default:CURRENT_TIMESTAMP;index"` // Last activity, used to sort the conversation list
+	DirectKey     *string          `json:"-" gorm:"uniqueIndex"`                                            // Set for direct conversations only, see DirectConversationKey
+
+	// Foreign Keys
+	OwnerID     *uuid.UUID `json:"owner_id" gorm:"type:uuid"`      // Set for group conversations only
+	IconAssetID *uuid.UUID `json:"icon_asset_id" gorm:"type:uuid"` // Can be null if the group has no icon
+
+	// Relations
+	Owner        *User                     `json:"-" gorm:"foreignKey:OwnerID"`                                    // Relation: A group conversation has one owner
+	IconAsset    *MediaAsset               `json:"-" gorm:"foreignKey:IconAssetID"`                                // Relation: A group conversation can have an icon
+	Participants []ConversationParticipant `json:"-" gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"` // Relation: A conversation has many participants
+	Messages     []Message                 `json:"-" gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"` // Relation: A conversation has many messages
+}
+
+// DirectConversationKey returns the key that identifies the direct conversation of two users.
+// The key does not depend on the order of the users.
+func DirectConversationKey(a uuid.UUID, b uuid.UUID) string {
+	first, second := a.String(), b.String()
+	if second < first {
+		first, second = second, first
+	}
+	return first + ":" + second
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConversationParticipant table gorm model
+type ConversationParticipant struct {
+	// Composite Primary Keys (Foreign Keys)
+	ConversationID uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false"`
+	UserID         uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index"`
+
+	// Base Fields
+	JoinedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+
+	// Relations
+	Conversation Conversation `gorm:"foreignKey:ConversationID"` // Relation: Connects to the conversation
+	User         User         `gorm:"foreignKey:UserID"`         // Relation: Connects to the user
+}
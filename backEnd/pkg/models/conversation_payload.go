@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConversationPayload is the JSON representation of a conversation returned by the API.
+type ConversationPayload struct {
+	ID            uuid.UUID         `json:"id"`
+	Type          ConversationType  `json:"type"`
+	Name          *string           `json:"name"`
+	IconAssetID   *uuid.UUID        `json:"icon_asset_id"`
+	OwnerID       *uuid.UUID        `json:"owner_id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	LastMessageAt time.Time         `json:"last_message_at"`
+	Participants  []PublicUser      `json:"participants"`
+	ReadState     *ReadStatePayload `json:"read_state,omitempty"` // Set in the conversation list of the caller
+}
+
+// NewConversationPayload creates the JSON representation of a conversation.
+// The participants are included if they were loaded together with their users.
+func NewConversationPayload(conversation *Conversation) ConversationPayload {
+	payload := ConversationPayload{
+		ID:            conversation.ID,
+		Type:          conversation.Type,
+		Name:          conversation.Name,
+		IconAssetID:   conversation.IconAssetID,
+		OwnerID:       conversation.OwnerID,
+		CreatedAt:     conversation.CreatedAt,
+		LastMessageAt: conversation.LastMessageAt,
+		Participants:  make([]PublicUser, 0, len(conversation.Participants)),
+	}
+	for _, participant := range conversation.Participants {
+		payload.Participants = append(payload.Participants, *NewPublicUser(&participant.User))
+	}
+	return payload
+}
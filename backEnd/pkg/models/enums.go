@@ -20,17 +20,19 @@ const (
 type ChannelType string
 
 const (
-	ChannelTypeChat  ChannelType = "chat"
-	ChannelTypeVoice ChannelType = "voice"
+	ChannelTypeChat     ChannelType = "chat"
+	ChannelTypeVoice    ChannelType = "voice"
+	ChannelTypeCategory ChannelType = "category" // Categories only group other channels and can never have a parent
 )
 
 type MessageType string
 
 const (
-	MessageTypeText  MessageType = "text"
-	MessageTypeImage MessageType = "image"
-	MessageTypeVideo MessageType = "video"
-	MessageTypeAudio MessageType = "audio"
+	MessageTypeText   MessageType = "text"
+	MessageTypeImage  MessageType = "image"
+	MessageTypeVideo  MessageType = "video"
+	MessageTypeAudio  MessageType = "audio"
+	MessageTypeSystem MessageType = "system" // Generated by the server, e.g. when a message is pinned
 )
 
 type AssetType string
@@ -41,3 +43,45 @@ const (
 	AssetTypeAudio    AssetType = "audio"
 	AssetTypeDocument AssetType = "document"
 )
+
+type ConversationType string
+
+const (
+	ConversationTypeDirect ConversationType = "direct" // One-to-one direct messages
+	ConversationTypeGroup  ConversationType = "group"  // Group direct messages
+)
+
+type DMPrivacy string
+
+const (
+	DMPrivacyEveryone DMPrivacy = "everyone" // Anyone can start a direct message with the user
+	DMPrivacyFriends  DMPrivacy = "friends"  // Only accepted friends can start a direct message with the user
+)
+
+// EmojiKind distinguishes custom emoji used inline and in reactions from stickers sent on their own.
+type EmojiKind string
+
+const (
+	EmojiKindEmoji   EmojiKind = "emoji"
+	EmojiKindSticker EmojiKind = "sticker"
+)
+
+// MentionKind is what a message mention refers to.
+type MentionKind string
+
+const (
+	MentionKindUser     MentionKind = "user"
+	MentionKindChannel  MentionKind = "channel"  // A link to another channel, it does not notify anyone
+	MentionKindEveryone MentionKind = "everyone" // Every member of the server or participant of the conversation
+	MentionKindHere     MentionKind = "here"     // The members that are online when the message is sent
+)
+
+// ProcessingStatus is the state of the background processing of an uploaded image.
+type ProcessingStatus string
+
+const (
+	ProcessingStatusPending    ProcessingStatus = "pending"    // Waiting for a worker, the file is not served yet
+	ProcessingStatusProcessing ProcessingStatus = "processing" // Claimed by a worker
+	ProcessingStatusReady      ProcessingStatus = "ready"      // Processed, or a file that is not processed
+	ProcessingStatusFailed     ProcessingStatus = "failed"     // The image could not be processed, it is not served
+)
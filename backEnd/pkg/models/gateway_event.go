@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GatewayEvent table gorm model
+// A gateway event holds an event published on the Postgres event bus that does not fit into a
+// notification payload. The notification only carries its ID and the receiving instances load
+// the body from here. Rows are removed once they are older than the retention of the bus.
+type GatewayEvent struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Payload   string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+}
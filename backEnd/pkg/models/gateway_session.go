@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GatewaySession table gorm model
+// A gateway session records a session of the gateway, the instance holding it and its
+// subscriptions, so a client can resume it on any instance. ExpiresAt is set while the session has
+// no connection, the session is removed when it expires.
+type GatewaySession struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	InstanceID uuid.UUID `gorm:"type:uuid;not null"` // Hub holding the session and buffering its dispatches
+	Seq        int64     `gorm:"not null;default:0"` // Sequence number of the last dispatch
+	All        bool      `gorm:"not null;default:true"`
+	// Interests is the JSON array of the subscribed servers and conversations when All is not set
+	Interests string     `gorm:"type:text;not null;default:'[]'"`
+	ExpiresAt *time.Time `gorm:"index"`
+	CreatedAt time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime;index"`
+}
+
+// GatewaySessionFrame table gorm model
+// A gateway session frame holds one of the last dispatches of a session, replayed when the
+// session is resumed on another instance.
+type GatewaySessionFrame struct {
+	SessionID uuid.UUID `gorm:"type:uuid;primaryKey;autoIncrement:false"`
+	Seq       int64     `gorm:"primaryKey;autoIncrement:false"`
+	Frame     string    `gorm:"type:text;not null"`
+
+	// Relations
+	Session GatewaySession `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"` // Relation: Connects to the session
+}
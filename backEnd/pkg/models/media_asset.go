@@ -16,6 +16,17 @@ type MediaAsset struct {
 	MimeType  AssetType `gorm:"not null"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 
+	// Storage Fields
+	StorageKey  string `gorm:"not null;default:'';index"`                   // Content-addressed key of the file in the storage backend, shared by identical files
+	ContentType string `gorm:"not null;default:'application/octet-stream'"` // MIME type sniffed from the content at upload, the file is served with it
+
+	// Image Fields
+	Width               int              `gorm:"not null;default:0"`       // Width in pixels once the image is processed
+	Height              int              `gorm:"not null;default:0"`       // Height in pixels once the image is processed
+	BlurHash            string           `gorm:"not null;default:''"`      // Placeholder shown while the image loads
+	ProcessingStatus    ProcessingStatus `gorm:"not null;default:'ready'"` // Images are stripped of metadata and thumbnailed in the background
+	ProcessingStartedAt *time.Time       // When a worker claimed the image, to retry it if the worker stopped
+
 	// Foreign Key for Uploader (Optional)
 	UploadedByUserID *uuid.UUID `gorm:"type:uuid"` // Optional: Track who uploaded it
 
@@ -24,4 +35,5 @@ type MediaAsset struct {
 	MessageAttachments  []MessageAttachment `gorm:"foreignKey:MediaAssetID"`          // Relation: A media asset can be part of many message attachments
 	UserProfilePictures []User              `gorm:"foreignKey:ProfilePictureAssetID"` // Relation: A media asset can be a profile picture for multiple users
 	ServerIcons         []Server            `gorm:"foreignKey:IconAssetID"`           // Relation: A media asset can be an icon for multiple servers
+	Thumbnails          []MediaThumbnail    `gorm:"foreignKey:MediaAssetID"`          // Relation: A processed image has thumbnails of several sizes
 }
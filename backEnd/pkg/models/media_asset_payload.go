@@ -0,0 +1,85 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MediaAssetPayload is the JSON representation of an uploaded media asset. The file is downloaded
+// from UrlPath, the storage key is not exposed. The dimensions, blurhash and thumbnails of images
+// are set once ProcessingStatus is ready.
+type MediaAssetPayload struct {
+	ID               uuid.UUID               `json:"id"`
+	Filename         string                  `json:"filename"`
+	UrlPath          string                  `json:"url_path"`
+	FileSize         int                     `json:"file_size"`
+	MimeType         AssetType               `json:"mime_type"`
+	ContentType      string                  `json:"content_type"`
+	Width            int                     `json:"width,omitempty"`
+	Height           int                     `json:"height,omitempty"`
+	BlurHash         string                  `json:"blurhash,omitempty"`
+	ProcessingStatus ProcessingStatus        `json:"processing_status"`
+	Thumbnails       []MediaThumbnailPayload `json:"thumbnails,omitempty"`
+	CreatedAt        time.Time               `json:"created_at"`
+}
+
+// MediaThumbnailPayload is the JSON representation of a thumbnail of an image media asset.
+type MediaThumbnailPayload struct {
+	Size    int    `json:"size"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	UrlPath string `json:"url_path"`
+}
+
+// NewMediaAssetPayload creates the JSON representation of a media asset, with its thumbnails if
+// they are loaded. A thumbnail is downloaded from the URL of the asset with its size.
+func NewMediaAssetPayload(asset *MediaAsset) MediaAssetPayload {
+	payload := MediaAssetPayload{
+		ID:               asset.ID,
+		Filename:         asset.Filename,
+		UrlPath:          asset.UrlPath,
+		FileSize:         asset.FileSize,
+		MimeType:         asset.MimeType,
+		ContentType:      asset.ContentType,
+		Width:            asset.Width,
+		Height:           asset.Height,
+		BlurHash:         asset.BlurHash,
+		ProcessingStatus: asset.ProcessingStatus,
+		CreatedAt:        asset.CreatedAt,
+	}
+	for _, thumbnail := range asset.Thumbnails {
+		payload.Thumbnails = append(payload.Thumbnails, MediaThumbnailPayload{
+			Size:    thumbnail.Size,
+			Width:   thumbnail.Width,
+			Height:  thumbnail.Height,
+			UrlPath: asset.UrlPath + "?size=" + strconv.Itoa(thumbnail.Size),
+		})
+	}
+	return payload
+}
+
+// UploadPayload is the JSON representation of a resumable upload. MediaAsset is set once the
+// upload is complete, the upload itself is removed then.
+type UploadPayload struct {
+	ID          uuid.UUID          `json:"id"`
+	Filename    string             `json:"filename"`
+	ContentType string             `json:"content_type"`
+	Length      int64              `json:"length"`
+	Offset      int64              `json:"offset"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+	MediaAsset  *MediaAssetPayload `json:"media_asset,omitempty"`
+}
+
+// NewUploadPayload creates the JSON representation of an upload at an offset.
+func NewUploadPayload(upload *Upload, offset int64) UploadPayload {
+	return UploadPayload{
+		ID:          upload.ID,
+		Filename:    upload.Filename,
+		ContentType: upload.ContentType,
+		Length:      upload.Length,
+		Offset:      offset,
+		ExpiresAt:   upload.ExpiresAt,
+	}
+}
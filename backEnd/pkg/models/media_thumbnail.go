@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// MediaThumbnail table gorm model
+// A thumbnail is a downscaled version of an image media asset, generated when the image is
+// processed. Its file is stored like the files of media assets.
+type MediaThumbnail struct {
+	// Base Fields
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Size        int       `gorm:"not null;uniqueIndex:idx_media_thumbnail_size"` // Longest side the thumbnail was generated for
+	Width       int       `gorm:"not null"`
+	Height      int       `gorm:"not null"`
+	FileSize    int       `gorm:"not null"`
+	ContentType string    `gorm:"not null"`
+	StorageKey  string    `gorm:"not null;index"` // Content-addressed key of the file in the storage backend
+
+	// Foreign Key for Media Asset
+	MediaAssetID uuid.UUID `gorm:"not null;type:uuid;uniqueIndex:idx_media_thumbnail_size"`
+
+	// Relations
+	MediaAsset MediaAsset `gorm:"foreignKey:MediaAssetID;constraint:OnDelete:CASCADE"` // Relation: Connects to the thumbnailed media asset
+}
@@ -1,27 +1,70 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// ErrMessageOwner is returned when a message does not belong to exactly one channel or conversation.
+var ErrMessageOwner = errors.New("a message must belong to exactly one channel or conversation")
+
 // Message table gorm model
+// A message belongs to exactly one server channel or one direct-message conversation.
+// Deleted messages are soft deleted tombstones without content, attachments, reactions or mentions.
+// GORM leaves them out of every query, replies load them unscoped to show that the original was deleted.
+// System messages are generated by the server and reference the message they are about through ReplyTo.
+// Messages posted in a thread keep the owner of the thread's root message and reference it through ThreadID.
+// The composite indexes end with the primary key, so history can be paginated on (created_at, id).
 type Message struct {
 	// Base Fields
-	ID          uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid();index:idx_messages_channel_created,priority:3;index:idx_messages_conversation_created,priority:3;index:idx_messages_thread_created,priority:3"`
 	AuthorID    uuid.UUID   `gorm:"not null;type:uuid"`
 	MessageType MessageType `gorm:"not null"`
-	Content     string      `gorm:"not null;index:idx_content_type_search,priority:1"`
-	CreatedAt   time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+	Content     string      `gorm:"not null"` // Searched through the generated content_tsv column, see runPostMigrations
+	CreatedAt   time.Time   `gorm:"default:CURRENT_TIMESTAMP;index:idx_messages_channel_created,priority:2;index:idx_messages_conversation_created,priority:2;index:idx_messages_thread_created,priority:2"`
 	UpdatedAt   *time.Time  `gorm:"autoUpdateTime"`
 
+	// Deletion tombstone, a deleted message keeps its row so replies can still reference it
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	DeletedByID *uuid.UUID     `gorm:"type:uuid"`
+
+	// Foreign Keys for the owner of the message, exactly one of them is set
+	ChannelID      *uuid.UUID `gorm:"type:uuid;index:idx_messages_channel_created,priority:1"`      // Set for messages in a server channel
+	ConversationID *uuid.UUID `gorm:"type:uuid;index:idx_messages_conversation_created,priority:1"` // Set for messages in a direct-message conversation
+
 	// Foreign Key for Reply
 	ReplyTo *uuid.UUID `gorm:"type:uuid"` // Can be null if not a reply
 
+	// Foreign Key for the thread the message was posted in, the ID of the thread's root message
+	ThreadID *uuid.UUID `gorm:"type:uuid;index:idx_messages_thread_created,priority:1"` // Null for messages outside of threads
+
+	// Pin state, both are set while the message is pinned to its channel, conversation or thread
+	PinnedAt   *time.Time `gorm:"index:idx_messages_pinned,where:pinned_at IS NOT NULL"`
+	PinnedByID *uuid.UUID `gorm:"type:uuid"`
+
 	// Relations
-	Author         User                `gorm:"foreignKey:AuthorID"`  // Relation: A message has one author
-	ReplyToMessage *Message            `gorm:"foreignKey:ReplyTo"`   // Relation: A message can reply to another message
-	Replies        []Message           `gorm:"foreignKey:ReplyTo"`   // Relation: A message can have many replies
-	Attachments    []MessageAttachment `gorm:"foreignKey:MessageID"` // Relation: A message can have many attachments
+	Author         User                `gorm:"foreignKey:AuthorID"`                                  // Relation: A message has one author
+	PinnedBy       *User               `gorm:"foreignKey:PinnedByID"`                                // Relation: A pinned message references the user who pinned it
+	Channel        *Channel            `gorm:"foreignKey:ChannelID"`                                 // Relation: A message can belong to a server channel
+	Conversation   *Conversation       `gorm:"foreignKey:ConversationID"`                            // Relation: A message can belong to a conversation
+	ReplyToMessage *Message            `gorm:"foreignKey:ReplyTo"`                                   // Relation: A message can reply to another message
+	Replies        []Message           `gorm:"foreignKey:ReplyTo"`                                   // Relation: A message can have many replies
+	Attachments    []MessageAttachment `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`     // Relation: A message can have many attachments
+	Thread         *Thread             `gorm:"foreignKey:RootMessageID;constraint:OnDelete:CASCADE"` // Relation: A message can be the root of a thread
+	Reactions      []MessageReaction   `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`     // Relation: A message can have many reactions
+	Mentions       []MessageMention    `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`     // Relation: A message can mention many users and channels
+	Revisions      []MessageRevision   `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`     // Relation: A message keeps its previous contents
+	ThreadMessages []Message           `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"`      // Relation: A thread root has many messages in its thread
+}
+
+// BeforeCreate is a GORM hook that rejects messages without exactly one owner,
+// mirroring the chk_messages_owner database constraint.
+func (m *Message) BeforeCreate(tx *gorm.DB) error {
+	if (m.ChannelID == nil) == (m.ConversationID == nil) {
+		return ErrMessageOwner
+	}
+	return nil
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// MessageMention table gorm model
+// TargetID holds the mentioned user, role or channel, it is uuid.Nil for @everyone and @here.
+// The index on (kind, target_id, message_id) serves the mention inbox of a user.
+type MessageMention struct {
+	// Composite Primary Keys
+	MessageID uuid.UUID   `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index:idx_message_mentions_target,priority:3"`
+	Kind      MentionKind `gorm:"not null;primaryKey;index:idx_message_mentions_target,priority:1"`
+	TargetID  uuid.UUID   `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index:idx_message_mentions_target,priority:2"`
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/signedurl"
+	"github.com/google/uuid"
+)
+
+// PublicUser is the public projection of a user that is safe to show to other users.
+type PublicUser struct {
+	ID                    uuid.UUID  `json:"id"`
+	Username              string     `json:"username"`
+	ProfilePictureAssetID *uuid.UUID `json:"profile_picture_asset_id"`
+}
+
+// NewPublicUser creates the public projection of a user.
+func NewPublicUser(user *User) *PublicUser {
+	return &PublicUser{
+		ID:                    user.ID,
+		Username:              user.Username,
+		ProfilePictureAssetID: user.ProfilePictureAssetID,
+	}
+}
+
+// AttachmentPayload is the JSON representation of a message attachment. Images carry their
+// dimensions, blurhash and thumbnails once processed. The URL paths are signed and expire, clients
+// fetch the message again for new ones.
+type AttachmentPayload struct {
+	ID               uuid.UUID               `json:"id"`
+	MediaAssetID     uuid.UUID               `json:"media_asset_id"`
+	Filename         string                  `json:"filename"`
+	UrlPath          string                  `json:"url_path"`
+	FileSize         int                     `json:"file_size"`
+	MimeType         AssetType               `json:"mime_type"`
+	Width            int                     `json:"width,omitempty"`
+	Height           int                     `json:"height,omitempty"`
+	BlurHash         string                  `json:"blurhash,omitempty"`
+	ProcessingStatus ProcessingStatus        `json:"processing_status"`
+	Thumbnails       []MediaThumbnailPayload `json:"thumbnails,omitempty"`
+}
+
+// ReactionPayload is the aggregated JSON representation of the reactions with one emoji.
+// Emoji is the Unicode emoji or the ID of a custom emoji, Me is set when the caller reacted.
+type ReactionPayload struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	Me    bool   `json:"me"`
+}
+
+// MessagePayload is the JSON representation of a message returned by the API.
+type MessagePayload struct {
+	ID              uuid.UUID           `json:"id"`
+	ChannelID       *uuid.UUID          `json:"channel_id,omitempty"`
+	ConversationID  *uuid.UUID          `json:"conversation_id,omitempty"`
+	AuthorID        uuid.UUID           `json:"author_id"`
+	Author          *PublicUser         `json:"author,omitempty"`
+	MessageType     MessageType         `json:"message_type"`
+	Content         string              `json:"content"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       *time.Time          `json:"updated_at"`
+	ReplyTo         *uuid.UUID          `json:"reply_to"`
+	ReplyToMessage  *MessagePayload     `json:"reply_to_message,omitempty"`
+	ThreadID        *uuid.UUID          `json:"thread_id,omitempty"`
+	Thread          *ThreadPayload      `json:"thread,omitempty"`
+	Pinned          bool                `json:"pinned"`
+	PinnedAt        *time.Time          `json:"pinned_at,omitempty"`
+	PinnedByID      *uuid.UUID          `json:"pinned_by_id,omitempty"`
+	Mentions        []uuid.UUID         `json:"mentions"`
+	MentionChannels []uuid.UUID         `json:"mention_channels"`
+	MentionEveryone bool                `json:"mention_everyone"`
+	Attachments     []AttachmentPayload `json:"attachments"`
+	Reactions       []ReactionPayload   `json:"reactions"`
+}
+
+// NewMessagePayload creates the JSON representation of a message.
+// The author, attachments, mentions and thread summary are included if they were loaded, the replied message is
+// included shallowly: its own replied message and attachments are never expanded. A deleted replied
+// message is included as a tombstone flagged with deleted, its content is empty.
+func NewMessagePayload(message *Message) MessagePayload {
+	payload := MessagePayload{
+		ID:              message.ID,
+		ChannelID:       message.ChannelID,
+		ConversationID:  message.ConversationID,
+		AuthorID:        message.AuthorID,
+		MessageType:     message.MessageType,
+		Content:         message.Content,
+		CreatedAt:       message.CreatedAt,
+		UpdatedAt:       message.UpdatedAt,
+		ReplyTo:         message.ReplyTo,
+		ThreadID:        message.ThreadID,
+		Pinned:          message.PinnedAt != nil,
+		PinnedAt:        message.PinnedAt,
+		PinnedByID:      message.PinnedByID,
+		Attachments:     make([]AttachmentPayload, 0, len(message.Attachments)),
+		Mentions:        []uuid.UUID{},
+		MentionChannels: []uuid.UUID{},
+		Reactions:       []ReactionPayload{},
+	}
+	if message.Author.ID != uuid.Nil {
+		payload.Author = NewPublicUser(&message.Author)
+	}
+	if message.Thread != nil {
+		payload.Thread = NewThreadPayload(message.Thread)
+	}
+	if message.ReplyToMessage != nil {
+		replied := NewMessagePayload(&Message{
+			ID:             message.ReplyToMessage.ID,
+			ChannelID:      message.ReplyToMessage.ChannelID,
+			ConversationID: message.ReplyToMessage.ConversationID,
+			AuthorID:       message.ReplyToMessage.AuthorID,
+			Author:         message.ReplyToMessage.Author,
+			MessageType:    message.ReplyToMessage.MessageType,
+			Content:        message.ReplyToMessage.Content,
+			CreatedAt:      message.ReplyToMessage.CreatedAt,
+			UpdatedAt:      message.ReplyToMessage.UpdatedAt,
+			ReplyTo:        message.ReplyToMessage.ReplyTo,
+			DeletedAt:      message.ReplyToMessage.DeletedAt,
+		})
+		payload.ReplyToMessage = &replied
+	}
+	for _, mention := range message.Mentions {
+		switch mention.Kind {
+		case MentionKindUser:
+			payload.Mentions = append(payload.Mentions, mention.TargetID)
+		case MentionKindChannel:
+			payload.MentionChannels = append(payload.MentionChannels, mention.TargetID)
+		case MentionKindEveryone, MentionKindHere:
+			payload.MentionEveryone = true
+		}
+	}
+	// Attachments are only downloaded by the readers of the message, from URLs that expire.
+	expires := signedurl.Expiry(time.Now())
+	for _, attachment := range message.Attachments {
+		asset := NewMediaAssetPayload(&attachment.MediaAsset)
+		urlPath := signedurl.Sign(asset.UrlPath, expires)
+		for i := range asset.Thumbnails {
+			asset.Thumbnails[i].UrlPath = urlPath + "&size=" + strconv.Itoa(asset.Thumbnails[i].Size)
+		}
+		payload.Attachments = append(payload.Attachments, AttachmentPayload{
+			ID:               attachment.ID,
+			MediaAssetID:     attachment.MediaAssetID,
+			Filename:         asset.Filename,
+			UrlPath:          urlPath,
+			FileSize:         asset.FileSize,
+			MimeType:         asset.MimeType,
+			Width:            asset.Width,
+			Height:           asset.Height,
+			BlurHash:         asset.BlurHash,
+			ProcessingStatus: asset.ProcessingStatus,
+			Thumbnails:       asset.Thumbnails,
+		})
+	}
+	return payload
+}
+
+// ThreadPayload is the JSON representation of a thread returned by the API.
+type ThreadPayload struct {
+	ID                 uuid.UUID  `json:"id"`
+	Name               *string    `json:"name"`
+	CreatorID          uuid.UUID  `json:"creator_id"`
+	ReplyCount         int        `json:"reply_count"`
+	LastReplyAt        *time.Time `json:"last_reply_at"`
+	Archived           bool       `json:"archived"`
+	ArchivedAt         *time.Time `json:"archived_at"`
+	AutoArchiveMinutes int        `json:"auto_archive_minutes"`
+	CreatedAt          time.Time  `json:"created_at"`
+	Following          *bool      `json:"following,omitempty"`
+}
+
+// NewThreadPayload creates the JSON representation of a thread, evaluating its archived state now.
+func NewThreadPayload(thread *Thread) *ThreadPayload {
+	return &ThreadPayload{
+		ID:                 thread.RootMessageID,
+		Name:               thread.Name,
+		CreatorID:          thread.CreatorID,
+		ReplyCount:         thread.ReplyCount,
+		LastReplyAt:        thread.LastReplyAt,
+		Archived:           thread.IsArchived(time.Now()),
+		ArchivedAt:         thread.ArchivedAt,
+		AutoArchiveMinutes: thread.AutoArchiveMinutes,
+		CreatedAt:          thread.CreatedAt,
+	}
+}
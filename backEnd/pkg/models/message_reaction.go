@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageReaction table gorm model
+// Emoji holds the key of the emoji: the sequence of a Unicode emoji or the ID of a custom
+// server emoji. The index on (message_id, emoji, user_id) serves both the aggregated counts
+// and the paginated reactor lists.
+type MessageReaction struct {
+	// Composite Primary Keys (Foreign Keys)
+	MessageID uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index:idx_message_reactions_emoji,priority:1"`
+	UserID    uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index;index:idx_message_reactions_emoji,priority:3"`
+	Emoji     string    `gorm:"not null;primaryKey;size:64;index:idx_message_reactions_emoji,priority:2"`
+
+	// Base Fields
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID"` // Relation: Connects to the reacting user
+}
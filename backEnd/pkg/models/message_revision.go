@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageRevision table gorm model
+// A revision keeps a previous content of a message. One is stored for every edit, holding the
+// content that was replaced, and one when the message is deleted, holding its last content.
+type MessageRevision struct {
+	// Base Fields
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid();index:idx_message_revisions_message,priority:3"`
+	MessageID uuid.UUID `gorm:"not null;type:uuid;index:idx_message_revisions_message,priority:1"`
+	Content   string    `gorm:"not null"`
+	// ReplacedAt is when the content was replaced by an edit or removed by the deletion.
+	ReplacedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_message_revisions_message,priority:2"`
+}
+
+// MessageRevisionPayload is the JSON representation of a message revision returned by the API.
+type MessageRevisionPayload struct {
+	ID         uuid.UUID `json:"id"`
+	MessageID  uuid.UUID `json:"message_id"`
+	Content    string    `json:"content"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// NewMessageRevisionPayload creates the JSON representation of a message revision.
+func NewMessageRevisionPayload(revision *MessageRevision) MessageRevisionPayload {
+	return MessageRevisionPayload{
+		ID:         revision.ID,
+		MessageID:  revision.MessageID,
+		Content:    revision.Content,
+		ReplacedAt: revision.ReplacedAt,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Nullable distinguishes a JSON field that was omitted from one that was explicitly set to null.
+// Set is only true if the field was present in the request body, Value is nil for null.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// UnmarshalJSON marks the field as set and decodes its value, keeping Value nil for null.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		n.Value = nil
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
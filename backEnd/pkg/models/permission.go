@@ -0,0 +1,49 @@
+package models
+
+// Permission is a bit set of actions a server member is allowed to perform.
+// The bit values are persisted in the database, so existing values must never change.
+type Permission int64
+
+const (
+	PermissionViewChannels    Permission = 1 << 0  // Allows reading the channel list of a server
+	PermissionManageChannels  Permission = 1 << 1  // Allows creating, updating and deleting channels
+	PermissionAdministrator   Permission = 1 << 2  // Grants every permission
+	PermissionSendMessages    Permission = 1 << 3  // Allows sending messages in chat channels
+	PermissionManageMessages  Permission = 1 << 4  // Allows deleting messages of other users
+	PermissionAddReactions    Permission = 1 << 5  // Allows reacting to messages
+	PermissionManageEmoji     Permission = 1 << 6  // Allows creating, renaming and deleting custom emoji and stickers
+	PermissionPinMessages     Permission = 1 << 7  // Allows pinning and unpinning messages
+	PermissionMentionEveryone Permission = 1 << 8  // Allows notifying everyone with @everyone and @here
+	PermissionConnect         Permission = 1 << 9  // Allows joining voice channels
+	PermissionSpeak           Permission = 1 << 10 // Allows speaking in voice channels, users without it are suppressed
+
+	// PermissionNone is the empty permission set.
+	PermissionNone Permission = 0
+	// PermissionAll contains every permission bit and is granted to server owners.
+	PermissionAll Permission = ^Permission(0)
+	// PermissionDefaultMember is granted to every member of a server on top of their explicit grants.
+	PermissionDefaultMember Permission = PermissionViewChannels | PermissionSendMessages | PermissionAddReactions | PermissionConnect | PermissionSpeak
+	// PermissionConversationParticipant is granted to every participant of a conversation.
+	PermissionConversationParticipant Permission = PermissionViewChannels | PermissionSendMessages | PermissionAddReactions | PermissionPinMessages
+	// PermissionConversationOwner is granted to the owner of a group conversation.
+	PermissionConversationOwner Permission = PermissionConversationParticipant | PermissionManageMessages
+	// PermissionChannelDeniable contains the permissions a channel can deny to the members of its server.
+	PermissionChannelDeniable Permission = PermissionSendMessages | PermissionAddReactions | PermissionConnect | PermissionSpeak
+)
+
+// Has reports whether the permission set contains every bit of the given flag.
+// Administrators implicitly have every permission.
+func (p Permission) Has(flag Permission) bool {
+	if p&PermissionAdministrator == PermissionAdministrator {
+		return true
+	}
+	return p&flag == flag
+}
+
+// Without removes the denied permissions from the set. Administrators keep every permission.
+func (p Permission) Without(denied Permission) Permission {
+	if p&PermissionAdministrator == PermissionAdministrator {
+		return p
+	}
+	return p &^ denied
+}
@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPermission_Without tests removing channel-denied permissions from a permission set.
+func TestPermission_Without(t *testing.T) {
+	tests := []struct {
+		name   string
+		perms  models.Permission
+		denied models.Permission
+		check  models.Permission
+		want   bool
+	}{
+		{
+			name:   "Allowed: Nothing denied",
+			perms:  models.PermissionDefaultMember,
+			denied: models.PermissionNone,
+			check:  models.PermissionAddReactions,
+			want:   true,
+		},
+		{
+			name:   "Denied: Reactions disabled in the channel",
+			perms:  models.PermissionDefaultMember,
+			denied: models.PermissionAddReactions,
+			check:  models.PermissionAddReactions,
+			want:   false,
+		},
+		{
+			name:   "Allowed: Other permissions are kept",
+			perms:  models.PermissionDefaultMember,
+			denied: models.PermissionAddReactions,
+			check:  models.PermissionViewChannels | models.PermissionSendMessages,
+			want:   true,
+		},
+		{
+			name:   "Allowed: Administrators ignore denied permissions",
+			perms:  models.PermissionAdministrator,
+			denied: models.PermissionChannelDeniable,
+			check:  models.PermissionAddReactions,
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.perms.Without(tt.denied).Has(tt.check))
+		})
+	}
+}
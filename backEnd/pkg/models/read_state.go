@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadState table gorm model
+// A read state marks how far a user has read a channel, conversation or thread. ChannelID holds
+// the ID used in the message routes, so it has no foreign key. Messages after
+// (LastReadAt, LastReadMessageID) are unread, the creation time of the last read message is kept
+// so the position survives when that message is deleted.
+type ReadState struct {
+	// Composite Primary Keys
+	UserID    uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false"`
+	ChannelID uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index"`
+
+	// Base Fields
+	LastReadMessageID uuid.UUID `gorm:"not null;type:uuid"`
+	LastReadAt        time.Time `gorm:"not null"` // Creation time of the last read message
+	UpdatedAt         time.Time `gorm:"autoUpdateTime"`
+}
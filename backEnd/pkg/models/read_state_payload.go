@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadStatePayload is the JSON representation of the read state of a channel, conversation or thread.
+// LastReadMessageID is nil while nothing has been acknowledged, every message counts as unread then.
+// The counts stop at readstate.MAX_UNREAD_COUNT, which clients show as "99+".
+type ReadStatePayload struct {
+	ChannelID         uuid.UUID  `json:"channel_id"`
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"`
+	UnreadCount       int        `json:"unread_count"`
+	MentionCount      int        `json:"mention_count"`
+}
+
+// ServerPayload is the JSON representation of a server in the server list of a user, with the
+// read state of each of its chat channels and the totals used for the server badge.
+type ServerPayload struct {
+	ID           uuid.UUID          `json:"id"`
+	Title        string             `json:"title"`
+	OwnerID      uuid.UUID          `json:"owner_id"`
+	IconAssetID  *uuid.UUID         `json:"icon_asset_id"`
+	Visibility   Visibility         `json:"visibility"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UnreadCount  int                `json:"unread_count"`
+	MentionCount int                `json:"mention_count"`
+	Channels     []ReadStatePayload `json:"channels"`
+}
+
+// NewServerPayload creates the JSON representation of a server without channel read states.
+func NewServerPayload(server *Server) ServerPayload {
+	return ServerPayload{
+		ID:          server.ID,
+		Title:       server.Title,
+		OwnerID:     server.OwnerID,
+		IconAssetID: server.IconAssetID,
+		Visibility:  server.Visibility,
+		CreatedAt:   server.CreatedAt,
+		Channels:    []ReadStatePayload{},
+	}
+}
@@ -19,8 +19,9 @@ type Server struct {
 	IconAssetID *uuid.UUID `gorm:"type:uuid"`
 
 	// Relations
-	Owner       User                `gorm:"foreignKey:OwnerID"`     // Relation: A server has one owner
-	IconAsset   *MediaAsset         `gorm:"foreignKey:IconAssetID"` // Relation: A server has one icon asset
-	Channels    []Channel           `gorm:"foreignKey:ServerID"`    // Relation: A server has many channels
-	ServerUsers []ServerUserConnect `gorm:"foreignKey:ServerID"`    // Relation: A server has many connected users
+	Owner       User                `gorm:"foreignKey:OwnerID"`                              // Relation: A server has one owner
+	IconAsset   *MediaAsset         `gorm:"foreignKey:IconAssetID"`                          // Relation: A server has one icon asset
+	Channels    []Channel           `gorm:"foreignKey:ServerID"`                             // Relation: A server has many channels
+	ServerUsers []ServerUserConnect `gorm:"foreignKey:ServerID"`                             // Relation: A server has many connected users
+	Emoji       []ServerEmoji       `gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE"` // Relation: A server has many custom emoji
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServerEmoji table gorm model
+// A custom emoji or sticker of a server. Emoji and stickers share the names of a server.
+type ServerEmoji struct {
+	// Base Fields
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ServerID  uuid.UUID `json:"server_id" gorm:"not null;type:uuid;uniqueIndex:idx_server_emoji_name,priority:1"`
+	Name      string    `json:"name" gorm:"not null;size:32;uniqueIndex:idx_server_emoji_name,priority:2"`
+	Kind      EmojiKind `json:"kind" gorm:"not null;default:emoji"`
+	Animated  bool      `json:"animated" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+
+	// Foreign Keys for the image and the uploader
+	MediaAssetID uuid.UUID `json:"media_asset_id" gorm:"not null;type:uuid"`
+	UploaderID   uuid.UUID `json:"uploader_id" gorm:"not null;type:uuid"`
+
+	// Relations
+	Server     Server     `json:"-" gorm:"foreignKey:ServerID"`     // Relation: An emoji belongs to one server
+	MediaAsset MediaAsset `json:"-" gorm:"foreignKey:MediaAssetID"` // Relation: An emoji has one image
+	Uploader   User       `json:"-" gorm:"foreignKey:UploaderID"`   // Relation: An emoji was uploaded by one user
+}
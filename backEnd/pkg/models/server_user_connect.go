@@ -10,6 +10,9 @@ type ServerUserConnect struct {
 	ServerID uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false"`
 	UserID   uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false"`
 
+	// Base Fields
+	Permissions Permission `gorm:"not null;default:0"` // Permissions granted on top of PermissionDefaultMember
+
 	// Relations
 	Server Server `gorm:"foreignKey:ServerID"` // Relation: Connects to the server
 	User   User   `gorm:"foreignKey:UserID"`   // Relation: Connects to the user
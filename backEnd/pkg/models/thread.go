@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// THREAD_DEFAULT_AUTO_ARCHIVE_MINUTES is the inactivity after which a new thread is archived.
+const THREAD_DEFAULT_AUTO_ARCHIVE_MINUTES = 1440
+
+// Thread table gorm model
+// A thread is started from a root message and shares its ID. Replies in the thread are
+// messages of the same channel or conversation with ThreadID set to the root message.
+// A thread is archived explicitly, or implicitly after AutoArchiveMinutes without activity.
+// Reopening an archived thread restarts the inactivity timer like a reply does.
+type Thread struct {
+	// Base Fields
+	RootMessageID      uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	Name               *string    `json:"name"`
+	CreatorID          uuid.UUID  `json:"creator_id" gorm:"type:uuid;not null"`
+	ReplyCount         int        `json:"reply_count" gorm:"not null;default:0"`
+	LastReplyAt        *time.Time `json:"last_reply_at"`
+	ArchivedAt         *time.Time `json:"archived_at"`
+	ReopenedAt         *time.Time `json:"-"`
+	AutoArchiveMinutes int        `json:"auto_archive_minutes" gorm:"not null;default:1440"`
+	CreatedAt          time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+
+	// Relations
+	Followers []ThreadFollower `json:"-" gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"` // Relation: A thread has many followers
+}
+
+// LastActivity returns the latest of the creation, the last reply and the last reopening of the thread.
+func (t *Thread) LastActivity() time.Time {
+	last := t.CreatedAt
+	if t.LastReplyAt != nil && t.LastReplyAt.After(last) {
+		last = *t.LastReplyAt
+	}
+	if t.ReopenedAt != nil && t.ReopenedAt.After(last) {
+		last = *t.ReopenedAt
+	}
+	return last
+}
+
+// IsArchived reports whether the thread was archived explicitly or has been inactive for
+// longer than its auto archive duration at the given time.
+func (t *Thread) IsArchived(now time.Time) bool {
+	if t.ArchivedAt != nil {
+		return true
+	}
+	return now.Sub(t.LastActivity()) > time.Duration(t.AutoArchiveMinutes)*time.Minute
+}
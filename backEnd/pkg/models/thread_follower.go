@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ThreadFollower table gorm model
+type ThreadFollower struct {
+	// Composite Primary Keys (Foreign Keys)
+	ThreadID uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false"`
+	UserID   uuid.UUID `gorm:"not null;type:uuid;primaryKey;autoIncrement:false;index"`
+
+	// Base Fields
+	FollowedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID"` // Relation: Connects to the following user
+}
@@ -0,0 +1,67 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestThread_IsArchived tests the explicit and the inactivity based archived state of threads.
+func TestThread_IsArchived(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	lastReply := created.Add(2 * time.Hour)
+	archivedAt := created.Add(time.Minute)
+	reopenedAt := created.Add(5 * time.Hour)
+
+	tests := []struct {
+		name   string
+		thread models.Thread
+		now    time.Time
+		want   bool
+	}{
+		{
+			name:   "Active: New thread within the archive duration",
+			thread: models.Thread{CreatedAt: created, AutoArchiveMinutes: 60},
+			now:    created.Add(59 * time.Minute),
+			want:   false,
+		},
+		{
+			name:   "Archived: New thread without replies after the archive duration",
+			thread: models.Thread{CreatedAt: created, AutoArchiveMinutes: 60},
+			now:    created.Add(61 * time.Minute),
+			want:   true,
+		},
+		{
+			name:   "Active: A reply restarts the archive duration",
+			thread: models.Thread{CreatedAt: created, LastReplyAt: &lastReply, AutoArchiveMinutes: 60},
+			now:    lastReply.Add(30 * time.Minute),
+			want:   false,
+		},
+		{
+			name:   "Archived: Inactive since the last reply",
+			thread: models.Thread{CreatedAt: created, LastReplyAt: &lastReply, AutoArchiveMinutes: 60},
+			now:    lastReply.Add(2 * time.Hour),
+			want:   true,
+		},
+		{
+			name:   "Active: Reopening restarts the archive duration",
+			thread: models.Thread{CreatedAt: created, LastReplyAt: &lastReply, ReopenedAt: &reopenedAt, AutoArchiveMinutes: 60},
+			now:    reopenedAt.Add(30 * time.Minute),
+			want:   false,
+		},
+		{
+			name:   "Archived: Explicitly archived",
+			thread: models.Thread{CreatedAt: created, ArchivedAt: &archivedAt, AutoArchiveMinutes: 60},
+			now:    created.Add(2 * time.Minute),
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.thread.IsArchived(tt.now))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upload table gorm model
+// An upload records a resumable upload in progress. Its chunks are kept by the uploads package
+// until the whole file is received, then it is moved to the storage as a media asset and the
+// upload is removed. Uploads that are not continued before ExpiresAt are removed with their chunks.
+type Upload struct {
+	// Base Fields
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Filename    string    `gorm:"not null"`
+	ContentType string    `gorm:"not null"`
+	Length      int64     `gorm:"not null"`           // Size of the whole file in bytes
+	Received    int64     `gorm:"not null;default:0"` // Bytes received so far, the offset of the upload
+	Checksum    string    `gorm:"not null"`           // Hexadecimal SHA-256 digest of the whole file
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+
+	// Media Type Fields
+	Context string `gorm:"not null;default:'attachment'"` // What the file is uploaded for, the content type is only declared until it is sniffed on completion
+
+	// Foreign Key for Uploader
+	UserID uuid.UUID `gorm:"not null;type:uuid;index"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Relation: Connects to the uploading user
+}
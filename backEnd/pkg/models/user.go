@@ -22,16 +22,18 @@ type User struct {
 	DateOfBirth time.Time  `json:"date_of_birth" gorm:"not null"`
 	Location    *string    `json:"location"`
 	IsVerified  bool       `json:"is_verified" gorm:"default:false"`
+	DMPrivacy   DMPrivacy  `json:"dm_privacy" gorm:"not null;default:everyone"`
 
 	// Foreign Key for Profile Picture
 	ProfilePictureAssetID *uuid.UUID `json:"profile_picture_asset_id" gorm:"type:uuid"`
 
 	// Relations (Has One / Has Many)
-	ProfilePictureAsset    *MediaAsset         `gorm:"foreignKey:ProfilePictureAssetID"` // Relation: A user has one profile picture
-	SentMessages           []Message           `gorm:"foreignKey:AuthorID"`              // Relation: A user sends many messages
-	UserFriendConnectsSent []UserFriendConnect `gorm:"foreignKey:User1ID"`               // Relation: A user initiates many friend connections
-	UserFriendConnectsRecv []UserFriendConnect `gorm:"foreignKey:User2ID"`               // Relation: A user receives many friend connections
-	OwnedServers           []Server            `gorm:"foreignKey:OwnerID"`               // Relation: A user owns many servers
-	ServerUserConnects     []ServerUserConnect `gorm:"foreignKey:UserID"`                // Relation: A user is connected to many servers
-	UploadedMediaAssets    []MediaAsset        `gorm:"foreignKey:UploadedByUserID"`      // Relation: A user uploads many media assets
+	ProfilePictureAsset    *MediaAsset               `gorm:"foreignKey:ProfilePictureAssetID"` // Relation: A user has one profile picture
+	SentMessages           []Message                 `gorm:"foreignKey:AuthorID"`              // Relation: A user sends many messages
+	UserFriendConnectsSent []UserFriendConnect       `gorm:"foreignKey:User1ID"`               // Relation: A user initiates many friend connections
+	UserFriendConnectsRecv []UserFriendConnect       `gorm:"foreignKey:User2ID"`               // Relation: A user receives many friend connections
+	OwnedServers           []Server                  `gorm:"foreignKey:OwnerID"`               // Relation: A user owns many servers
+	ServerUserConnects     []ServerUserConnect       `gorm:"foreignKey:UserID"`                // Relation: A user is connected to many servers
+	UploadedMediaAssets    []MediaAsset              `gorm:"foreignKey:UploadedByUserID"`      // Relation: A user uploads many media assets
+	ConversationLinks      []ConversationParticipant `gorm:"foreignKey:UserID"`                // Relation: A user takes part in many conversations
 }
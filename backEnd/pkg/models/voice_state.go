@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VoiceState table gorm model
+// A voice state records that a user is connected to a voice channel from a gateway session and
+// the state of their microphone, speaker and screen share. A user is connected to one voice
+// channel at a time, the state is removed when they leave or their session expires.
+type VoiceState struct {
+	// Primary Key (Foreign Key)
+	UserID uuid.UUID `json:"user_id" gorm:"not null;type:uuid;primaryKey;autoIncrement:false"`
+
+	// Base Fields
+	SessionID uuid.UUID `json:"session_id" gorm:"not null;type:uuid;uniqueIndex"` // Gateway session the user is connected from
+	// ChannelID is the voice channel, nil in the VOICE_STATE_UPDATE events of users leaving
+	ChannelID *uuid.UUID `json:"channel_id" gorm:"not null;type:uuid;index"`
+	ServerID  uuid.UUID  `json:"server_id" gorm:"not null;type:uuid"`
+	Muted     bool       `json:"muted" gorm:"not null;default:false"`     // The user turned off their microphone
+	Deafened  bool       `json:"deafened" gorm:"not null;default:false"`  // The user turned off their speaker
+	Streaming bool       `json:"streaming" gorm:"not null;default:false"` // The user shares their screen
+	Speaking  bool       `json:"speaking" gorm:"not null;default:false"`  // The user is currently speaking
+	// Suppressed is set when the user lacks the speak permission, their audio is not played
+	Suppressed bool      `json:"suppressed" gorm:"not null;default:false"`
+	JoinedAt   time.Time `json:"joined_at" gorm:"not null"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relations
+	User    User    `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`    // Relation: Connects to the connected user
+	Channel Channel `json:"-" gorm:"foreignKey:ChannelID;constraint:OnDelete:CASCADE"` // Relation: Connects to the voice channel
+}
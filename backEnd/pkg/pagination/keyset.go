@@ -0,0 +1,134 @@
+package pagination
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DEFAULT_LIMIT is the page size used when the request does not specify one.
+	DEFAULT_LIMIT = 50
+	// MAX_LIMIT is the largest page size a client can request.
+	MAX_LIMIT = 100
+)
+
+var (
+	// ErrConflictingCursors is returned when more than one of before, after and around is given.
+	ErrConflictingCursors = errors.New("only one of before, after and around can be used")
+	// ErrInvalidCursor is returned when a cursor is not a valid ID.
+	ErrInvalidCursor = errors.New("the cursor must be a valid ID")
+	// ErrInvalidLimit is returned when the limit is not a number between 1 and MAX_LIMIT.
+	ErrInvalidLimit = errors.New("the limit must be a number between 1 and 100")
+)
+
+// Direction selects which page of a keyset-paginated list is requested.
+type Direction string
+
+const (
+	DirectionLatest Direction = "latest" // The newest items, no cursor given
+	DirectionBefore Direction = "before" // Items older than the cursor
+	DirectionAfter  Direction = "after"  // Items newer than the cursor
+	DirectionAround Direction = "around" // The cursor item and the items around it
+)
+
+// Query is a parsed page request.
+type Query struct {
+	Direction Direction
+	// Cursor is the ID of the item the page is relative to, uuid.Nil for DirectionLatest.
+	Cursor uuid.UUID
+	Limit  int
+}
+
+// Key is the position of an item in a list ordered by creation time, with the ID breaking ties.
+type Key struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// ParseQuery reads the before, after, around and limit query parameters of a page request.
+// params:
+// - values: The query parameters of the request.
+// returns:
+// - Query: The parsed page request, limited to DEFAULT_LIMIT items if no limit is given.
+// - error: ErrConflictingCursors, ErrInvalidCursor or ErrInvalidLimit.
+func ParseQuery(values url.Values) (Query, error) {
+	query := Query{Direction: DirectionLatest, Limit: DEFAULT_LIMIT}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MAX_LIMIT {
+			return Query{}, ErrInvalidLimit
+		}
+		query.Limit = limit
+	}
+
+	for _, direction := range []Direction{DirectionBefore, DirectionAfter, DirectionAround} {
+		raw := values.Get(string(direction))
+		if raw == "" {
+			continue
+		}
+		if query.Direction != DirectionLatest {
+			return Query{}, ErrConflictingCursors
+		}
+		cursor, err := uuid.Parse(raw)
+		if err != nil {
+			return Query{}, ErrInvalidCursor
+		}
+		query.Direction = direction
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
+// AroundSplit returns how many items an around page holds before and after the cursor item.
+// The cursor item itself takes one place of the limit.
+func (q Query) AroundSplit() (before int, after int) {
+	before = q.Limit / 2
+	after = q.Limit - before - 1
+	return before, after
+}
+
+// Link builds the URL of a page relative to the given cursor.
+// params:
+// - path: The path of the list endpoint.
+// - direction: DirectionBefore or DirectionAfter.
+// - cursor: The ID of the item the page is relative to.
+// - limit: The page size.
+// returns:
+// - string: The path with the encoded query parameters.
+func Link(path string, direction Direction, cursor uuid.UUID, limit int) string {
+	values := url.Values{}
+	values.Set(string(direction), cursor.String())
+	values.Set("limit", strconv.Itoa(limit))
+	return path + "?" + values.Encode()
+}
+
+// Older restricts a query to the items strictly older than the key, newest first.
+// The row comparison lets the database walk a (created_at, id) index instead of using OFFSET.
+func Older(query *gorm.DB, key Key, limit int) *gorm.DB {
+	return query.
+		Where("(created_at, id) < (?, ?)", key.CreatedAt, key.ID).
+		Order("created_at DESC, id DESC").
+		Limit(limit)
+}
+
+// Newer restricts a query to the items strictly newer than the key, oldest first.
+func Newer(query *gorm.DB, key Key, limit int) *gorm.DB {
+	return query.
+		Where("(created_at, id) > (?, ?)", key.CreatedAt, key.ID).
+		Order("created_at ASC, id ASC").
+		Limit(limit)
+}
+
+// Latest restricts a query to the newest items, newest first.
+func Latest(query *gorm.DB, limit int) *gorm.DB {
+	return query.
+		Order("created_at DESC, id DESC").
+		Limit(limit)
+}
@@ -0,0 +1,98 @@
+package pagination_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/pagination"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseQuery tests the ParseQuery function.
+func TestParseQuery(t *testing.T) {
+	cursor := uuid.New()
+
+	tests := []struct {
+		name    string
+		values  url.Values
+		want    pagination.Query
+		wantErr error
+	}{
+		{
+			name:   "Valid: No parameters",
+			values: url.Values{},
+			want:   pagination.Query{Direction: pagination.DirectionLatest, Limit: pagination.DEFAULT_LIMIT},
+		},
+		{
+			name:   "Valid: Before with limit",
+			values: url.Values{"before": {cursor.String()}, "limit": {"20"}},
+			want:   pagination.Query{Direction: pagination.DirectionBefore, Cursor: cursor, Limit: 20},
+		},
+		{
+			name:   "Valid: After",
+			values: url.Values{"after": {cursor.String()}},
+			want:   pagination.Query{Direction: pagination.DirectionAfter, Cursor: cursor, Limit: pagination.DEFAULT_LIMIT},
+		},
+		{
+			name:   "Valid: Around with maximum limit",
+			values: url.Values{"around": {cursor.String()}, "limit": {"100"}},
+			want:   pagination.Query{Direction: pagination.DirectionAround, Cursor: cursor, Limit: pagination.MAX_LIMIT},
+		},
+		{
+			name:    "Invalid: Before and after",
+			values:  url.Values{"before": {cursor.String()}, "after": {cursor.String()}},
+			wantErr: pagination.ErrConflictingCursors,
+		},
+		{
+			name:    "Invalid: Malformed cursor",
+			values:  url.Values{"around": {"not-a-uuid"}},
+			wantErr: pagination.ErrInvalidCursor,
+		},
+		{
+			name:    "Invalid: Limit above maximum",
+			values:  url.Values{"limit": {"101"}},
+			wantErr: pagination.ErrInvalidLimit,
+		},
+		{
+			name:    "Invalid: Zero limit",
+			values:  url.Values{"limit": {"0"}},
+			wantErr: pagination.ErrInvalidLimit,
+		},
+		{
+			name:    "Invalid: Limit not a number",
+			values:  url.Values{"limit": {"ten"}},
+			wantErr: pagination.ErrInvalidLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pagination.ParseQuery(tt.values)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestQuery_AroundSplit tests that an around page always fills the limit including the cursor item.
+func TestQuery_AroundSplit(t *testing.T) {
+	for _, limit := range []int{1, 2, 3, 50, 99, 100} {
+		before, after := pagination.Query{Limit: limit}.AroundSplit()
+		assert.Equal(t, limit, before+after+1, "limit %d", limit)
+		assert.GreaterOrEqual(t, after, 0, "limit %d", limit)
+	}
+}
+
+// TestLink tests that Link encodes the cursor and the limit.
+func TestLink(t *testing.T) {
+	cursor := uuid.MustParse("6738e4eb-f36c-4ac7-8e2a-34157f3eeb66")
+
+	link := pagination.Link("/api/channels/x/messages", pagination.DirectionBefore, cursor, 25)
+
+	assert.Equal(t, "/api/channels/x/messages?before=6738e4eb-f36c-4ac7-8e2a-34157f3eeb66&limit=25", link)
+}
@@ -0,0 +1,150 @@
+package permissions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrConversationNotFound is returned when the requested conversation does not exist.
+	ErrConversationNotFound = errors.New("conversation not found")
+	// ErrNotParticipant is returned when the user does not take part in the conversation.
+	ErrNotParticipant = errors.New("user is not a participant of the conversation")
+	// ErrDirectMessagesClosed is returned when the privacy settings of a user do not allow the sender to reach them.
+	ErrDirectMessagesClosed = errors.New("the user only accepts direct messages from friends")
+	// ErrUserBlocked is returned when one of the users has blocked the other.
+	ErrUserBlocked = errors.New("direct messages are not possible between these users")
+)
+
+// ForConversation resolves a conversation and the permissions of a user in it.
+// Participants are granted PermissionConversationParticipant, the owner of a group
+// conversation PermissionConversationOwner.
+// params:
+// - db: The GORM database instance.
+// - conversationID: The ID of the conversation.
+// - userID: The ID of the user.
+// returns:
+// - *models.Conversation: The conversation, without relations.
+// - models.Permission: The effective permission set of the user.
+// - error: ErrConversationNotFound, ErrNotParticipant or a wrapped database error.
+func ForConversation(db *gorm.DB, conversationID uuid.UUID, userID uuid.UUID) (*models.Conversation, models.Permission, error) {
+	var conversation models.Conversation
+	if err := db.First(&conversation, "id = ?", conversationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.PermissionNone, ErrConversationNotFound
+		}
+		return nil, models.PermissionNone, fmt.Errorf("failed to fetch conversation: %w", err)
+	}
+
+	var count int64
+	err := db.Model(&models.ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Count(&count).Error
+	if err != nil {
+		return nil, models.PermissionNone, fmt.Errorf("failed to fetch conversation participant: %w", err)
+	}
+	if count == 0 {
+		return nil, models.PermissionNone, ErrNotParticipant
+	}
+
+	if conversation.OwnerID != nil && *conversation.OwnerID == userID {
+		return &conversation, models.PermissionConversationOwner, nil
+	}
+	return &conversation, models.PermissionConversationParticipant, nil
+}
+
+// CanDirectMessage checks whether the sender may start a direct message with the recipient
+// or add them to a group conversation.
+// A blocked friendship in either direction always prevents it, and recipients with
+// DMPrivacyFriends only accept users they have an accepted friendship with.
+// params:
+// - db: The GORM database instance.
+// - senderID: The ID of the user starting the conversation.
+// - recipient: The user being contacted, with DMPrivacy loaded.
+// returns:
+// - error: ErrUserBlocked, ErrDirectMessagesClosed or a wrapped database error, nil if allowed.
+func CanDirectMessage(db *gorm.DB, senderID uuid.UUID, recipient *models.User) error {
+	var connections []models.UserFriendConnect
+	err := db.Select("user1_id", "user2_id", "status").
+		Where("(user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)", senderID, recipient.ID, recipient.ID, senderID).
+		Find(&connections).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch friendship: %w", err)
+	}
+
+	friends := false
+	for _, connection := range connections {
+		switch connection.Status {
+		case models.StatusBlocked:
+			return ErrUserBlocked
+		case models.StatusAccepted:
+			friends = true
+		}
+	}
+
+	if recipient.DMPrivacy == models.DMPrivacyFriends && !friends {
+		return ErrDirectMessagesClosed
+	}
+	return nil
+}
+
+// CanMessageConversation checks whether the sender may post in a conversation. Direct
+// conversations apply CanDirectMessage to the other participant on every message, so a block or a
+// change of privacy settings also closes conversations that already exist. Group conversations
+// were checked when the participants were added.
+// params:
+// - db: The GORM database instance.
+// - conversation: The conversation, without relations.
+// - senderID: The ID of the participant posting.
+// returns:
+// - error: The errors of CanDirectMessage, nil if allowed.
+func CanMessageConversation(db *gorm.DB, conversation *models.Conversation, senderID uuid.UUID) error {
+	if conversation.Type != models.ConversationTypeDirect {
+		return nil
+	}
+	var recipients []models.User
+	err := db.Select("users.id", "users.dm_privacy").
+		Joins("JOIN conversation_participants ON conversation_participants.user_id = users.id").
+		Where("conversation_participants.conversation_id = ? AND users.id <> ?", conversation.ID, senderID).
+		Find(&recipients).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch conversation recipient: %w", err)
+	}
+	for i := range recipients {
+		if err := CanDirectMessage(db, senderID, &recipients[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BlockedUsers lists the users a user has blocked or is blocked by.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// returns:
+// - []uuid.UUID: The IDs of the users with a blocked friendship with the user, in either direction.
+// - error: A wrapped database error.
+func BlockedUsers(db *gorm.DB, userID uuid.UUID) ([]uuid.UUID, error) {
+	var connections []models.UserFriendConnect
+	err := db.Select("user1_id", "user2_id").
+		Where("(user1_id = ? OR user2_id = ?) AND status = ?", userID, userID, models.StatusBlocked).
+		Find(&connections).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blocked users: %w", err)
+	}
+
+	blocked := make([]uuid.UUID, 0, len(connections))
+	for _, connection := range connections {
+		if connection.User1ID == userID {
+			blocked = append(blocked, connection.User2ID)
+		} else {
+			blocked = append(blocked, connection.User1ID)
+		}
+	}
+	return blocked, nil
+}
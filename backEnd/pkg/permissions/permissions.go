@@ -0,0 +1,51 @@
+package permissions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrServerNotFound is returned when the requested server does not exist.
+	ErrServerNotFound = errors.New("server not found")
+	// ErrNotMember is returned when the user is neither the owner nor a member of the server.
+	ErrNotMember = errors.New("user is not a member of the server")
+)
+
+// ForServer resolves the effective permissions of a user in a server.
+// The server owner is granted PermissionAll, members get PermissionDefaultMember
+// combined with their explicit grants stored on ServerUserConnect.
+// params:
+// - db: The GORM database instance.
+// - serverID: The ID of the server.
+// - userID: The ID of the user.
+// returns:
+// - models.Permission: The effective permission set of the user.
+// - error: ErrServerNotFound, ErrNotMember or a wrapped database error.
+func ForServer(db *gorm.DB, serverID uuid.UUID, userID uuid.UUID) (models.Permission, error) {
+	var server models.Server
+	if err := db.Select("id", "owner_id").First(&server, "id = ?", serverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.PermissionNone, ErrServerNotFound
+		}
+		return models.PermissionNone, fmt.Errorf("failed to fetch server: %w", err)
+	}
+	if server.OwnerID == userID {
+		return models.PermissionAll, nil
+	}
+
+	var membership models.ServerUserConnect
+	err := db.Select("server_id", "user_id", "permissions").
+		First(&membership, "server_id = ? AND user_id = ?", serverID, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.PermissionNone, ErrNotMember
+		}
+		return models.PermissionNone, fmt.Errorf("failed to fetch server membership: %w", err)
+	}
+	return models.PermissionDefaultMember | membership.Permissions, nil
+}
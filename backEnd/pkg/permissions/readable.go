@@ -0,0 +1,39 @@
+package permissions
+
+import (
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MemberServers returns a subquery selecting the IDs of the servers a user owns or is a member of.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// returns:
+// - *gorm.DB: The subquery, to be used as the argument of an IN condition.
+func MemberServers(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	memberships := db.Model(&models.ServerUserConnect{}).Select("server_id").Where("user_id = ?", userID)
+	return db.Model(&models.Server{}).Select("id").Where("owner_id = ? OR id IN (?)", userID, memberships)
+}
+
+// ReadableChannels returns a subquery selecting the IDs of the server channels a user can read.
+// Every member can view every channel of their servers, channels cannot deny viewing.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// returns:
+// - *gorm.DB: The subquery, to be used as the argument of an IN condition.
+func ReadableChannels(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.Channel{}).Select("id").Where("server_id IN (?)", MemberServers(db, userID))
+}
+
+// ReadableConversations returns a subquery selecting the IDs of the conversations a user takes part in.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// returns:
+// - *gorm.DB: The subquery, to be used as the argument of an IN condition.
+func ReadableConversations(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.ConversationParticipant{}).Select("conversation_id").Where("user_id = ?", userID)
+}
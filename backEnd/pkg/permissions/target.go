@@ -0,0 +1,203 @@
+package permissions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTargetNotFound is returned when no channel or conversation exists with the given ID.
+	ErrTargetNotFound = errors.New("channel or conversation not found")
+	// ErrNotChatChannel is returned when messages are requested for a channel that cannot hold messages.
+	ErrNotChatChannel = errors.New("messages can only be posted in chat channels")
+)
+
+// Target is a place messages live in: a chat channel of a server, a direct-message conversation
+// or a thread started in one of them. Exactly one of ChannelID and ConversationID is set, threads
+// additionally set ThreadID and share the owner and the permissions of their root message.
+type Target struct {
+	ChannelID      *uuid.UUID
+	ConversationID *uuid.UUID
+	// ServerID is the server of the channel, nil for conversations.
+	ServerID *uuid.UUID
+	// Channel is the resolved channel, nil for conversations.
+	Channel *models.Channel
+	// Conversation is the resolved conversation, nil for channels.
+	Conversation *models.Conversation
+	// ThreadID is the ID of the thread's root message, nil outside of threads.
+	ThreadID *uuid.UUID
+	// Thread is the resolved thread, nil outside of threads.
+	Thread *models.Thread
+	// Permissions are the effective permissions of the user in the target.
+	Permissions models.Permission
+}
+
+// ID returns the ID of the thread, channel or conversation.
+func (t *Target) ID() uuid.UUID {
+	if t.ThreadID != nil {
+		return *t.ThreadID
+	}
+	if t.ChannelID != nil {
+		return *t.ChannelID
+	}
+	return *t.ConversationID
+}
+
+// Scope restricts a message query to the messages of the target.
+// Messages posted in threads are only part of their thread, not of the channel or conversation.
+func (t *Target) Scope(query *gorm.DB) *gorm.DB {
+	if t.ThreadID != nil {
+		return query.Where("thread_id = ?", *t.ThreadID)
+	}
+	if t.ChannelID != nil {
+		return query.Where("channel_id = ? AND thread_id IS NULL", *t.ChannelID)
+	}
+	return query.Where("conversation_id = ? AND thread_id IS NULL", *t.ConversationID)
+}
+
+// Assign sets the owner of a message to the target.
+func (t *Target) Assign(message *models.Message) {
+	message.ChannelID = t.ChannelID
+	message.ConversationID = t.ConversationID
+	message.ThreadID = t.ThreadID
+}
+
+// ResolveTarget resolves the chat channel, conversation or thread with the given ID and the
+// permissions of the user in it. They share the message routes, so the ID is looked up as a
+// channel first, as a conversation second and as a thread last.
+// params:
+// - db: The GORM database instance.
+// - id: The ID of the channel, conversation or thread.
+// - userID: The ID of the user.
+// returns:
+// - *Target: The resolved target.
+// - error: ErrTargetNotFound, ErrNotChatChannel, ErrNotMember, ErrNotParticipant or a wrapped database error.
+func ResolveTarget(db *gorm.DB, id uuid.UUID, userID uuid.UUID) (*Target, error) {
+	target, err := channelTarget(db, id, userID)
+	if errors.Is(err, ErrTargetNotFound) {
+		target, err = conversationTarget(db, id, userID)
+	}
+	if errors.Is(err, ErrTargetNotFound) {
+		target, err = threadTarget(db, id, userID)
+	}
+	return target, err
+}
+
+// ResolveMessageTarget resolves the target the given message belongs to.
+// params:
+// - db: The GORM database instance.
+// - message: The message, with ChannelID, ConversationID and ThreadID loaded.
+// - userID: The ID of the user.
+// returns:
+// - *Target: The resolved target. Thread is not loaded for messages posted in threads.
+// - error: The errors of ResolveTarget.
+func ResolveMessageTarget(db *gorm.DB, message *models.Message, userID uuid.UUID) (*Target, error) {
+	var target *Target
+	var err error
+	switch {
+	case message.ChannelID != nil:
+		target, err = channelTarget(db, *message.ChannelID, userID)
+	case message.ConversationID != nil:
+		target, err = conversationTarget(db, *message.ConversationID, userID)
+	default:
+		return nil, ErrTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	target.ThreadID = message.ThreadID
+	return target, nil
+}
+
+// ResolveThread resolves the thread with the given root message ID and the permissions of
+// the user in it, which are those of the channel or conversation of the root message.
+// params:
+// - db: The GORM database instance.
+// - threadID: The ID of the thread, which is the ID of its root message.
+// - userID: The ID of the user.
+// returns:
+// - *Target: The resolved target with Thread loaded.
+// - error: The errors of ResolveTarget, ErrTargetNotFound when the thread does not exist.
+func ResolveThread(db *gorm.DB, threadID uuid.UUID, userID uuid.UUID) (*Target, error) {
+	return threadTarget(db, threadID, userID)
+}
+
+// channelTarget resolves the chat channel with the given ID as a message target.
+// The permissions denied by the channel are removed from the permissions in the server.
+func channelTarget(db *gorm.DB, id uuid.UUID, userID uuid.UUID) (*Target, error) {
+	var channel models.Channel
+	if err := db.First(&channel, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch channel: %w", err)
+	}
+	if channel.Type != models.ChannelTypeChat {
+		return nil, ErrNotChatChannel
+	}
+
+	perms, err := ForServer(db, channel.ServerID, userID)
+	if err != nil {
+		if errors.Is(err, ErrServerNotFound) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, err
+	}
+
+	return &Target{
+		ChannelID:   &channel.ID,
+		ServerID:    &channel.ServerID,
+		Channel:     &channel,
+		Permissions: perms.Without(channel.DeniedPermissions),
+	}, nil
+}
+
+// conversationTarget resolves the conversation with the given ID as a message target.
+func conversationTarget(db *gorm.DB, id uuid.UUID, userID uuid.UUID) (*Target, error) {
+	conversation, perms, err := ForConversation(db, id, userID)
+	if err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, err
+	}
+
+	return &Target{
+		ConversationID: &conversation.ID,
+		Conversation:   conversation,
+		Permissions:    perms,
+	}, nil
+}
+
+// threadTarget resolves the thread with the given root message ID as a message target.
+// The permissions are those of the channel or conversation of the root message, deleted or not.
+func threadTarget(db *gorm.DB, id uuid.UUID, userID uuid.UUID) (*Target, error) {
+	var thread models.Thread
+	if err := db.First(&thread, "root_message_id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch thread: %w", err)
+	}
+
+	// The root message can be a tombstone, the thread outlives it.
+	var root models.Message
+	if err := db.Unscoped().Select("id", "channel_id", "conversation_id").First(&root, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch thread root message: %w", err)
+	}
+
+	target, err := ResolveMessageTarget(db, &root, userID)
+	if err != nil {
+		return nil, err
+	}
+	target.ThreadID = &thread.RootMessageID
+	target.Thread = &thread
+	return target, nil
+}
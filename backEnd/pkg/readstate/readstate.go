@@ -0,0 +1,143 @@
+// Package readstate computes the unread message and mention counts of channels, conversations
+// and threads for a user from their read states.
+package readstate
+
+import (
+	"fmt"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/permissions"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MAX_UNREAD_COUNT is the largest unread and mention count reported for a channel, conversation or
+// thread. Counting stops there, so channels the user never opened do not count their whole history,
+// and clients show the limit as "99+".
+const MAX_UNREAD_COUNT = 100
+
+// countsQuery counts the unread messages and mentions of several channels, conversations or threads
+// in one query. The placeholders are the ID column and the table of the targets, the ID column
+// again and twice the condition selecting the messages of a target. Messages of the user and
+// deleted messages never count as unread. Only the newest MAX_UNREAD_COUNT unread messages of each
+// target are read, through the (target, created_at, id) indexes. Mentions are counted on their own
+// up to MAX_UNREAD_COUNT among all unread messages, so older mentions still count once the newest
+// messages are unread.
+const countsQuery = `
+SELECT t.id AS channel_id,
+	rs.last_read_message_id AS last_read_message_id,
+	rs.last_read_at AS last_read_at,
+	COUNT(u.id) AS unread_count,
+	mc.mention_count AS mention_count
+FROM (SELECT %s AS id FROM %s WHERE %s IN @ids) t
+LEFT JOIN read_states rs ON rs.channel_id = t.id AND rs.user_id = @user
+LEFT JOIN LATERAL (
+	SELECT m.id FROM messages m
+	WHERE %s
+		AND m.author_id <> @user
+		AND m.deleted_at IS NULL
+		AND (rs.user_id IS NULL OR (m.created_at, m.id) > (rs.last_read_at, rs.last_read_message_id))
+	ORDER BY m.created_at DESC, m.id DESC
+	LIMIT @limit
+) u ON true
+CROSS JOIN LATERAL (
+	SELECT COUNT(*) AS mention_count FROM (
+		SELECT DISTINCT m.id FROM message_mentions mm
+		JOIN messages m ON m.id = mm.message_id
+		WHERE ((mm.kind = @user_kind AND mm.target_id = @user) OR mm.kind IN @everyone_kinds)
+			AND %s
+			AND m.author_id <> @user
+			AND m.deleted_at IS NULL
+			AND (rs.user_id IS NULL OR (m.created_at, m.id) > (rs.last_read_at, rs.last_read_message_id))
+		LIMIT @limit
+	) mentioned
+) mc
+GROUP BY t.id, rs.last_read_message_id, rs.last_read_at, mc.mention_count`
+
+// ForChannels returns the read states of server channels, messages posted in threads are not counted.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// - channelIDs: The IDs of the channels.
+// returns:
+// - map[uuid.UUID]models.ReadStatePayload: The read states by channel ID.
+// - error: A wrapped database error.
+func ForChannels(db *gorm.DB, userID uuid.UUID, channelIDs []uuid.UUID) (map[uuid.UUID]models.ReadStatePayload, error) {
+	return counts(db, userID, channelIDs, "channels", "id", "m.channel_id = t.id AND m.thread_id IS NULL")
+}
+
+// ForConversations returns the read states of conversations, messages posted in threads are not counted.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// - conversationIDs: The IDs of the conversations.
+// returns:
+// - map[uuid.UUID]models.ReadStatePayload: The read states by conversation ID.
+// - error: A wrapped database error.
+func ForConversations(db *gorm.DB, userID uuid.UUID, conversationIDs []uuid.UUID) (map[uuid.UUID]models.ReadStatePayload, error) {
+	return counts(db, userID, conversationIDs, "conversations", "id", "m.conversation_id = t.id AND m.thread_id IS NULL")
+}
+
+// ForThreads returns the read states of threads.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// - threadIDs: The IDs of the threads, the IDs of their root messages.
+// returns:
+// - map[uuid.UUID]models.ReadStatePayload: The read states by thread ID.
+// - error: A wrapped database error.
+func ForThreads(db *gorm.DB, userID uuid.UUID, threadIDs []uuid.UUID) (map[uuid.UUID]models.ReadStatePayload, error) {
+	return counts(db, userID, threadIDs, "threads", "root_message_id", "m.thread_id = t.id")
+}
+
+// ForTarget returns the read state of the channel, conversation or thread messages are posted in.
+// params:
+// - db: The GORM database instance.
+// - userID: The ID of the user.
+// - target: The resolved channel, conversation or thread.
+// returns:
+// - models.ReadStatePayload: The read state of the target.
+// - error: A wrapped database error.
+func ForTarget(db *gorm.DB, userID uuid.UUID, target *permissions.Target) (models.ReadStatePayload, error) {
+	ids := []uuid.UUID{target.ID()}
+	var states map[uuid.UUID]models.ReadStatePayload
+	var err error
+	switch {
+	case target.ThreadID != nil:
+		states, err = ForThreads(db, userID, ids)
+	case target.ChannelID != nil:
+		states, err = ForChannels(db, userID, ids)
+	default:
+		states, err = ForConversations(db, userID, ids)
+	}
+	if err != nil {
+		return models.ReadStatePayload{}, err
+	}
+	state := states[target.ID()]
+	state.ChannelID = target.ID()
+	return state, nil
+}
+
+// counts runs countsQuery for the given targets. Targets without any message still get a
+// read state with zero counts.
+func counts(db *gorm.DB, userID uuid.UUID, ids []uuid.UUID, table string, idColumn string, messageJoin string) (map[uuid.UUID]models.ReadStatePayload, error) {
+	states := make(map[uuid.UUID]models.ReadStatePayload, len(ids))
+	if len(ids) == 0 {
+		return states, nil
+	}
+	var rows []models.ReadStatePayload
+	err := db.Raw(fmt.Sprintf(countsQuery, idColumn, table, idColumn, messageJoin, messageJoin), map[string]interface{}{
+		"user":           userID,
+		"ids":            ids,
+		"limit":          MAX_UNREAD_COUNT,
+		"user_kind":      models.MentionKindUser,
+		"everyone_kinds": []models.MentionKind{models.MentionKindEveryone, models.MentionKindHere},
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+	for _, row := range rows {
+		states[row.ChannelID] = row
+	}
+	return states, nil
+}
@@ -0,0 +1,88 @@
+package readstate_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/database"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/readstate"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestCounts tests counting the unread messages and mentions of conversations in the database of
+// DATABASE_URL.
+func TestCounts(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	database.Migrate(db, false)
+
+	author := models.User{ID: uuid.New(), Username: "readstate", Email: uuid.NewString() + "@example.com", Password: "hash", DateOfBirth: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, db.Create(&author).Error)
+	t.Cleanup(func() { db.Delete(&models.User{}, "id = ?", author.ID) })
+	var conversations [2]models.Conversation
+	for i := range conversations {
+		conversations[i] = models.Conversation{ID: uuid.New(), Type: models.ConversationTypeGroup}
+		require.NoError(t, db.Create(&conversations[i]).Error)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&models.Message{}, "conversation_id = ?", conversations[0].ID)
+		db.Delete(&models.Conversation{}, "id IN ?", []uuid.UUID{conversations[0].ID, conversations[1].ID})
+	})
+
+	// More messages than are counted, the mentions are on the oldest of them.
+	reader := uuid.New()
+	start := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	messages := make([]models.Message, readstate.MAX_UNREAD_COUNT+50)
+	for i := range messages {
+		messages[i] = models.Message{
+			ID:             uuid.New(),
+			AuthorID:       author.ID,
+			MessageType:    models.MessageTypeText,
+			Content:        "hello",
+			CreatedAt:      start.Add(time.Duration(i) * time.Second),
+			ConversationID: &conversations[0].ID,
+		}
+	}
+	require.NoError(t, db.Create(&messages).Error)
+	mentions := []models.MessageMention{
+		{MessageID: messages[0].ID, Kind: models.MentionKindUser, TargetID: reader},
+		// A message mentioning the reader twice counts once.
+		{MessageID: messages[2].ID, Kind: models.MentionKindUser, TargetID: reader},
+		{MessageID: messages[2].ID, Kind: models.MentionKindEveryone, TargetID: uuid.Nil},
+		{MessageID: messages[3].ID, Kind: models.MentionKindHere, TargetID: uuid.Nil},
+		// Deleted messages and mentions of someone else do not count.
+		{MessageID: messages[4].ID, Kind: models.MentionKindUser, TargetID: reader},
+		{MessageID: messages[5].ID, Kind: models.MentionKindUser, TargetID: author.ID},
+	}
+	require.NoError(t, db.Create(&mentions).Error)
+	require.NoError(t, db.Delete(&messages[4]).Error)
+
+	ids := []uuid.UUID{conversations[0].ID, conversations[1].ID}
+	states, err := readstate.ForConversations(db, reader, ids)
+	require.NoError(t, err)
+	assert.Equal(t, readstate.MAX_UNREAD_COUNT, states[conversations[0].ID].UnreadCount)
+	assert.Equal(t, 3, states[conversations[0].ID].MentionCount)
+	assert.Nil(t, states[conversations[0].ID].LastReadMessageID)
+	assert.Zero(t, states[conversations[1].ID].UnreadCount)
+	assert.Zero(t, states[conversations[1].ID].MentionCount)
+
+	// Messages up to the last read one no longer count.
+	require.NoError(t, db.Create(&models.ReadState{UserID: reader, ChannelID: conversations[0].ID, LastReadMessageID: messages[2].ID, LastReadAt: messages[2].CreatedAt}).Error)
+	t.Cleanup(func() { db.Delete(&models.ReadState{}, "user_id = ?", reader) })
+	states, err = readstate.ForConversations(db, reader, ids)
+	require.NoError(t, err)
+	assert.Equal(t, readstate.MAX_UNREAD_COUNT, states[conversations[0].ID].UnreadCount)
+	assert.Equal(t, 1, states[conversations[0].ID].MentionCount)
+	assert.Equal(t, &messages[2].ID, states[conversations[0].ID].LastReadMessageID)
+}
@@ -1,8 +1,19 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/413ksz/BlueFox/backEnd/pkg/handlers"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/channel"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/conversation"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/emoji"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/gateway"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/media"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/message"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/server"
+	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/thread"
 	"github.com/413ksz/BlueFox/backEnd/pkg/handlers/user"
+	"github.com/413ksz/BlueFox/backEnd/pkg/middleware"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
 )
@@ -20,6 +31,60 @@ func RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/user/{id}", handlers.TestHandler).Methods("DELETE")
 	r.HandleFunc("/api/user/login", user.UserLoginHandler).Methods("POST")
 	r.HandleFunc("/api/user/{id}", user.UserUpdateHandler).Methods("PATCH")
+	// The gateway and the event stream verify the token themselves, as browsers send it in the query string
+	r.HandleFunc("/api/gateway", gateway.GatewayConnectHandler).Methods("GET")
+	r.HandleFunc("/api/events/stream", gateway.GatewayStreamHandler).Methods("GET")
+	// Browsers load media in img and video tags without the token. Message attachments need a signed,
+	// expiring URL, other media are public behind their random asset IDs
+	r.HandleFunc("/api/media/{id}", media.MediaDownloadHandler).Methods("GET")
+
+	// Routes below require a valid JWT token
+	r.Handle("/api/servers/{id}/channels", authenticated(channel.ChannelListHandler)).Methods("GET")
+	r.Handle("/api/servers/{id}/channels", authenticated(channel.ChannelCreateHandler)).Methods("POST")
+	// The order route must be registered before the {channelId} routes, which would otherwise match "order"
+	r.Handle("/api/servers/{id}/channels/order", authenticated(channel.ChannelOrderHandler)).Methods("PATCH")
+	r.Handle("/api/servers/{id}/channels/{channelId}", authenticated(channel.ChannelUpdateHandler)).Methods("PATCH")
+	r.Handle("/api/servers/{id}/channels/{channelId}", authenticated(channel.ChannelDeleteHandler)).Methods("DELETE")
+	r.Handle("/api/servers/{id}/channels/{channelId}/voice", authenticated(channel.ChannelVoiceListHandler)).Methods("GET")
+	r.Handle("/api/servers/{id}/emoji", authenticated(emoji.EmojiListHandler)).Methods("GET")
+	r.Handle("/api/servers/{id}/emoji", authenticated(emoji.EmojiCreateHandler)).Methods("POST")
+	r.Handle("/api/servers/{id}/emoji/{emojiId}", authenticated(emoji.EmojiUpdateHandler)).Methods("PATCH")
+	r.Handle("/api/servers/{id}/emoji/{emojiId}", authenticated(emoji.EmojiDeleteHandler)).Methods("DELETE")
+	r.Handle("/api/channels/{id}/messages", authenticated(message.MessageListHandler)).Methods("GET")
+	r.Handle("/api/channels/{id}/messages", authenticated(message.MessageCreateHandler)).Methods("POST")
+	r.Handle("/api/channels/{id}/ack", authenticated(message.MessageAckHandler)).Methods("POST")
+	r.Handle("/api/channels/{id}/typing", authenticated(message.MessageTypingHandler)).Methods("POST")
+	r.Handle("/api/channels/{id}/pins", authenticated(message.MessagePinListHandler)).Methods("GET")
+	r.Handle("/api/channels/{id}/pins/{messageId}", authenticated(message.MessagePinAddHandler)).Methods("PUT")
+	r.Handle("/api/channels/{id}/pins/{messageId}", authenticated(message.MessagePinRemoveHandler)).Methods("DELETE")
+	r.Handle("/api/messages/{id}", authenticated(message.MessageUpdateHandler)).Methods("PATCH")
+	r.Handle("/api/messages/{id}", authenticated(message.MessageDeleteHandler)).Methods("DELETE")
+	r.Handle("/api/messages/{id}/history", authenticated(message.MessageHistoryHandler)).Methods("GET")
+	r.Handle("/api/messages/{id}/reactions/{emoji}", authenticated(message.MessageReactionListHandler)).Methods("GET")
+	r.Handle("/api/messages/{id}/reactions/{emoji}", authenticated(message.MessageReactionAddHandler)).Methods("PUT")
+	r.Handle("/api/messages/{id}/reactions/{emoji}", authenticated(message.MessageReactionRemoveHandler)).Methods("DELETE")
+	r.Handle("/api/search/messages", authenticated(message.MessageSearchHandler)).Methods("GET")
+	r.Handle("/api/events/poll", authenticated(gateway.GatewayPollHandler)).Methods("GET")
+	r.Handle("/api/media", authenticated(media.MediaUploadHandler)).Methods("POST")
+	// Resumable uploads follow the tus protocol, see the uploads package
+	r.Handle("/api/media/uploads", authenticated(media.MediaUploadCreateHandler)).Methods("POST")
+	r.Handle("/api/media/uploads/{id}", authenticated(media.MediaUploadStatusHandler)).Methods("HEAD")
+	r.Handle("/api/media/uploads/{id}", authenticated(media.MediaUploadPatchHandler)).Methods("PATCH")
+	r.Handle("/api/media/uploads/{id}", authenticated(media.MediaUploadDeleteHandler)).Methods("DELETE")
+	// Thread replies use the channel message routes with the thread ID
+	r.Handle("/api/messages/{id}/thread", authenticated(thread.ThreadCreateHandler)).Methods("POST")
+	r.Handle("/api/threads/{id}", authenticated(thread.ThreadGetHandler)).Methods("GET")
+	r.Handle("/api/threads/{id}", authenticated(thread.ThreadUpdateHandler)).Methods("PATCH")
+	r.Handle("/api/threads/{id}/follow", authenticated(thread.ThreadFollowHandler)).Methods("PUT")
+	r.Handle("/api/threads/{id}/follow", authenticated(thread.ThreadUnfollowHandler)).Methods("DELETE")
+	// Conversation messages use the channel message routes with the conversation ID
+	r.Handle("/api/user/me/conversations", authenticated(conversation.ConversationListHandler)).Methods("GET")
+	r.Handle("/api/user/me/mentions", authenticated(message.MessageMentionListHandler)).Methods("GET")
+	r.Handle("/api/user/me/servers", authenticated(server.ServerListHandler)).Methods("GET")
+	r.Handle("/api/conversations", authenticated(conversation.ConversationCreateHandler)).Methods("POST")
+	r.Handle("/api/conversations/{id}", authenticated(conversation.ConversationUpdateHandler)).Methods("PATCH")
+	r.Handle("/api/conversations/{id}/participants/{userId}", authenticated(conversation.ConversationParticipantAddHandler)).Methods("PUT")
+	r.Handle("/api/conversations/{id}/participants/{userId}", authenticated(conversation.ConversationParticipantRemoveHandler)).Methods("DELETE")
 
 	log.Info().
 		Str("component", "router").
@@ -27,3 +92,8 @@ func RegisterRoutes(r *mux.Router) {
 		Msg("Finished registering routes...")
 
 }
+
+// authenticated wraps a handler function with the JWT authentication middleware.
+func authenticated(handler http.HandlerFunc) http.Handler {
+	return middleware.AuthMiddleware(handler)
+}
@@ -0,0 +1,209 @@
+// Package search parses message search requests.
+// A search query is free text mixed with filters written as key:value tokens:
+//
+//	from:<user ID or username>  messages of an author
+//	in:<channel ID>             messages of a channel, conversation or thread
+//	has:attachment|link|image   messages with an attachment, a link or an image
+//	before:YYYY-MM-DD           messages sent before the day
+//	after:YYYY-MM-DD            messages sent after the day
+//	during:YYYY-MM-DD           messages sent on the day
+//
+// Several from: or in: filters match any of their values, every other filter must match.
+// The remaining text is matched with websearch syntax: quoted phrases, OR and -excluded words.
+// Days are in UTC.
+package search
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// QUERY_MAX_LENGTH is the maximum length of a search query in characters.
+	QUERY_MAX_LENGTH = 512
+	// DEFAULT_LIMIT is the page size used when the request does not specify one.
+	DEFAULT_LIMIT = 25
+	// MAX_LIMIT is the largest page size a client can request.
+	MAX_LIMIT = 100
+	// dateLayout is the format of the date filters.
+	dateLayout = "2006-01-02"
+)
+
+var (
+	// ErrEmptyQuery is returned when a query has neither text nor filters.
+	ErrEmptyQuery = errors.New("the search query must contain text or a filter")
+	// ErrQueryTooLong is returned when a query exceeds QUERY_MAX_LENGTH characters.
+	ErrQueryTooLong = errors.New("the search query is too long")
+	// ErrInvalidFilter is returned when a filter has an invalid value.
+	ErrInvalidFilter = errors.New("invalid search filter, see the supported from:, in:, has:, before:, after: and during: values")
+	// ErrInvalidSort is returned when the sort order is neither relevance nor date.
+	ErrInvalidSort = errors.New("the sort must be relevance or date")
+	// ErrInvalidPage is returned when the page or the limit is out of range.
+	ErrInvalidPage = errors.New("the page must be a non-negative number and the limit a number between 1 and 100")
+)
+
+// Has is a content requirement of the has: filter.
+type Has string
+
+const (
+	HasAttachment Has = "attachment"
+	HasLink       Has = "link"
+	HasImage      Has = "image"
+)
+
+// Sort is the order of the search results.
+type Sort string
+
+const (
+	SortRelevance Sort = "relevance" // Best match first, newest first among equal matches
+	SortDate      Sort = "date"      // Newest first
+)
+
+// Query is a parsed search query.
+type Query struct {
+	// Text is the free text of the query, empty when only filters are given.
+	Text string
+	// From holds the user IDs or usernames of the from: filters.
+	From []string
+	// In holds the channel, conversation or thread IDs of the in: filters.
+	In  []uuid.UUID
+	Has []Has
+	// Before is the exclusive upper bound of the creation time, nil without a bound.
+	Before *time.Time
+	// After is the inclusive lower bound of the creation time, nil without a bound.
+	After *time.Time
+}
+
+// Request is a parsed search request.
+type Request struct {
+	Query Query
+	Sort  Sort
+	// Page is the zero-based index of the requested page.
+	Page  int
+	Limit int
+}
+
+// ParseRequest reads the q, sort, page and limit query parameters of a search request.
+// params:
+// - values: The query parameters of the request.
+// returns:
+// - Request: The parsed request, sorted by relevance and limited to DEFAULT_LIMIT items by default.
+// - error: ErrInvalidSort, ErrInvalidPage or an error returned by ParseQuery.
+func ParseRequest(values url.Values) (Request, error) {
+	request := Request{Sort: SortRelevance, Limit: DEFAULT_LIMIT}
+
+	switch Sort(values.Get("sort")) {
+	case "", SortRelevance:
+	case SortDate:
+		request.Sort = SortDate
+	default:
+		return Request{}, ErrInvalidSort
+	}
+
+	if raw := values.Get("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 0 {
+			return Request{}, ErrInvalidPage
+		}
+		request.Page = page
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MAX_LIMIT {
+			return Request{}, ErrInvalidPage
+		}
+		request.Limit = limit
+	}
+
+	query, err := ParseQuery(values.Get("q"))
+	if err != nil {
+		return Request{}, err
+	}
+	request.Query = query
+	return request, nil
+}
+
+// ParseQuery splits a search query into its free text and its filters.
+// Words that look like a filter with an unknown key are kept as text.
+// params:
+// - input: The search query.
+// returns:
+// - Query: The parsed query.
+// - error: ErrEmptyQuery, ErrQueryTooLong or ErrInvalidFilter.
+func ParseQuery(input string) (Query, error) {
+	if utf8.RuneCountInString(input) > QUERY_MAX_LENGTH {
+		return Query{}, ErrQueryTooLong
+	}
+
+	var query Query
+	var text []string
+	for _, word := range strings.Fields(input) {
+		key, value, found := strings.Cut(word, ":")
+		if !found {
+			text = append(text, word)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "from":
+			if value == "" {
+				return Query{}, ErrInvalidFilter
+			}
+			query.From = append(query.From, value)
+		case "in":
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return Query{}, ErrInvalidFilter
+			}
+			query.In = append(query.In, id)
+		case "has":
+			has := Has(strings.ToLower(value))
+			if has != HasAttachment && has != HasLink && has != HasImage {
+				return Query{}, ErrInvalidFilter
+			}
+			query.Has = append(query.Has, has)
+		case "before", "after", "during":
+			day, err := time.Parse(dateLayout, value)
+			if err != nil {
+				return Query{}, ErrInvalidFilter
+			}
+			query.narrow(strings.ToLower(key), day)
+		default:
+			text = append(text, word)
+		}
+	}
+	query.Text = strings.Join(text, " ")
+
+	if query.Text == "" && len(query.From) == 0 && len(query.In) == 0 && len(query.Has) == 0 && query.Before == nil && query.After == nil {
+		return Query{}, ErrEmptyQuery
+	}
+	return query, nil
+}
+
+// narrow restricts the time range of the query by a date filter, keeping the tightest bounds.
+func (q *Query) narrow(key string, day time.Time) {
+	nextDay := day.AddDate(0, 0, 1)
+	if key == "before" || key == "during" {
+		upper := day
+		if key == "during" {
+			upper = nextDay
+		}
+		if q.Before == nil || upper.Before(*q.Before) {
+			q.Before = &upper
+		}
+	}
+	if key == "after" || key == "during" {
+		lower := nextDay
+		if key == "during" {
+			lower = day
+		}
+		if q.After == nil || lower.After(*q.After) {
+			q.After = &lower
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package search_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/search"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseQuery tests splitting search queries into text and filters.
+func TestParseQuery(t *testing.T) {
+	channelID := uuid.MustParse("1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+	day := func(value string) *time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		require.NoError(t, err)
+		return &parsed
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    search.Query
+		wantErr error
+	}{
+		{
+			name:  "Text only",
+			input: "release notes",
+			want:  search.Query{Text: "release notes"},
+		},
+		{
+			name:  "Phrases and exclusions are kept as text",
+			input: `"release notes" -draft`,
+			want:  search.Query{Text: `"release notes" -draft`},
+		},
+		{
+			name:  "Text with filters",
+			input: "deploy from:alice in:" + channelID.String() + " has:link HAS:Image",
+			want: search.Query{
+				Text: "deploy",
+				From: []string{"alice"},
+				In:   []uuid.UUID{channelID},
+				Has:  []search.Has{search.HasLink, search.HasImage},
+			},
+		},
+		{
+			name:  "Filters only",
+			input: "from:alice from:bob",
+			want:  search.Query{From: []string{"alice", "bob"}},
+		},
+		{
+			name:  "Date range",
+			input: "after:2024-01-01 before:2024-02-01",
+			want:  search.Query{After: day("2024-01-02"), Before: day("2024-02-01")},
+		},
+		{
+			name:  "During a day",
+			input: "during:2024-03-15",
+			want:  search.Query{After: day("2024-03-15"), Before: day("2024-03-16")},
+		},
+		{
+			name:  "Tightest bounds win",
+			input: "before:2024-02-01 during:2024-01-10 after:2023-12-01",
+			want:  search.Query{After: day("2024-01-10"), Before: day("2024-01-11")},
+		},
+		{
+			name:  "Unknown keys are text",
+			input: "time:12:30",
+			want:  search.Query{Text: "time:12:30"},
+		},
+		{
+			name:    "Error: Empty query",
+			input:   "   ",
+			wantErr: search.ErrEmptyQuery,
+		},
+		{
+			name:    "Error: Invalid channel ID",
+			input:   "in:general",
+			wantErr: search.ErrInvalidFilter,
+		},
+		{
+			name:    "Error: Unknown has value",
+			input:   "has:video",
+			wantErr: search.ErrInvalidFilter,
+		},
+		{
+			name:    "Error: Invalid date",
+			input:   "before:2024-13-01",
+			wantErr: search.ErrInvalidFilter,
+		},
+		{
+			name:    "Error: Empty author",
+			input:   "from:",
+			wantErr: search.ErrInvalidFilter,
+		},
+		{
+			name:    "Error: Too long",
+			input:   strings.Repeat("a", search.QUERY_MAX_LENGTH+1),
+			wantErr: search.ErrQueryTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := search.ParseQuery(tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestParseRequest tests reading the sort order and the page of search requests.
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    url.Values
+		wantSort  search.Sort
+		wantPage  int
+		wantLimit int
+		wantErr   error
+	}{
+		{
+			name:      "Defaults",
+			values:    url.Values{"q": {"hello"}},
+			wantSort:  search.SortRelevance,
+			wantLimit: search.DEFAULT_LIMIT,
+		},
+		{
+			name:      "Sort by date with page",
+			values:    url.Values{"q": {"hello"}, "sort": {"date"}, "page": {"2"}, "limit": {"10"}},
+			wantSort:  search.SortDate,
+			wantPage:  2,
+			wantLimit: 10,
+		},
+		{
+			name:    "Error: Unknown sort",
+			values:  url.Values{"q": {"hello"}, "sort": {"oldest"}},
+			wantErr: search.ErrInvalidSort,
+		},
+		{
+			name:    "Error: Negative page",
+			values:  url.Values{"q": {"hello"}, "page": {"-1"}},
+			wantErr: search.ErrInvalidPage,
+		},
+		{
+			name:    "Error: Limit too large",
+			values:  url.Values{"q": {"hello"}, "limit": {"101"}},
+			wantErr: search.ErrInvalidPage,
+		},
+		{
+			name:    "Error: Missing query",
+			values:  url.Values{},
+			wantErr: search.ErrEmptyQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := search.ParseRequest(tt.values)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSort, got.Sort)
+			assert.Equal(t, tt.wantPage, got.Page)
+			assert.Equal(t, tt.wantLimit, got.Limit)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Package signedurl signs the URL paths of private media, such as message attachments, so they can
+// be loaded without a token for a limited time.
+//
+// A signed URL carries its expiry as a Unix timestamp and an HMAC-SHA256 of the path and expiry,
+// "/api/media/{id}?expires=1700000000&signature=...". Expiries are rounded up to EXPIRY_STEP, so
+// the URLs of an asset stay the same across responses for a while and browsers can cache them.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// TTL is how long a signed URL is valid at least.
+	TTL = 24 * time.Hour
+	// EXPIRY_STEP is what expiries are rounded up to.
+	EXPIRY_STEP = time.Hour
+	// DERIVED_KEY_LABEL is the message the signing key is derived from another secret with.
+	DERIVED_KEY_LABEL = "media-url"
+)
+
+var (
+	// ErrNoSecret is returned when URLs are verified without a secret.
+	ErrNoSecret = errors.New("no secret is configured for signed URLs")
+	// ErrInvalidSignature is returned when the signature or expiry of a URL is missing or does not match.
+	ErrInvalidSignature = errors.New("the URL signature is invalid")
+	// ErrExpired is returned when a URL is used after its expiry.
+	ErrExpired = errors.New("the URL has expired")
+)
+
+// Secret is the key URLs are signed with, nil until the application sets it. Without it no URL is valid.
+var Secret []byte
+
+// DeriveSecret derives the key URLs are signed with from a secret used for something else, such as
+// the JWT secret, so a signature never doubles as a MAC made with that secret.
+// params:
+// - secret: The other secret.
+// returns:
+// - []byte: HMAC-SHA256 of DERIVED_KEY_LABEL with the secret, nil if the secret is empty.
+func DeriveSecret(secret []byte) []byte {
+	if len(secret) == 0 {
+		return nil
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(DERIVED_KEY_LABEL))
+	return mac.Sum(nil)
+}
+
+// Expiry returns the expiry of the URLs signed at a time.
+// params:
+// - now: The time of signing.
+// returns:
+// - time.Time: The expiry, at least TTL after now.
+func Expiry(now time.Time) time.Time {
+	return now.Add(TTL + EXPIRY_STEP - 1).Truncate(EXPIRY_STEP)
+}
+
+// Sign returns a URL path valid until an expiry.
+// params:
+// - path: The URL path without query.
+// - expires: When the URL expires.
+// returns:
+// - string: The path with the expires and signature query parameters.
+func Sign(path string, expires time.Time) string {
+	timestamp := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{"expires": {timestamp}, "signature": {signature(path, timestamp)}}
+	return path + "?" + query.Encode()
+}
+
+// Verify checks the expires and signature query parameters of a signed URL path.
+// params:
+// - path: The URL path without query.
+// - query: The query parameters of the request.
+// - now: The time of the request.
+// returns:
+// - time.Time: The expiry of the URL.
+// - error: ErrNoSecret, ErrInvalidSignature or ErrExpired.
+func Verify(path string, query url.Values, now time.Time) (time.Time, error) {
+	if len(Secret) == 0 {
+		return time.Time{}, ErrNoSecret
+	}
+	timestamp := query.Get("expires")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !hmac.Equal([]byte(query.Get("signature")), []byte(signature(path, timestamp))) {
+		return time.Time{}, ErrInvalidSignature
+	}
+	expires := time.Unix(unix, 0)
+	if !now.Before(expires) {
+		return time.Time{}, ErrExpired
+	}
+	return expires, nil
+}
+
+// signature returns the signature of a path and expiry timestamp.
+func signature(path string, timestamp string) string {
+	mac := hmac.New(sha256.New, Secret)
+	mac.Write([]byte(path + "\n" + timestamp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
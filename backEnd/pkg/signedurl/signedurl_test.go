@@ -0,0 +1,86 @@
+package signedurl_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/signedurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const path = "/api/media/0b7f6c2e-3f0a-4a53-9d2b-4c0f4f3f9a10"
+
+// query returns the query parameters of a signed URL path.
+func query(t *testing.T, signed string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, path, parsed.Path)
+	return parsed.Query()
+}
+
+// TestExpiry tests that expiries are rounded up to the step and at least TTL away.
+func TestExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 5, 2, 11, 0, 0, 0, time.UTC), signedurl.Expiry(now))
+	exact := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), signedurl.Expiry(exact))
+}
+
+// TestVerify tests verifying signed URL paths.
+func TestVerify(t *testing.T) {
+	signedurl.Secret = []byte("secret")
+	t.Cleanup(func() { signedurl.Secret = nil })
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Hour)
+	signed := query(t, signedurl.Sign(path, expires))
+
+	tampered := query(t, signedurl.Sign(path, expires))
+	tampered.Set("expires", "1800000000")
+
+	tests := []struct {
+		name  string
+		path  string
+		query url.Values
+		now   time.Time
+		err   error
+	}{
+		{"valid", path, signed, now, nil},
+		{"other path", path + "0", signed, now, signedurl.ErrInvalidSignature},
+		{"extended expiry", path, tampered, now, signedurl.ErrInvalidSignature},
+		{"unsigned", path, url.Values{}, now, signedurl.ErrInvalidSignature},
+		{"expired", path, signed, expires, signedurl.ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signedurl.Verify(tt.path, tt.query, tt.now)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, expires.Equal(got))
+		})
+	}
+
+	// URLs signed with another secret are not valid.
+	signedurl.Secret = []byte("other")
+	_, err := signedurl.Verify(path, signed, now)
+	assert.ErrorIs(t, err, signedurl.ErrInvalidSignature)
+
+	signedurl.Secret = nil
+	_, err = signedurl.Verify(path, signed, now)
+	assert.ErrorIs(t, err, signedurl.ErrNoSecret)
+}
+
+// TestDeriveSecret tests deriving the signing key from another secret.
+func TestDeriveSecret(t *testing.T) {
+	derived := signedurl.DeriveSecret([]byte("jwt secret"))
+	assert.Len(t, derived, 32)
+	assert.NotEqual(t, []byte("jwt secret"), derived)
+	assert.Equal(t, derived, signedurl.DeriveSecret([]byte("jwt secret")))
+	assert.NotEqual(t, derived, signedurl.DeriveSecret([]byte("other secret")))
+	assert.Nil(t, signedurl.DeriveSecret(nil))
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage keeps the files in a directory of the local filesystem, served by the API.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a storage in a directory, which is created if needed.
+// params:
+// - root: The directory the files are kept in.
+// returns:
+// - *LocalStorage: The storage.
+// - error: The error creating the directory.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Put stores the content read from r. It is written to a temporary file next to the stored files
+// and renamed to its key, so a file is never visible half written.
+// params:
+// - ctx: Unused, the content is read until r ends or fails.
+// - r: The content.
+// returns:
+// - Object: The key and size of the stored file.
+// - error: The error reading the content or writing the file.
+func (s *LocalStorage) Put(_ context.Context, r io.Reader) (Object, error) {
+	file, object, err := spool(filepath.Join(s.root, "tmp"), r)
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(file.Name())
+	if err := file.Close(); err != nil {
+		return Object{}, err
+	}
+
+	path := s.path(object.Key)
+	if _, err := os.Stat(path); err == nil {
+		return object, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Object{}, err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return Object{}, err
+	}
+	return object, nil
+}
+
+// Open opens the file stored under a key.
+// params:
+// - ctx: Unused.
+// - key: The key of the file.
+// returns:
+// - io.ReadCloser: The file, an *os.File.
+// - error: ErrInvalidKey, ErrNotFound or the error opening the file.
+func (s *LocalStorage) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Delete removes the file stored under a key, keys without file are ignored.
+// params:
+// - ctx: Unused.
+// - key: The key of the file.
+// returns:
+// - error: ErrInvalidKey or the error removing the file.
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the file stored under a key.
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PRESIGN_EXPIRY is how long the download URLs of S3Storage are valid.
+	PRESIGN_EXPIRY = 15 * time.Minute
+	// UNSIGNED_PAYLOAD is the payload hash of requests whose body is not signed.
+	UNSIGNED_PAYLOAD = "UNSIGNED-PAYLOAD"
+	// EMPTY_PAYLOAD_HASH is the SHA-256 digest of an empty body.
+	EMPTY_PAYLOAD_HASH = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Config configures an S3Storage.
+type S3Config struct {
+	// Endpoint is the base URL of the service, such as https://s3.eu-central-1.amazonaws.com or
+	// http://localhost:9000 for a MinIO server.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// AccessKeyID and SecretAccessKey are the credentials requests are signed with.
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket in the path instead of the host name, as most S3-compatible
+	// servers expect.
+	PathStyle bool
+}
+
+// S3Storage keeps the files in a bucket of an S3-compatible service. Requests are signed with
+// AWS Signature Version 4, the files are downloaded from presigned URLs.
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Storage creates a storage in a bucket.
+// params:
+// - config: The service, bucket and credentials.
+// returns:
+// - *S3Storage: The storage.
+// - error: The error parsing the endpoint.
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("the S3 endpoint %q needs a scheme and a host", config.Endpoint)
+	}
+	return &S3Storage{config: config, endpoint: endpoint, client: &http.Client{}}, nil
+}
+
+// Put stores the content read from r. It is spooled to a temporary file to compute its key and
+// size, then uploaded unless the bucket holds it already.
+// params:
+// - ctx: The context of the upload requests.
+// - r: The content.
+// returns:
+// - Object: The key and size of the stored file.
+// - error: The error reading the content or the error response of the service.
+func (s *S3Storage) Put(ctx context.Context, r io.Reader) (Object, error) {
+	file, object, err := spool(os.TempDir(), r)
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	exists, err := s.exists(ctx, object.Key)
+	if err != nil {
+		return Object{}, err
+	}
+	if exists {
+		return object, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(object.Key).String(), file)
+	if err != nil {
+		return Object{}, err
+	}
+	request.ContentLength = object.Size
+	// The key holds the digest of the content, so the payload is signed without reading it again.
+	response, err := s.do(request, digestOf(object.Key))
+	if err != nil {
+		return Object{}, err
+	}
+	response.Body.Close()
+	return object, nil
+}
+
+// Open downloads the file stored under a key.
+// params:
+// - ctx: The context of the download request.
+// - key: The key of the file.
+// returns:
+// - io.ReadCloser: The body of the response.
+// - error: ErrInvalidKey, ErrNotFound or the error response of the service.
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.do(request, EMPTY_PAYLOAD_HASH)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// Delete removes the file stored under a key, keys without file are ignored.
+// params:
+// - ctx: The context of the delete request.
+// - key: The key of the file.
+// returns:
+// - error: ErrInvalidKey or the error response of the service.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	response, err := s.do(request, EMPTY_PAYLOAD_HASH)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// URL returns a presigned URL the file stored under a key is downloaded from for PRESIGN_EXPIRY.
+// params:
+// - key: The key of the file.
+// - filename: The filename sent in the Content-Disposition header of the download.
+// - contentType: The Content-Type of the download.
+// returns:
+// - string: The presigned URL.
+// - error: ErrInvalidKey.
+func (s *S3Storage) URL(key string, filename string, contentType string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("response-content-type", contentType)
+	query.Set("response-content-disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	return s.PresignGet(key, query, time.Now(), PRESIGN_EXPIRY), nil
+}
+
+// PresignGet returns a URL downloading an object, signed at the given time.
+// params:
+// - key: The key of the object.
+// - query: Additional query parameters, such as response header overrides.
+// - at: The signing time.
+// - expires: How long the URL is valid.
+// returns:
+// - string: The presigned URL.
+func (s *S3Storage) PresignGet(key string, query url.Values, at time.Time, expires time.Duration) string {
+	target := s.objectURL(key)
+	at = at.UTC()
+	values := url.Values{}
+	for name, value := range query {
+		values[name] = value
+	}
+	values.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	values.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(at))
+	values.Set("X-Amz-Date", at.Format("20060102T150405Z"))
+	values.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	values.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := canonicalRequest(http.MethodGet, target, values, target.Host, http.Header{}, []string{"host"}, UNSIGNED_PAYLOAD)
+	target.RawQuery = canonicalQuery(values) + "&X-Amz-Signature=" + s.signature(at, canonical)
+	return target.String()
+}
+
+// exists reports whether an object is stored under a key.
+func (s *S3Storage) exists(ctx context.Context, key string) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(key).String(), nil)
+	if err != nil {
+		return false, err
+	}
+	response, err := s.do(request, EMPTY_PAYLOAD_HASH)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	response.Body.Close()
+	return true, nil
+}
+
+// do signs and sends a request. Error responses are closed and returned as errors, 404 as ErrNotFound.
+func (s *S3Storage) do(request *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(request, payloadHash, time.Now())
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 300 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return nil, fmt.Errorf("%s %s failed with status %d: %s", request.Method, request.URL.Path, response.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// sign adds the AWS Signature Version 4 Authorization header to a request.
+func (s *S3Storage) sign(request *http.Request, payloadHash string, at time.Time) {
+	at = at.UTC()
+	request.Header.Set("X-Amz-Date", at.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host"}
+	for name := range request.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "range" {
+			signed = append(signed, name)
+		}
+	}
+	sort.Strings(signed)
+
+	canonical := canonicalRequest(request.Method, request.URL, request.URL.Query(), request.URL.Host, request.Header, signed, payloadHash)
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, s.scope(at), strings.Join(signed, ";"), s.signature(at, canonical),
+	))
+}
+
+// scope returns the credential scope of a request signed at the given time.
+func (s *S3Storage) scope(at time.Time) string {
+	return at.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request.
+func (s *S3Storage) signature(at time.Time, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + at.Format("20060102T150405Z") + "\n" + s.scope(at) + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), at.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// objectURL returns the URL of an object, with the bucket in the path or in the host name.
+func (s *S3Storage) objectURL(key string) *url.URL {
+	target := *s.endpoint
+	path := strings.TrimSuffix(target.Path, "/")
+	if s.config.PathStyle {
+		path += "/" + s.config.Bucket
+	} else {
+		target.Host = s.config.Bucket + "." + target.Host
+	}
+	target.Path = path + "/" + key
+	target.RawPath = ""
+	target.RawQuery = ""
+	return &target
+}
+
+// canonicalRequest builds the canonical form of a request that is signed.
+func canonicalRequest(method string, target *url.URL, query url.Values, host string, headers http.Header, signed []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		value := host
+		if name != "host" {
+			value = strings.Join(headers.Values(name), ",")
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return strings.Join([]string{
+		method,
+		uriEncode(target.Path, false),
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signed.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters, and slashes unless
+// encodeSlash is set.
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of data with a key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// digestOf returns the hexadecimal SHA-256 digest held by a key.
+func digestOf(key string) string {
+	return key[strings.IndexByte(key, '/')+1:]
+}
@@ -0,0 +1,101 @@
+// Package storage stores the files of media assets.
+//
+// Files are stored under content-addressed keys derived from the SHA-256 digest of their content,
+// so uploading the same file twice stores it once. A Storage streams the uploaded content through
+// the digest into a temporary file, never holding the whole file in memory, and moves it to its key
+// once the digest is known. LocalStorage keeps the files in a directory and the API serves them,
+// S3Storage keeps them in an S3-compatible bucket and clients download them from presigned URLs.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+)
+
+var (
+	// ErrNotFound is returned when no file is stored under a key.
+	ErrNotFound = errors.New("file not found in storage")
+	// ErrInvalidKey is returned when a key was not created by Key.
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// keyRegex matches the keys created by Key.
+var keyRegex = regexp.MustCompile(`^[0-9a-f]{2}/[0-9a-f]{64}$`)
+
+// Object describes a stored file.
+type Object struct {
+	// Key is the content-addressed key of the file.
+	Key string
+	// Size is the size of the file in bytes.
+	Size int64
+}
+
+// Storage stores files under content-addressed keys.
+type Storage interface {
+	// Put stores the content read from r and returns its key and size. Content that is already
+	// stored is not stored again.
+	Put(ctx context.Context, r io.Reader) (Object, error)
+	// Open returns the content stored under a key, ErrNotFound if there is none. The reader also
+	// implements io.Seeker when the storage supports it. The caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the content stored under a key. As keys are shared by identical files, the
+	// caller checks that no other media asset references it.
+	Delete(ctx context.Context, key string) error
+}
+
+// Redirector is implemented by storages whose files are downloaded from them directly.
+type Redirector interface {
+	// URL returns a temporary URL the file stored under a key is downloaded from, served with the
+	// given filename and content type.
+	URL(key string, filename string, contentType string) (string, error)
+}
+
+// DefaultStorage is the storage the API handlers use, nil until the application sets it.
+var DefaultStorage Storage
+
+// Key returns the content-addressed key of a file, the hexadecimal SHA-256 digest of its content
+// prefixed with its first two characters to spread the files over directories.
+// params:
+// - digest: The hexadecimal SHA-256 digest of the content.
+// returns:
+// - string: The key, "<first two characters>/<digest>".
+func Key(digest string) string {
+	return digest[:2] + "/" + digest
+}
+
+// ValidateKey checks that a key was created by Key, so it cannot escape the storage.
+// params:
+// - key: The key to check.
+// returns:
+// - error: ErrInvalidKey if the key is malformed.
+func ValidateKey(key string) error {
+	if !keyRegex.MatchString(key) {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// spool copies the content read from r into a temporary file in dir while computing its digest.
+// The returned file is positioned at its start, the caller closes and removes it.
+func spool(dir string, r io.Reader) (*os.File, Object, error) {
+	file, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return nil, Object{}, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, Object{}, err
+	}
+	return file, Object{Key: Key(hex.EncodeToString(hash.Sum(nil))), Size: size}, nil
+}
@@ -0,0 +1,108 @@
+// Package typing tracks the users typing in channels, conversations and threads.
+//
+// A user is shown as typing for DURATION after a typing request. Clients send the request again
+// while the user keeps typing, but only requests coming DEBOUNCE after the last broadcast one are
+// broadcast again, so a client calling on every keystroke does not flood the gateway. Typing
+// expires on its own, there is no stop event, and sending a message ends it.
+package typing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DURATION is how long a user is shown as typing after a broadcast.
+	DURATION = 8 * time.Second
+	// DEBOUNCE is the time after a broadcast during which repeated requests are not broadcast.
+	DEBOUNCE = 5 * time.Second
+)
+
+// DefaultTracker is the tracker used by the API handlers.
+var DefaultTracker = NewTracker()
+
+// key identifies a user typing in a channel, conversation or thread.
+type key struct {
+	targetID uuid.UUID
+	userID   uuid.UUID
+}
+
+// Tracker remembers when the typing of each user was last broadcast.
+type Tracker struct {
+	mu sync.Mutex
+	// broadcasts holds the time of the last broadcast of each typing user.
+	broadcasts map[key]time.Time
+	// pruned is when the expired entries were last removed.
+	pruned time.Time
+}
+
+// NewTracker creates a tracker without typing users.
+// returns:
+// - *Tracker: The tracker.
+func NewTracker() *Tracker {
+	return &Tracker{broadcasts: make(map[key]time.Time)}
+}
+
+// Start marks a user as typing in a channel, conversation or thread.
+// params:
+// - targetID: The ID of the channel, conversation or thread.
+// - userID: The ID of the typing user.
+// - now: The time of the request.
+// returns:
+// - bool: True if the typing should be broadcast, false for a repeat within DEBOUNCE.
+// - time.Time: When the user stops being shown as typing.
+func (t *Tracker) Start(targetID uuid.UUID, userID uuid.UUID, now time.Time) (bool, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(now)
+
+	k := key{targetID: targetID, userID: userID}
+	if last, ok := t.broadcasts[k]; ok && now.Sub(last) < DEBOUNCE {
+		return false, last.Add(DURATION)
+	}
+	t.broadcasts[k] = now
+	return true, now.Add(DURATION)
+}
+
+// Stop ends the typing of a user, the next request is broadcast right away.
+// params:
+// - targetID: The ID of the channel, conversation or thread.
+// - userID: The ID of the user.
+func (t *Tracker) Stop(targetID uuid.UUID, userID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.broadcasts, key{targetID: targetID, userID: userID})
+}
+
+// Typing lists the users shown as typing in a channel, conversation or thread.
+// params:
+// - targetID: The ID of the channel, conversation or thread.
+// - now: The current time.
+// returns:
+// - []uuid.UUID: The IDs of the typing users, in no particular order.
+func (t *Tracker) Typing(targetID uuid.UUID, now time.Time) []uuid.UUID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var users []uuid.UUID
+	for k, last := range t.broadcasts {
+		if k.targetID == targetID && now.Sub(last) < DURATION {
+			users = append(users, k.userID)
+		}
+	}
+	return users
+}
+
+// prune removes the expired entries, at most once every DURATION, the caller holds the lock.
+func (t *Tracker) prune(now time.Time) {
+	if now.Sub(t.pruned) < DURATION {
+		return
+	}
+	t.pruned = now
+	for k, last := range t.broadcasts {
+		if now.Sub(last) >= DURATION {
+			delete(t.broadcasts, k)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package typing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/typing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStart tests the broadcast decisions of repeated typing requests.
+func TestStart(t *testing.T) {
+	channelID := uuid.MustParse("1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+	otherChannelID := uuid.MustParse("3e4f5a6b-7c8d-4e9f-8a0b-1c2d3e4f5a6b")
+	userID := uuid.MustParse("0b7f6c2e-3f0a-4a53-9d2b-4c0f4f3f9a10")
+	otherUserID := uuid.MustParse("5d1e0c8a-7b2f-4e6d-8a9c-1f2e3d4c5b6a")
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	type request struct {
+		channelID uuid.UUID
+		userID    uuid.UUID
+		after     time.Duration
+		broadcast bool
+		expiresIn time.Duration
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "First request is broadcast",
+			requests: []request{
+				{channelID, userID, 0, true, typing.DURATION},
+			},
+		},
+		{
+			name: "Rapid repeats are not broadcast and keep the expiry",
+			requests: []request{
+				{channelID, userID, 0, true, typing.DURATION},
+				{channelID, userID, time.Second, false, typing.DURATION},
+				{channelID, userID, typing.DEBOUNCE - time.Millisecond, false, typing.DURATION},
+			},
+		},
+		{
+			name: "Repeat after the debounce is broadcast again",
+			requests: []request{
+				{channelID, userID, 0, true, typing.DURATION},
+				{channelID, userID, typing.DEBOUNCE, true, typing.DEBOUNCE + typing.DURATION},
+			},
+		},
+		{
+			name: "Other users and channels are independent",
+			requests: []request{
+				{channelID, userID, 0, true, typing.DURATION},
+				{channelID, otherUserID, time.Second, true, time.Second + typing.DURATION},
+				{otherChannelID, userID, time.Second, true, time.Second + typing.DURATION},
+			},
+		},
+		{
+			name: "Expired typing is broadcast again",
+			requests: []request{
+				{channelID, userID, 0, true, typing.DURATION},
+				{channelID, userID, 10 * typing.DURATION, true, 11 * typing.DURATION},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := typing.NewTracker()
+			for i, r := range tt.requests {
+				broadcast, expiresAt := tracker.Start(r.channelID, r.userID, start.Add(r.after))
+				assert.Equal(t, r.broadcast, broadcast, "request %d", i)
+				assert.Equal(t, start.Add(r.expiresIn), expiresAt, "request %d", i)
+			}
+		})
+	}
+}
+
+// TestStopAndTyping tests ending typing and listing the typing users.
+func TestStopAndTyping(t *testing.T) {
+	channelID := uuid.MustParse("1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+	userID := uuid.MustParse("0b7f6c2e-3f0a-4a53-9d2b-4c0f4f3f9a10")
+	otherUserID := uuid.MustParse("5d1e0c8a-7b2f-4e6d-8a9c-1f2e3d4c5b6a")
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker := typing.NewTracker()
+	tracker.Start(channelID, userID, start)
+	tracker.Start(channelID, otherUserID, start.Add(2*time.Second))
+	assert.ElementsMatch(t, []uuid.UUID{userID, otherUserID}, tracker.Typing(channelID, start.Add(3*time.Second)))
+
+	// Typing expires without a stop.
+	assert.Equal(t, []uuid.UUID{otherUserID}, tracker.Typing(channelID, start.Add(typing.DURATION)))
+
+	// Sending a message stops typing and the next request is broadcast right away.
+	tracker.Stop(channelID, otherUserID)
+	assert.Equal(t, []uuid.UUID{userID}, tracker.Typing(channelID, start.Add(3*time.Second)))
+	broadcast, _ := tracker.Start(channelID, otherUserID, start.Add(3*time.Second))
+	assert.True(t, broadcast)
+}
@@ -0,0 +1,268 @@
+// Package uploads keeps the chunks of resumable uploads until they are complete.
+//
+// The protocol follows tus 1.0 (https://tus.io/protocols/resumable-upload): a client creates an
+// upload with its length, metadata and checksum, then sends the content in PATCH requests starting
+// at the offset the server reports. A request cut off by a flaky connection keeps the bytes that
+// arrived, and the client continues from the new offset. The chunks are appended to a part file per
+// upload, whose size is the offset, so the directory must be shared by all instances of the API.
+// A request writing to an upload holds an advisory lock on it in the database, so requests on
+// different instances do not interleave their chunks either.
+// Once the part file is complete its checksum is verified and it is moved to the media storage.
+package uploads
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// TUS_VERSION is the version of the tus protocol sent in the Tus-Resumable header.
+	TUS_VERSION = "1.0.0"
+	// CONTENT_TYPE is the content type of the PATCH requests carrying chunks.
+	CONTENT_TYPE = "application/offset+octet-stream"
+	// EXPIRY is how long an upload is kept after its creation or its last chunk.
+	EXPIRY = 24 * time.Hour
+)
+
+var (
+	// ErrOffsetMismatch is returned when a chunk does not start at the end of the part file.
+	ErrOffsetMismatch = errors.New("the offset does not match the received length of the upload")
+	// ErrTooLarge is returned when a chunk goes past the length of the upload.
+	ErrTooLarge = errors.New("the chunk exceeds the length of the upload")
+	// ErrLocked is returned when another request is writing to the upload.
+	ErrLocked = errors.New("the upload is being written by another request")
+	// ErrInvalidMetadata is returned for malformed Upload-Metadata headers.
+	ErrInvalidMetadata = errors.New("invalid upload metadata")
+	// ErrInvalidChecksum is returned for malformed or unsupported Upload-Checksum headers.
+	ErrInvalidChecksum = errors.New("invalid upload checksum, expected sha256 and a base64 digest")
+)
+
+// DefaultChunks keeps the chunks of the uploads handled by the API, nil until the application sets it.
+var DefaultChunks *Chunks
+
+// Chunks keeps the part files of uploads in a directory.
+type Chunks struct {
+	dir string
+}
+
+// NewChunks creates the part file store of a directory, which is created if needed.
+// params:
+// - dir: The directory the part files are kept in.
+// returns:
+// - *Chunks: The store.
+// - error: The error creating the directory.
+func NewChunks(dir string) (*Chunks, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Chunks{dir: dir}, nil
+}
+
+// Lock reserves an upload for a request on any instance, so chunks of concurrent requests are not
+// interleaved. The advisory lock belongs to a database connection, which is held until the upload
+// is released.
+// params:
+// - ctx: The context of the request.
+// - db: The GORM database instance.
+// - id: The ID of the upload.
+// returns:
+// - func(): Releases the upload.
+// - error: ErrLocked if another request holds it, or a database error.
+func Lock(ctx context.Context, db *gorm.DB, id uuid.UUID) (func(), error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := lockKey(id)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrLocked
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// The lock would stay with the connection in the pool, the connection is closed instead.
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// lockKey returns the advisory lock key of an upload, the first 8 bytes of its ID.
+func lockKey(id uuid.UUID) int64 {
+	return int64(binary.BigEndian.Uint64(id[:8]))
+}
+
+// Size returns the number of bytes received for an upload, which is its offset.
+// params:
+// - id: The ID of the upload.
+// returns:
+// - int64: The size of the part file, 0 if nothing was received.
+// - error: The error reading the part file.
+func (c *Chunks) Size(id uuid.UUID) (int64, error) {
+	info, err := os.Stat(c.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Append writes a chunk at the end of the part file of an upload. The bytes read before a failure
+// are kept, so the client continues after them.
+// params:
+// - id: The ID of the upload, locked by the caller.
+// - offset: The offset the client sends the chunk at, which must be the size of the part file.
+// - r: The chunk.
+// - limit: The number of bytes the upload is still missing.
+// returns:
+// - int64: The new offset of the upload.
+// - error: ErrOffsetMismatch, ErrTooLarge or the error reading the chunk or writing the file.
+func (c *Chunks) Append(id uuid.UUID, offset int64, r io.Reader, limit int64) (int64, error) {
+	file, err := os.OpenFile(c.path(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != offset {
+		return info.Size(), ErrOffsetMismatch
+	}
+
+	written, err := io.Copy(file, io.LimitReader(r, limit))
+	if err != nil {
+		return offset + written, err
+	}
+	// The chunk must end at the length of the upload.
+	if n, _ := r.Read(make([]byte, 1)); n > 0 {
+		return offset + written, ErrTooLarge
+	}
+	return offset + written, nil
+}
+
+// Digest returns the hexadecimal SHA-256 digest of the content received for an upload.
+// params:
+// - id: The ID of the upload.
+// returns:
+// - string: The digest.
+// - error: The error reading the part file.
+func (c *Chunks) Digest(id uuid.UUID) (string, error) {
+	file, err := os.Open(c.path(id))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Open opens the part file of an upload for reading.
+// params:
+// - id: The ID of the upload.
+// returns:
+// - *os.File: The part file, the caller closes it.
+// - error: The error opening the file.
+func (c *Chunks) Open(id uuid.UUID) (*os.File, error) {
+	return os.Open(c.path(id))
+}
+
+// Remove removes the part file of an upload, uploads without part file are ignored.
+// params:
+// - id: The ID of the upload.
+// returns:
+// - error: The error removing the file.
+func (c *Chunks) Remove(id uuid.UUID) error {
+	if err := os.Remove(c.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the part file of an upload.
+func (c *Chunks) path(id uuid.UUID) string {
+	return filepath.Join(c.dir, id.String()+".part")
+}
+
+// ParseMetadata parses an Upload-Metadata header, comma separated pairs of a key and its base64
+// encoded value, the value can be left out.
+// params:
+// - header: The header value.
+// returns:
+// - map[string]string: The decoded values by key.
+// - error: ErrInvalidMetadata if a pair is malformed or a key is repeated.
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, ErrInvalidMetadata
+		}
+		key := fields[0]
+		if _, repeated := metadata[key]; repeated {
+			return nil, ErrInvalidMetadata
+		}
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, ErrInvalidMetadata
+			}
+			value = string(decoded)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// ParseChecksum parses an Upload-Checksum header of the whole file, the algorithm and the base64
+// encoded digest. Only sha256 is supported, as files are stored under their SHA-256 digest.
+// params:
+// - header: The header value.
+// returns:
+// - string: The hexadecimal digest.
+// - error: ErrInvalidChecksum if the header is malformed or uses another algorithm.
+func ParseChecksum(header string) (string, error) {
+	algorithm, encoded, found := strings.Cut(header, " ")
+	if !found || algorithm != "sha256" {
+		return "", ErrInvalidChecksum
+	}
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(digest) != sha256.Size {
+		return "", ErrInvalidChecksum
+	}
+	return hex.EncodeToString(digest), nil
+}
@@ -0,0 +1,146 @@
+package uploads_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/uploads"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestChunks tests appending chunks to an upload and resuming after a failed chunk.
+func TestChunks(t *testing.T) {
+	chunks, err := uploads.NewChunks(t.TempDir())
+	require.NoError(t, err)
+	id := uuid.New()
+	const length = 11
+
+	size, err := chunks.Size(id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+
+	offset, err := chunks.Append(id, 0, strings.NewReader("hello"), length)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), offset)
+
+	// A chunk sent at an old offset is rejected.
+	offset, err = chunks.Append(id, 0, strings.NewReader("hello"), length)
+	assert.Equal(t, uploads.ErrOffsetMismatch, err)
+	assert.Equal(t, int64(5), offset)
+
+	// The bytes of a chunk cut off by the connection are kept.
+	failing := iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader(" wo")))
+	offset, err = chunks.Append(id, 5, failing, length-5)
+	assert.True(t, errors.Is(err, iotest.ErrTimeout))
+	assert.Equal(t, int64(6), offset)
+
+	// A chunk going past the length is rejected after the missing bytes.
+	offset, err = chunks.Append(id, 6, strings.NewReader("world and more"), length-6)
+	assert.Equal(t, uploads.ErrTooLarge, err)
+	assert.Equal(t, int64(length), offset)
+
+	hash := sha256.Sum256([]byte("hello world"))
+	digest, err := chunks.Digest(id)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(hash[:]), digest)
+
+	require.NoError(t, chunks.Remove(id))
+	size, err = chunks.Size(id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+	assert.NoError(t, chunks.Remove(id))
+}
+
+// TestLock tests that an upload is written by one request at a time in the database of DATABASE_URL.
+func TestLock(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	// The locks are taken by two instances of the API.
+	var instances [2]*gorm.DB
+	for i := range instances {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+		require.NoError(t, err)
+		instances[i] = db
+	}
+	ctx := context.Background()
+	id := uuid.New()
+
+	unlock, err := uploads.Lock(ctx, instances[0], id)
+	require.NoError(t, err)
+	_, err = uploads.Lock(ctx, instances[1], id)
+	assert.Equal(t, uploads.ErrLocked, err)
+	_, err = uploads.Lock(ctx, instances[0], id)
+	assert.Equal(t, uploads.ErrLocked, err)
+	other, err := uploads.Lock(ctx, instances[1], uuid.New())
+	require.NoError(t, err)
+	other()
+
+	unlock()
+	unlock, err = uploads.Lock(ctx, instances[1], id)
+	require.NoError(t, err)
+	unlock()
+}
+
+// TestParseMetadata tests parsing Upload-Metadata headers.
+func TestParseMetadata(t *testing.T) {
+	encode := base64.StdEncoding.EncodeToString
+	tests := []struct {
+		name    string
+		header  string
+		want    map[string]string
+		wantErr error
+	}{
+		{"Valid: Filename and type", "filename " + encode([]byte("cat.mp4")) + ",filetype " + encode([]byte("video/mp4")), map[string]string{"filename": "cat.mp4", "filetype": "video/mp4"}, nil},
+		{"Valid: Key without value", "filename " + encode([]byte("cat.mp4")) + ", is_private", map[string]string{"filename": "cat.mp4", "is_private": ""}, nil},
+		{"Valid: Empty", "", map[string]string{}, nil},
+		{"Invalid: Not base64", "filename cat.mp4", nil, uploads.ErrInvalidMetadata},
+		{"Invalid: Repeated key", "filename " + encode([]byte("a")) + ",filename " + encode([]byte("b")), nil, uploads.ErrInvalidMetadata},
+		{"Invalid: Empty pair", "filename " + encode([]byte("a")) + ",", nil, uploads.ErrInvalidMetadata},
+		{"Invalid: Too many fields", "filename a b", nil, uploads.ErrInvalidMetadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := uploads.ParseMetadata(tt.header)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestParseChecksum tests parsing Upload-Checksum headers.
+func TestParseChecksum(t *testing.T) {
+	hash := sha256.Sum256([]byte("hello world"))
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr error
+	}{
+		{"Valid: SHA-256", "sha256 " + base64.StdEncoding.EncodeToString(hash[:]), hex.EncodeToString(hash[:]), nil},
+		{"Invalid: SHA-1", "sha1 Kq5sNclPz7QV2+lfQIuc6R7oRu0=", "", uploads.ErrInvalidChecksum},
+		{"Invalid: Short digest", "sha256 " + base64.StdEncoding.EncodeToString(hash[:16]), "", uploads.ErrInvalidChecksum},
+		{"Invalid: Hexadecimal digest", "sha256 " + hex.EncodeToString(hash[:]), "", uploads.ErrInvalidChecksum},
+		{"Invalid: Missing digest", "sha256", "", uploads.ErrInvalidChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := uploads.ParseChecksum(tt.header)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
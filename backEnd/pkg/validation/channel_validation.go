@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+)
+
+const (
+	// CHANNEL_NAME_PATTERN defines the regex for valid channel names.
+	// It allows 1-100 characters without control characters,
+	// and prevents leading or trailing whitespace.
+	CHANNEL_NAME_PATTERN = `^[^\s\p{C}](?:[^\p{C}]{0,98}[^\s\p{C}])?$`
+
+	// CHANNEL_TOPIC_MAX_LENGTH is the maximum length of a channel topic in characters.
+	CHANNEL_TOPIC_MAX_LENGTH = 1024
+
+	// CHANNEL_USER_LIMIT_MAX is the highest user limit of a voice channel.
+	CHANNEL_USER_LIMIT_MAX = 99
+)
+
+var channelNameRegex = regexp.MustCompile(CHANNEL_NAME_PATTERN)
+
+// ValidateChannelName checks if the provided channel name matches the CHANNEL_NAME_PATTERN.
+// @param name: The channel name to validate.
+// @return bool: True if the channel name is valid, false otherwise.
+func ValidateChannelName(name string) bool {
+	return channelNameRegex.MatchString(name)
+}
+
+// ValidateChannelTopic checks that the topic does not exceed CHANNEL_TOPIC_MAX_LENGTH characters
+// and is valid UTF-8.
+// @param topic: The channel topic to validate.
+// @return bool: True if the topic is valid, false otherwise.
+func ValidateChannelTopic(topic string) bool {
+	return utf8.ValidString(topic) && utf8.RuneCountInString(topic) <= CHANNEL_TOPIC_MAX_LENGTH
+}
+
+// ValidateChannelType checks if the provided channel type is one of the known channel types.
+// @param channelType: The channel type to validate.
+// @return bool: True if the channel type is known, false otherwise.
+func ValidateChannelType(channelType models.ChannelType) bool {
+	switch channelType {
+	case models.ChannelTypeChat, models.ChannelTypeVoice, models.ChannelTypeCategory:
+		return true
+	}
+	return false
+}
+
+// ValidateChannelDeniedPermissions checks that a channel only denies permissions contained in
+// models.PermissionChannelDeniable.
+// @param denied: The permissions denied by the channel.
+// @return bool: True if every denied permission can be denied by a channel, false otherwise.
+func ValidateChannelDeniedPermissions(denied models.Permission) bool {
+	return denied&^models.PermissionChannelDeniable == 0
+}
+
+// ValidateChannelUserLimit checks that a user limit is between 0, for no limit, and
+// CHANNEL_USER_LIMIT_MAX, and that only voice channels are limited.
+// @param channelType: The type of the channel.
+// @param limit: The user limit of the channel.
+// @return bool: True if the user limit is valid for the channel type, false otherwise.
+func ValidateChannelUserLimit(channelType models.ChannelType, limit int) bool {
+	if limit == 0 {
+		return true
+	}
+	return channelType == models.ChannelTypeVoice && limit > 0 && limit <= CHANNEL_USER_LIMIT_MAX
+}
@@ -0,0 +1,189 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+)
+
+// TestValidateChannelName tests the ValidateChannelName function.
+func TestValidateChannelName(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelName string
+		want        bool
+	}{
+		{
+			name:        "Valid: Single character",
+			channelName: "a",
+			want:        true,
+		},
+		{
+			name:        "Valid: With spaces and punctuation",
+			channelName: "General chat #1",
+			want:        true,
+		},
+		{
+			name:        "Valid: Unicode characters",
+			channelName: "Általános 💬",
+			want:        true,
+		},
+		{
+			name:        "Valid: Maximum length (100 chars)",
+			channelName: strings.Repeat("a", 100),
+			want:        true,
+		},
+		{
+			name:        "Invalid: Too long (101 chars)",
+			channelName: strings.Repeat("a", 101),
+			want:        false,
+		},
+		{
+			name:        "Invalid: Empty string",
+			channelName: "",
+			want:        false,
+		},
+		{
+			name:        "Invalid: Leading space",
+			channelName: " general",
+			want:        false,
+		},
+		{
+			name:        "Invalid: Trailing space",
+			channelName: "general ",
+			want:        false,
+		},
+		{
+			name:        "Invalid: Contains newline",
+			channelName: "gen\neral",
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateChannelName(tt.channelName); got != tt.want {
+				t.Errorf("ValidateChannelName(%q) = %v, want %v", tt.channelName, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateChannelTopic tests the ValidateChannelTopic function.
+func TestValidateChannelTopic(t *testing.T) {
+	tests := []struct {
+		name  string
+		topic string
+		want  bool
+	}{
+		{
+			name:  "Valid: Empty topic",
+			topic: "",
+			want:  true,
+		},
+		{
+			name:  "Valid: Multi-line topic",
+			topic: "Rules:\n1. Be nice",
+			want:  true,
+		},
+		{
+			name:  "Valid: Maximum length",
+			topic: strings.Repeat("é", validation.CHANNEL_TOPIC_MAX_LENGTH),
+			want:  true,
+		},
+		{
+			name:  "Invalid: Too long",
+			topic: strings.Repeat("a", validation.CHANNEL_TOPIC_MAX_LENGTH+1),
+			want:  false,
+		},
+		{
+			name:  "Invalid: Not UTF-8",
+			topic: string([]byte{0xff, 0xfe}),
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateChannelTopic(tt.topic); got != tt.want {
+				t.Errorf("ValidateChannelTopic(%q) = %v, want %v", tt.topic, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateChannelType tests the ValidateChannelType function.
+func TestValidateChannelType(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType models.ChannelType
+		want        bool
+	}{
+		{name: "Valid: Chat", channelType: models.ChannelTypeChat, want: true},
+		{name: "Valid: Voice", channelType: models.ChannelTypeVoice, want: true},
+		{name: "Valid: Category", channelType: models.ChannelTypeCategory, want: true},
+		{name: "Invalid: Empty", channelType: "", want: false},
+		{name: "Invalid: Unknown", channelType: "forum", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateChannelType(tt.channelType); got != tt.want {
+				t.Errorf("ValidateChannelType(%q) = %v, want %v", tt.channelType, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateChannelDeniedPermissions tests the ValidateChannelDeniedPermissions function.
+func TestValidateChannelDeniedPermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		denied models.Permission
+		want   bool
+	}{
+		{name: "Valid: Nothing denied", denied: models.PermissionNone, want: true},
+		{name: "Valid: Reactions denied", denied: models.PermissionAddReactions, want: true},
+		{name: "Valid: Read-only channel", denied: models.PermissionSendMessages | models.PermissionAddReactions, want: true},
+		{name: "Valid: Listen-only voice channel", denied: models.PermissionSpeak, want: true},
+		{name: "Invalid: Administrator", denied: models.PermissionAdministrator, want: false},
+		{name: "Invalid: View channels", denied: models.PermissionViewChannels | models.PermissionAddReactions, want: false},
+		{name: "Invalid: Unknown bit", denied: 1 << 40, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateChannelDeniedPermissions(tt.denied); got != tt.want {
+				t.Errorf("ValidateChannelDeniedPermissions(%d) = %v, want %v", tt.denied, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateChannelUserLimit tests the ValidateChannelUserLimit function.
+func TestValidateChannelUserLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType models.ChannelType
+		limit       int
+		want        bool
+	}{
+		{name: "Valid: No limit", channelType: models.ChannelTypeVoice, limit: 0, want: true},
+		{name: "Valid: No limit on a chat channel", channelType: models.ChannelTypeChat, limit: 0, want: true},
+		{name: "Valid: Limited voice channel", channelType: models.ChannelTypeVoice, limit: 10, want: true},
+		{name: "Valid: Maximum limit", channelType: models.ChannelTypeVoice, limit: validation.CHANNEL_USER_LIMIT_MAX, want: true},
+		{name: "Invalid: Above maximum", channelType: models.ChannelTypeVoice, limit: validation.CHANNEL_USER_LIMIT_MAX + 1, want: false},
+		{name: "Invalid: Negative", channelType: models.ChannelTypeVoice, limit: -1, want: false},
+		{name: "Invalid: Limited chat channel", channelType: models.ChannelTypeChat, limit: 5, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateChannelUserLimit(tt.channelType, tt.limit); got != tt.want {
+				t.Errorf("ValidateChannelUserLimit(%q, %d) = %v, want %v", tt.channelType, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package validation
+
+import "github.com/413ksz/BlueFox/backEnd/pkg/models"
+
+// CONVERSATION_MAX_PARTICIPANTS is the maximum number of participants of a group conversation,
+// including its owner.
+const CONVERSATION_MAX_PARTICIPANTS = 10
+
+// ValidateConversationName checks if the provided group conversation name is valid.
+// Group names follow the same rules as channel names.
+// @param name: The conversation name to validate.
+// @return bool: True if the name is valid, false otherwise.
+func ValidateConversationName(name string) bool {
+	return channelNameRegex.MatchString(name)
+}
+
+// ValidateParticipantCount checks that a group conversation has at least two and at most
+// CONVERSATION_MAX_PARTICIPANTS participants.
+// @param count: The number of participants including the owner.
+// @return bool: True if the number of participants is allowed, false otherwise.
+func ValidateParticipantCount(count int) bool {
+	return count >= 2 && count <= CONVERSATION_MAX_PARTICIPANTS
+}
+
+// ValidateDMPrivacy checks if the provided direct message privacy setting is known.
+// @param privacy: The privacy setting to validate.
+// @return bool: True if the setting is known, false otherwise.
+func ValidateDMPrivacy(privacy models.DMPrivacy) bool {
+	switch privacy {
+	case models.DMPrivacyEveryone, models.DMPrivacyFriends:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+)
+
+// TestValidateConversationName tests the ValidateConversationName function.
+func TestValidateConversationName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "Valid: Simple name", input: "Weekend plans", want: true},
+		{name: "Valid: Single character", input: "x", want: true},
+		{name: "Invalid: Empty", input: "", want: false},
+		{name: "Invalid: Leading space", input: " plans", want: false},
+		{name: "Invalid: Control character", input: "plans\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateConversationName(tt.input); got != tt.want {
+				t.Errorf("ValidateConversationName(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateParticipantCount tests the ValidateParticipantCount function.
+func TestValidateParticipantCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  bool
+	}{
+		{name: "Valid: Two participants", count: 2, want: true},
+		{name: "Valid: Maximum", count: validation.CONVERSATION_MAX_PARTICIPANTS, want: true},
+		{name: "Invalid: Owner alone", count: 1, want: false},
+		{name: "Invalid: Too many", count: validation.CONVERSATION_MAX_PARTICIPANTS + 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateParticipantCount(tt.count); got != tt.want {
+				t.Errorf("ValidateParticipantCount(%d) = %v, want %v", tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateDMPrivacy tests the ValidateDMPrivacy function.
+func TestValidateDMPrivacy(t *testing.T) {
+	tests := []struct {
+		name    string
+		privacy models.DMPrivacy
+		want    bool
+	}{
+		{name: "Valid: Everyone", privacy: models.DMPrivacyEveryone, want: true},
+		{name: "Valid: Friends", privacy: models.DMPrivacyFriends, want: true},
+		{name: "Invalid: Unknown", privacy: "nobody", want: false},
+		{name: "Invalid: Empty", privacy: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateDMPrivacy(tt.privacy); got != tt.want {
+				t.Errorf("ValidateDMPrivacy(%q) = %v, want %v", tt.privacy, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"regexp"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/emoji"
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+)
+
+const (
+	// EMOJI_MAX_FILE_SIZE is the maximum size of a custom emoji image in bytes.
+	EMOJI_MAX_FILE_SIZE = 256 * 1024
+	// STICKER_MAX_FILE_SIZE is the maximum size of a sticker image in bytes.
+	STICKER_MAX_FILE_SIZE = 512 * 1024
+
+	// SERVER_MAX_EMOJI is the maximum number of custom emoji of a server.
+	SERVER_MAX_EMOJI = 50
+	// SERVER_MAX_STICKERS is the maximum number of stickers of a server.
+	SERVER_MAX_STICKERS = 5
+)
+
+var emojiNameRegex = regexp.MustCompile(emoji.NAME_PATTERN)
+
+// ValidateEmojiName checks if the provided custom emoji name matches emoji.NAME_PATTERN.
+// @param name: The emoji name to validate.
+// @return bool: True if the name is valid, false otherwise.
+func ValidateEmojiName(name string) bool {
+	return emojiNameRegex.MatchString(name)
+}
+
+// ValidateEmojiKind checks if the provided kind is a known emoji kind.
+// @param kind: The kind to validate.
+// @return bool: True if the kind is known, false otherwise.
+func ValidateEmojiKind(kind models.EmojiKind) bool {
+	switch kind {
+	case models.EmojiKindEmoji, models.EmojiKindSticker:
+		return true
+	}
+	return false
+}
+
+// ValidateEmojiFileSize checks that the image of an emoji does not exceed the size limit of its kind.
+// @param kind: The kind of the emoji.
+// @param size: The size of the image in bytes.
+// @return bool: True if the image is small enough, false otherwise.
+func ValidateEmojiFileSize(kind models.EmojiKind, size int) bool {
+	limit := EMOJI_MAX_FILE_SIZE
+	if kind == models.EmojiKindSticker {
+		limit = STICKER_MAX_FILE_SIZE
+	}
+	return size > 0 && size <= limit
+}
+
+// ValidateServerEmojiCount checks that a server does not exceed the emoji or sticker limit.
+// @param kind: The kind of the emoji.
+// @param count: The number of emoji of that kind including the new one.
+// @return bool: True if the number is allowed, false otherwise.
+func ValidateServerEmojiCount(kind models.EmojiKind, count int) bool {
+	limit := SERVER_MAX_EMOJI
+	if kind == models.EmojiKindSticker {
+		limit = SERVER_MAX_STICKERS
+	}
+	return count >= 0 && count <= limit
+}
@@ -0,0 +1,81 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/models"
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+)
+
+// TestValidateEmojiName tests the ValidateEmojiName function.
+func TestValidateEmojiName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "Valid: Letters", input: "party", want: true},
+		{name: "Valid: Underscores and digits", input: "party_parrot_2", want: true},
+		{name: "Valid: Two characters", input: "ok", want: true},
+		{name: "Invalid: One character", input: "x", want: false},
+		{name: "Invalid: Too long", input: "abcdefghijklmnopqrstuvwxyz1234567", want: false},
+		{name: "Invalid: Space", input: "party parrot", want: false},
+		{name: "Invalid: Colon", input: "party:", want: false},
+		{name: "Invalid: Non-ASCII", input: "bulizás", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateEmojiName(tt.input); got != tt.want {
+				t.Errorf("ValidateEmojiName(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateEmojiFileSize tests the ValidateEmojiFileSize function.
+func TestValidateEmojiFileSize(t *testing.T) {
+	tests := []struct {
+		name string
+		kind models.EmojiKind
+		size int
+		want bool
+	}{
+		{name: "Valid: Emoji at the limit", kind: models.EmojiKindEmoji, size: validation.EMOJI_MAX_FILE_SIZE, want: true},
+		{name: "Invalid: Emoji over the limit", kind: models.EmojiKindEmoji, size: validation.EMOJI_MAX_FILE_SIZE + 1, want: false},
+		{name: "Valid: Sticker over the emoji limit", kind: models.EmojiKindSticker, size: validation.EMOJI_MAX_FILE_SIZE + 1, want: true},
+		{name: "Invalid: Sticker over the limit", kind: models.EmojiKindSticker, size: validation.STICKER_MAX_FILE_SIZE + 1, want: false},
+		{name: "Invalid: Empty file", kind: models.EmojiKindEmoji, size: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateEmojiFileSize(tt.kind, tt.size); got != tt.want {
+				t.Errorf("ValidateEmojiFileSize(%q, %d) = %v, want %v", tt.kind, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateServerEmojiCount tests the ValidateServerEmojiCount function.
+func TestValidateServerEmojiCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  models.EmojiKind
+		count int
+		want  bool
+	}{
+		{name: "Valid: Emoji at the limit", kind: models.EmojiKindEmoji, count: validation.SERVER_MAX_EMOJI, want: true},
+		{name: "Invalid: Emoji over the limit", kind: models.EmojiKindEmoji, count: validation.SERVER_MAX_EMOJI + 1, want: false},
+		{name: "Valid: Sticker at the limit", kind: models.EmojiKindSticker, count: validation.SERVER_MAX_STICKERS, want: true},
+		{name: "Invalid: Sticker over the limit", kind: models.EmojiKindSticker, count: validation.SERVER_MAX_STICKERS + 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateServerEmojiCount(tt.kind, tt.count); got != tt.want {
+				t.Errorf("ValidateServerEmojiCount(%q, %d) = %v, want %v", tt.kind, tt.count, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MEDIA_MAX_FILE_SIZE is the maximum size of an uploaded media file in bytes.
+	MEDIA_MAX_FILE_SIZE = 25 * 1024 * 1024
+	// UPLOAD_MAX_FILE_SIZE is the maximum size of a file sent with a resumable upload in bytes.
+	UPLOAD_MAX_FILE_SIZE = 1024 * 1024 * 1024
+	// MEDIA_USER_QUOTA is the number of bytes a user can upload in total, counting the uploads in progress.
+	MEDIA_USER_QUOTA = 10 * 1024 * 1024 * 1024
+	// MEDIA_FILENAME_MAX_LENGTH is the maximum length of the filename of a media file in characters.
+	MEDIA_FILENAME_MAX_LENGTH = 255
+)
+
+// ValidateMediaFilename checks that a filename is a single path element without control characters,
+// so it can be served in a Content-Disposition header.
+// @param filename: The filename to validate.
+// @return bool: True if the filename is valid, false otherwise.
+func ValidateMediaFilename(filename string) bool {
+	if filename == "" || filename == "." || filename == ".." || !utf8.ValidString(filename) {
+		return false
+	}
+	if utf8.RuneCountInString(filename) > MEDIA_FILENAME_MAX_LENGTH || strings.ContainsAny(filename, `/\`) {
+		return false
+	}
+	return strings.IndexFunc(filename, unicode.IsControl) == -1
+}
+
+// ValidateUploadLength checks that a resumable upload is not empty and within the size limit.
+// @param length: The size of the file in bytes.
+// @return bool: True if the size is allowed, false otherwise.
+func ValidateUploadLength(length int64) bool {
+	return length > 0 && length <= UPLOAD_MAX_FILE_SIZE
+}
+
+// ValidateMediaQuota checks that a new file fits in the storage quota of a user.
+// @param used: The bytes the user already uploaded or reserved with uploads in progress.
+// @param size: The size of the new file in bytes.
+// @return bool: True if the file fits, false otherwise.
+func ValidateMediaQuota(used int64, size int64) bool {
+	return used >= 0 && size >= 0 && used+size <= MEDIA_USER_QUOTA
+}
@@ -0,0 +1,82 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+)
+
+// TestValidateMediaFilename tests the ValidateMediaFilename function.
+func TestValidateMediaFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "Valid: Filename with extension", input: "cat.png", want: true},
+		{name: "Valid: Spaces and accents", input: "nyaralás 2024.jpg", want: true},
+		{name: "Valid: At the limit", input: strings.Repeat("a", validation.MEDIA_FILENAME_MAX_LENGTH), want: true},
+		{name: "Invalid: Too long", input: strings.Repeat("a", validation.MEDIA_FILENAME_MAX_LENGTH+1), want: false},
+		{name: "Invalid: Empty", input: "", want: false},
+		{name: "Invalid: Parent directory", input: "..", want: false},
+		{name: "Invalid: Path", input: "../etc/passwd", want: false},
+		{name: "Invalid: Windows path", input: `C:\cat.png`, want: false},
+		{name: "Invalid: Newline", input: "cat\r\n.png", want: false},
+		{name: "Invalid: Invalid UTF-8", input: "cat\xff.png", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateMediaFilename(tt.input); got != tt.want {
+				t.Errorf("ValidateMediaFilename(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateUploadLength tests the ValidateUploadLength function.
+func TestValidateUploadLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int64
+		want   bool
+	}{
+		{name: "Valid: Over the simple upload limit", length: validation.MEDIA_MAX_FILE_SIZE + 1, want: true},
+		{name: "Valid: At the limit", length: validation.UPLOAD_MAX_FILE_SIZE, want: true},
+		{name: "Invalid: Over the limit", length: validation.UPLOAD_MAX_FILE_SIZE + 1, want: false},
+		{name: "Invalid: Empty file", length: 0, want: false},
+		{name: "Invalid: Negative", length: -1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateUploadLength(tt.length); got != tt.want {
+				t.Errorf("ValidateUploadLength(%d) = %v, want %v", tt.length, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateMediaQuota tests the ValidateMediaQuota function.
+func TestValidateMediaQuota(t *testing.T) {
+	tests := []struct {
+		name string
+		used int64
+		size int64
+		want bool
+	}{
+		{name: "Valid: First file", used: 0, size: validation.UPLOAD_MAX_FILE_SIZE, want: true},
+		{name: "Valid: Filling the quota", used: validation.MEDIA_USER_QUOTA - 10, size: 10, want: true},
+		{name: "Invalid: Over the quota", used: validation.MEDIA_USER_QUOTA - 10, size: 11, want: false},
+		{name: "Invalid: Quota already exceeded", used: validation.MEDIA_USER_QUOTA + 1, size: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateMediaQuota(tt.used, tt.size); got != tt.want {
+				t.Errorf("ValidateMediaQuota(%d, %d) = %v, want %v", tt.used, tt.size, got, tt.want)
+			}
+		})
+	}
+}
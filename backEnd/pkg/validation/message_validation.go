@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MESSAGE_CONTENT_MAX_LENGTH is the maximum length of a message in characters.
+	MESSAGE_CONTENT_MAX_LENGTH = 4000
+
+	// MESSAGE_MAX_ATTACHMENTS is the maximum number of attachments of a single message.
+	MESSAGE_MAX_ATTACHMENTS = 10
+
+	// MESSAGE_MAX_REACTION_EMOJI is the maximum number of distinct emoji a single message can be reacted with.
+	MESSAGE_MAX_REACTION_EMOJI = 20
+
+	// MESSAGE_MAX_PINS is the maximum number of pinned messages in a single channel.
+	MESSAGE_MAX_PINS = 50
+)
+
+// ValidateMessageContent checks that the message content is valid UTF-8, does not exceed
+// MESSAGE_CONTENT_MAX_LENGTH characters and is not blank.
+// Blank content is only accepted for messages that carry attachments.
+// @param content: The message content to validate.
+// @param hasAttachments: Whether the message has at least one attachment.
+// @return bool: True if the content is valid, false otherwise.
+func ValidateMessageContent(content string, hasAttachments bool) bool {
+	if !utf8.ValidString(content) || utf8.RuneCountInString(content) > MESSAGE_CONTENT_MAX_LENGTH {
+		return false
+	}
+	if strings.TrimSpace(content) == "" {
+		return hasAttachments
+	}
+	return true
+}
+
+// ValidateAttachmentCount checks that a message does not exceed MESSAGE_MAX_ATTACHMENTS attachments.
+// @param count: The number of attachments.
+// @return bool: True if the number of attachments is allowed, false otherwise.
+func ValidateAttachmentCount(count int) bool {
+	return count >= 0 && count <= MESSAGE_MAX_ATTACHMENTS
+}
+
+// ValidateReactionEmojiCount checks that a message is not reacted with more than
+// MESSAGE_MAX_REACTION_EMOJI distinct emoji.
+// @param count: The number of distinct emoji including the new one.
+// @return bool: True if the number of emoji is allowed, false otherwise.
+func ValidateReactionEmojiCount(count int) bool {
+	return count >= 0 && count <= MESSAGE_MAX_REACTION_EMOJI
+}
+
+// ValidatePinCount checks that a channel does not have more than MESSAGE_MAX_PINS pinned messages.
+// @param count: The number of pinned messages including the new one.
+// @return bool: True if the number of pins is allowed, false otherwise.
+func ValidatePinCount(count int) bool {
+	return count >= 0 && count <= MESSAGE_MAX_PINS
+}
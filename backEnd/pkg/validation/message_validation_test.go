@@ -0,0 +1,133 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+)
+
+// TestValidateMessageContent tests the ValidateMessageContent function.
+func TestValidateMessageContent(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		hasAttachments bool
+		want           bool
+	}{
+		{
+			name:    "Valid: Simple text",
+			content: "Hello there!",
+			want:    true,
+		},
+		{
+			name:    "Valid: Multi-line unicode text",
+			content: "Szia! 👋\nHow are you?",
+			want:    true,
+		},
+		{
+			name:    "Valid: Maximum length",
+			content: strings.Repeat("ő", validation.MESSAGE_CONTENT_MAX_LENGTH),
+			want:    true,
+		},
+		{
+			name:           "Valid: Empty content with attachments",
+			content:        "",
+			hasAttachments: true,
+			want:           true,
+		},
+		{
+			name:    "Invalid: Empty content without attachments",
+			content: "",
+			want:    false,
+		},
+		{
+			name:    "Invalid: Whitespace only",
+			content: " \n\t ",
+			want:    false,
+		},
+		{
+			name:           "Invalid: Too long even with attachments",
+			content:        strings.Repeat("a", validation.MESSAGE_CONTENT_MAX_LENGTH+1),
+			hasAttachments: true,
+			want:           false,
+		},
+		{
+			name:    "Invalid: Not UTF-8",
+			content: string([]byte{0xc3, 0x28}),
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateMessageContent(tt.content, tt.hasAttachments); got != tt.want {
+				t.Errorf("ValidateMessageContent(%q, %v) = %v, want %v", tt.content, tt.hasAttachments, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateAttachmentCount tests the ValidateAttachmentCount function.
+func TestValidateAttachmentCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  bool
+	}{
+		{name: "Valid: No attachments", count: 0, want: true},
+		{name: "Valid: Maximum", count: validation.MESSAGE_MAX_ATTACHMENTS, want: true},
+		{name: "Invalid: Too many", count: validation.MESSAGE_MAX_ATTACHMENTS + 1, want: false},
+		{name: "Invalid: Negative", count: -1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateAttachmentCount(tt.count); got != tt.want {
+				t.Errorf("ValidateAttachmentCount(%d) = %v, want %v", tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateReactionEmojiCount tests the ValidateReactionEmojiCount function.
+func TestValidateReactionEmojiCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  bool
+	}{
+		{name: "Valid: First emoji", count: 1, want: true},
+		{name: "Valid: Maximum", count: validation.MESSAGE_MAX_REACTION_EMOJI, want: true},
+		{name: "Invalid: Too many", count: validation.MESSAGE_MAX_REACTION_EMOJI + 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateReactionEmojiCount(tt.count); got != tt.want {
+				t.Errorf("ValidateReactionEmojiCount(%d) = %v, want %v", tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidatePinCount tests the ValidatePinCount function.
+func TestValidatePinCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  bool
+	}{
+		{name: "Valid: First pin", count: 1, want: true},
+		{name: "Valid: Maximum", count: validation.MESSAGE_MAX_PINS, want: true},
+		{name: "Invalid: Too many", count: validation.MESSAGE_MAX_PINS + 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidatePinCount(tt.count); got != tt.want {
+				t.Errorf("ValidatePinCount(%d) = %v, want %v", tt.count, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package validation
+
+// threadAutoArchiveMinutes are the inactivity durations a thread can be archived after:
+// one hour, one day, three days and one week.
+var threadAutoArchiveMinutes = map[int]bool{60: true, 1440: true, 4320: true, 10080: true}
+
+// ValidateThreadName checks if the provided thread name is valid.
+// Thread names follow the same rules as channel names.
+// @param name: The thread name to validate.
+// @return bool: True if the name is valid, false otherwise.
+func ValidateThreadName(name string) bool {
+	return channelNameRegex.MatchString(name)
+}
+
+// ValidateAutoArchiveMinutes checks if the provided auto archive duration is one of the
+// supported values: 60, 1440, 4320 or 10080 minutes.
+// @param minutes: The inactivity in minutes after which the thread is archived.
+// @return bool: True if the duration is supported, false otherwise.
+func ValidateAutoArchiveMinutes(minutes int) bool {
+	return threadAutoArchiveMinutes[minutes]
+}
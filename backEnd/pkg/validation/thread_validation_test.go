@@ -0,0 +1,53 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/413ksz/BlueFox/backEnd/pkg/validation"
+)
+
+// TestValidateThreadName tests the ValidateThreadName function.
+func TestValidateThreadName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "Valid: Simple name", input: "Release notes", want: true},
+		{name: "Invalid: Empty", input: "", want: false},
+		{name: "Invalid: Trailing space", input: "notes ", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateThreadName(tt.input); got != tt.want {
+				t.Errorf("ValidateThreadName(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateAutoArchiveMinutes tests the ValidateAutoArchiveMinutes function.
+func TestValidateAutoArchiveMinutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes int
+		want    bool
+	}{
+		{name: "Valid: One hour", minutes: 60, want: true},
+		{name: "Valid: One day", minutes: 1440, want: true},
+		{name: "Valid: Three days", minutes: 4320, want: true},
+		{name: "Valid: One week", minutes: 10080, want: true},
+		{name: "Invalid: Zero", minutes: 0, want: false},
+		{name: "Invalid: Unsupported duration", minutes: 120, want: false},
+		{name: "Invalid: Negative", minutes: -60, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validation.ValidateAutoArchiveMinutes(tt.minutes); got != tt.want {
+				t.Errorf("ValidateAutoArchiveMinutes(%d) = %v, want %v", tt.minutes, got, tt.want)
+			}
+		})
+	}
+}